@@ -0,0 +1,86 @@
+package actor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	//Header is the request header naming who makes the request. It is set by the gateway authenticating
+	//the users, clients cannot be trusted to send it themselves.
+	Header = "X-Actor"
+	//Anonymous is the actor of the requests that do not name one
+	Anonymous = "anonymous"
+	//System is the actor of the changes made by the service itself, like its background jobs
+	System = "system"
+
+	//RolesHeader is the request header listing the roles of the actor separated by commas, set by the gateway too
+	RolesHeader = "X-Actor-Roles"
+	//Admin is the role of the actors managing the service, acting for any user
+	Admin = "admin"
+	//Moderator is the role of the actors moderating what the users publish
+	Moderator = "moderator"
+)
+
+type key struct{}
+
+type rolesKey struct{}
+
+//NewContext returns a copy of the context carrying the actor
+func NewContext(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, key{}, actor)
+}
+
+//FromContext returns the actor of the context, Anonymous when it has none
+func FromContext(ctx context.Context) string {
+	if a, ok := ctx.Value(key{}).(string); ok && a != "" {
+		return a
+	}
+	return Anonymous
+}
+
+//WithRoles returns a copy of the context carrying the roles of its actor
+func WithRoles(ctx context.Context, roles ...string) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+//HasRole tells whether the actor of the context has the role, the System actor has them all
+func HasRole(ctx context.Context, role string) bool {
+	if FromContext(ctx) == System {
+		return true
+	}
+	roles, _ := ctx.Value(rolesKey{}).([]string)
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+//Middleware carries the actor of the Header and its roles of the RolesHeader in the context of the requests. The
+//roles of a request without an actor are left out.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			a := c.Request().Header.Get(Header)
+			if a == "" {
+				return next(c)
+			}
+			ctx := NewContext(c.Request().Context(), a)
+			if roles := c.Request().Header.Get(RolesHeader); roles != "" {
+				var rs []string
+				for _, r := range strings.Split(roles, ",") {
+					if r = strings.TrimSpace(r); r != "" {
+						rs = append(rs, r)
+					}
+				}
+				ctx = WithRoles(ctx, rs...)
+			}
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
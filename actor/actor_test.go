@@ -0,0 +1,66 @@
+package actor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, Anonymous, FromContext(context.Background()))
+	assert.Equal(t, "ana", FromContext(NewContext(context.Background(), "ana")))
+}
+
+func TestMiddleware(t *testing.T) {
+	for header, want := range map[string]string{"ana": "ana", "": Anonymous} {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(Header, header)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		var got string
+		assert.NoError(t, Middleware()(func(c echo.Context) error {
+			got = FromContext(c.Request().Context())
+			return nil
+		})(c))
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	ctx := WithRoles(NewContext(context.Background(), "ana"), Moderator)
+	assert.True(t, HasRole(ctx, Moderator))
+	assert.False(t, HasRole(ctx, Admin))
+	assert.False(t, HasRole(context.Background(), Admin))
+	assert.True(t, HasRole(NewContext(context.Background(), System), Admin))
+}
+
+func TestMiddleware_Roles(t *testing.T) {
+	tests := []struct {
+		actor string
+		roles string
+		admin bool
+	}{
+		{"ana", "moderator, admin", true},
+		{"ana", "moderator", false},
+		{"", "admin", false},
+	}
+	for _, tt := range tests {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(Header, tt.actor)
+		req.Header.Set(RolesHeader, tt.roles)
+		c := e.NewContext(req, httptest.NewRecorder())
+
+		var got bool
+		assert.NoError(t, Middleware()(func(c echo.Context) error {
+			got = HasRole(c.Request().Context(), Admin)
+			return nil
+		})(c))
+		assert.Equal(t, tt.admin, got)
+	}
+}
@@ -1,6 +1,10 @@
 package cache
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/go-redis/cache"
+)
 
 //RedisErr is the err that every cache err should return.
 type RedisErr struct {
@@ -10,3 +14,9 @@ type RedisErr struct {
 func (e *RedisErr) Error() string {
 	return fmt.Sprintf("redis err: %s", e.Msg)
 }
+
+//IsMiss reports whether the err is a key missing from the cache
+func IsMiss(err error) bool {
+	rerr, ok := err.(*RedisErr)
+	return ok && rerr.Msg == cache.ErrCacheMiss.Error()
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ednesic/coursemanagement/metrics"
+	"github.com/vmihailenco/msgpack"
+)
+
+//refreshBeta weights how early before expiring a value may be refreshed, greater values refresh earlier
+const refreshBeta = 1.0
+
+var loads = &group{calls: map[string]*call{}}
+
+//Entry is how GetOrLoad stores a value: encoded along with how long it took to load and when it expires
+type Entry struct {
+	Value   []byte
+	Delta   time.Duration
+	Expires int64
+}
+
+//NewEntry encodes the object in an entry expiring after ttl that took delta to load
+func NewEntry(object interface{}, delta, ttl time.Duration) (Entry, error) {
+	value, err := msgpack.Marshal(object)
+	if err != nil {
+		return Entry{}, &RedisErr{Msg: err.Error()}
+	}
+	return Entry{Value: value, Delta: delta, Expires: time.Now().Add(ttl).UnixNano()}, nil
+}
+
+//refreshEarly decides, with a probability growing as the expiration gets closer and the longer the
+//value takes to load, if the value should be loaded again before it expires (XFetch).
+func (e Entry) refreshEarly(now time.Time) bool {
+	if e.Delta <= 0 {
+		return false
+	}
+	gap := -float64(e.Delta) * refreshBeta * math.Log(rand.Float64())
+	return float64(now.UnixNano())+gap >= float64(e.Expires)
+}
+
+//Loader loads into object, a pointer like the one given to GetOrLoad, the value of a key
+type Loader func(object interface{}) error
+
+//Put stores the object in the cache the way GetOrLoad reads it
+func Put(key string, object interface{}, ttl time.Duration) error {
+	e, err := NewEntry(object, 0, ttl)
+	if err != nil {
+		return err
+	}
+	return GetInstance().Set(key, e, ttl)
+}
+
+//GetOrLoad reads the key into object. On a miss it loads and stores the value for ttl, collapsing the
+//concurrent loads of the same key in this process in a single one. Hits may trigger a load in the
+//background shortly before the value expires, so hot keys do not all miss at once. The error of storing
+//the loaded value is returned to the caller who loaded it, as the object is filled regardless.
+func GetOrLoad(key string, object interface{}, ttl time.Duration, load Loader) error {
+	var e Entry
+	if err := GetInstance().Get(key, &e); err == nil && len(e.Value) > 0 {
+		if err = msgpack.Unmarshal(e.Value, object); err == nil {
+			if e.refreshEarly(time.Now()) {
+				go refresh(key, reflect.TypeOf(object).Elem(), ttl, load)
+			}
+			return nil
+		}
+	}
+
+	c, leader := loads.do(key, func() *call { return loadAndStore(key, reflect.TypeOf(object).Elem(), ttl, load) })
+	if !leader {
+		metrics.CacheLoad(metrics.CacheLoadCollapsed)
+	}
+	if c.err != nil {
+		return c.err
+	}
+	if err := msgpack.Unmarshal(c.value, object); err != nil {
+		return &RedisErr{Msg: err.Error()}
+	}
+	if leader {
+		return c.setErr
+	}
+	return nil
+}
+
+func refresh(key string, typ reflect.Type, ttl time.Duration, load Loader) {
+	if _, leader := loads.do(key, func() *call { return loadAndStore(key, typ, ttl, load) }); leader {
+		metrics.CacheLoad(metrics.CacheLoadRefreshed)
+	}
+}
+
+func loadAndStore(key string, typ reflect.Type, ttl time.Duration, load Loader) *call {
+	metrics.CacheLoad(metrics.CacheLoadLoaded)
+	object := reflect.New(typ).Interface()
+	start := time.Now()
+	if err := load(object); err != nil {
+		return &call{err: err}
+	}
+	e, err := NewEntry(object, time.Since(start), ttl)
+	if err != nil {
+		return &call{err: err}
+	}
+	return &call{value: e.Value, setErr: GetInstance().Set(key, e, ttl)}
+}
+
+type call struct {
+	done   chan struct{}
+	value  []byte
+	err    error
+	setErr error
+}
+
+//group runs a single function at a time per key, the callers arriving meanwhile share its result
+type group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+func (g *group) do(key string, fn func() *call) (*call, bool) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-c.done
+		return c, false
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	res := fn()
+	c.value, c.err, c.setErr = res.value, res.err, res.setErr
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(c.done)
+	return c, true
+}
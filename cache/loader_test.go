@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrLoad_CollapsesConcurrentLoads(t *testing.T) {
+	lc := NewLRU()
+	lc.Initialize(map[string]string{})
+	SetInstance(lc)
+	var calls int32
+	release := make(chan struct{})
+	load := func(v interface{}) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		*v.(*lruItem) = lruItem{Name: "loaded"}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]lruItem, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, GetOrLoad("collapse", &results[i], time.Minute, load))
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, r := range results {
+		assert.Equal(t, "loaded", r.Name)
+	}
+
+	var cached lruItem
+	assert.NoError(t, GetOrLoad("collapse", &cached, time.Minute, func(interface{}) error {
+		t.Fatal("a cached key should not be loaded")
+		return nil
+	}))
+	assert.Equal(t, "loaded", cached.Name)
+}
+
+func TestGetOrLoad_LoadErr(t *testing.T) {
+	lc := NewLRU()
+	lc.Initialize(map[string]string{})
+	SetInstance(lc)
+	errLoad := errors.New("load err")
+	var got lruItem
+
+	err := GetOrLoad("err", &got, time.Minute, func(interface{}) error { return errLoad })
+	assert.Equal(t, errLoad, err)
+	assert.Error(t, GetInstance().Get("err", &Entry{}))
+}
+
+func TestGetOrLoad_RefreshesBeforeExpiring(t *testing.T) {
+	lc := NewLRU()
+	lc.Initialize(map[string]string{})
+	SetInstance(lc)
+	e, _ := NewEntry(lruItem{Name: "old"}, time.Hour, time.Minute)
+	assert.NoError(t, GetInstance().Set("refresh", e, time.Minute))
+
+	refreshed := make(chan struct{})
+	var got lruItem
+	assert.NoError(t, GetOrLoad("refresh", &got, time.Minute, func(v interface{}) error {
+		*v.(*lruItem) = lruItem{Name: "new"}
+		defer close(refreshed)
+		return nil
+	}))
+	assert.Equal(t, "old", got.Name)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("the key was not refreshed")
+	}
+	for i := 0; i < 100 && e.Delta == time.Hour; i++ {
+		time.Sleep(10 * time.Millisecond)
+		assert.NoError(t, GetInstance().Get("refresh", &e))
+	}
+	assert.NoError(t, GetOrLoad("refresh", &got, time.Minute, nil))
+	assert.Equal(t, "new", got.Name)
+}
+
+func TestEntry_RefreshEarly(t *testing.T) {
+	now := time.Now()
+	assert.False(t, Entry{Delta: 0, Expires: now.UnixNano()}.refreshEarly(now.Add(-time.Second)))
+	assert.False(t, Entry{Delta: time.Nanosecond, Expires: now.Add(time.Hour).UnixNano()}.refreshEarly(now))
+	assert.True(t, Entry{Delta: time.Millisecond, Expires: now.UnixNano()}.refreshEarly(now))
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/cache"
+	"github.com/vmihailenco/msgpack"
+)
+
+const defaultLRUSize = 1000
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+//lruImpl is an in-process cache bounded to size entries, evicting the least recently used one when full
+type lruImpl struct {
+	mu    sync.Mutex
+	size  int
+	items map[string]*list.Element
+	order *list.List
+}
+
+//NewLRU returns an in-process cache, SetInstance makes it the one returned by GetInstance
+func NewLRU() Cache {
+	return &lruImpl{}
+}
+
+//Initialize reads the maximum number of entries from the "size" option
+func (lc *lruImpl) Initialize(opts map[string]string) {
+	size, _ := strconv.Atoi(opts["size"])
+	lc.reset(size)
+}
+
+//reset empties the cache and bounds it to size entries, the default size when not positive
+func (lc *lruImpl) reset(size int) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.size = defaultLRUSize
+	if size > 0 {
+		lc.size = size
+	}
+	lc.items = map[string]*list.Element{}
+	lc.order = list.New()
+}
+
+func (lc *lruImpl) Get(key string, object interface{}) error {
+	lc.mu.Lock()
+	el, ok := lc.items[key]
+	if ok && lc.expired(el.Value.(*lruEntry)) {
+		lc.remove(el)
+		ok = false
+	}
+	if !ok {
+		lc.mu.Unlock()
+		return &RedisErr{Msg: cache.ErrCacheMiss.Error()}
+	}
+	lc.order.MoveToFront(el)
+	value := el.Value.(*lruEntry).value
+	lc.mu.Unlock()
+
+	if err := msgpack.Unmarshal(value, object); err != nil {
+		return &RedisErr{Msg: err.Error()}
+	}
+	return nil
+}
+
+func (lc *lruImpl) Set(k string, obj interface{}, d time.Duration) error {
+	if d < 0 {
+		if err := lc.Delete(k); !IsMiss(err) {
+			return err
+		}
+		return nil
+	}
+	value, err := msgpack.Marshal(obj)
+	if err != nil {
+		return &RedisErr{Msg: err.Error()}
+	}
+	e := &lruEntry{key: k, value: value}
+	if d > 0 {
+		e.expires = time.Now().Add(d)
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if el, ok := lc.items[k]; ok {
+		el.Value = e
+		lc.order.MoveToFront(el)
+		return nil
+	}
+	lc.items[k] = lc.order.PushFront(e)
+	for lc.order.Len() > lc.size {
+		lc.remove(lc.order.Back())
+	}
+	return nil
+}
+
+func (lc *lruImpl) Delete(key string) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	el, ok := lc.items[key]
+	if !ok || lc.expired(el.Value.(*lruEntry)) {
+		if ok {
+			lc.remove(el)
+		}
+		return &RedisErr{Msg: cache.ErrCacheMiss.Error()}
+	}
+	lc.remove(el)
+	return nil
+}
+
+func (lc *lruImpl) Disconnect() {}
+
+func (lc *lruImpl) expired(e *lruEntry) bool {
+	return !e.expires.IsZero() && time.Now().After(e.expires)
+}
+
+func (lc *lruImpl) remove(el *list.Element) {
+	lc.order.Remove(el)
+	delete(lc.items, el.Value.(*lruEntry).key)
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type lruItem struct {
+	Name  string
+	Price float64
+}
+
+func TestLRU_SetGetDelete(t *testing.T) {
+	lc := NewLRU()
+	lc.Initialize(map[string]string{})
+	want := lruItem{Name: "test", Price: 10}
+
+	var got lruItem
+	assert.IsType(t, &RedisErr{}, lc.Get("key", &got))
+
+	assert.NoError(t, lc.Set("key", want, time.Minute))
+	assert.NoError(t, lc.Get("key", &got))
+	assert.Equal(t, want, got)
+	assert.True(t, GetInstance() != lc)
+	SetInstance(lc)
+	assert.Equal(t, lc, GetInstance())
+
+	assert.NoError(t, lc.Delete("key"))
+	assert.IsType(t, &RedisErr{}, lc.Get("key", &got))
+	assert.IsType(t, &RedisErr{}, lc.Delete("key"))
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	lc := NewLRU()
+	lc.Initialize(map[string]string{"size": "2"})
+	var got lruItem
+
+	assert.NoError(t, lc.Set("a", lruItem{Name: "a"}, time.Minute))
+	assert.NoError(t, lc.Set("b", lruItem{Name: "b"}, time.Minute))
+	assert.NoError(t, lc.Get("a", &got))
+	assert.NoError(t, lc.Set("c", lruItem{Name: "c"}, time.Minute))
+
+	assert.NoError(t, lc.Get("a", &got))
+	assert.Error(t, lc.Get("b", &got))
+	assert.NoError(t, lc.Get("c", &got))
+}
+
+func TestLRU_Expiration(t *testing.T) {
+	lc := &lruImpl{}
+	lc.Initialize(map[string]string{})
+	var got lruItem
+
+	assert.NoError(t, lc.Set("never", lruItem{}, 0))
+	assert.NoError(t, lc.Set("short", lruItem{}, 20*time.Millisecond))
+	assert.NoError(t, lc.Set("long", lruItem{}, time.Minute))
+	assert.True(t, lc.items["never"].Value.(*lruEntry).expires.IsZero())
+	assert.WithinDuration(t, time.Now().Add(time.Minute), lc.items["long"].Value.(*lruEntry).expires, time.Second)
+	assert.NoError(t, lc.Get("short", &got))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Error(t, lc.Get("short", &got))
+	assert.NotContains(t, lc.items, "short")
+	assert.NoError(t, lc.Get("never", &got))
+
+	assert.NoError(t, lc.Set("long", lruItem{}, -1))
+	assert.NotContains(t, lc.items, "long")
+	assert.NoError(t, lc.Set("missing", lruItem{}, -1))
+	assert.NotContains(t, lc.items, "missing")
+}
+
+func TestIsMiss(t *testing.T) {
+	lc := NewLRU()
+	lc.Initialize(map[string]string{})
+	SetInstance(lc)
+	assert.True(t, IsMiss(GetInstance().Delete("missing")))
+	assert.True(t, IsMiss(GetInstance().Get("missing", &lruItem{})))
+	assert.False(t, IsMiss(&RedisErr{Msg: "connection refused"}))
+	assert.False(t, IsMiss(nil))
+}
@@ -14,7 +14,8 @@ var (
 	once     sync.Once
 )
 
-//Cache is an interface to handle cache
+//Cache is an interface to handle cache. Values are kept for the duration given to Set, forever when it is 0,
+//and not at all when it is negative.
 type Cache interface {
 	Get(string, interface{}) error
 	Set(string, interface{}, time.Duration) error
@@ -38,6 +39,11 @@ func GetInstance() Cache {
 	return instance
 }
 
+//SetInstance makes the cache the one returned by GetInstance
+func SetInstance(c Cache) {
+	instance = c
+}
+
 func (rc *rImpl) Initialize(hosts map[string]string) {
 	rc.ring = redis.NewRing(&redis.RingOptions{
 		Addrs: hosts,
@@ -61,8 +67,19 @@ func (rc *rImpl) Get(key string, object interface{}) error {
 	return nil
 }
 
+//Set stores the object with the ring rather than the codec, which keeps values under a second for an hour
 func (rc *rImpl) Set(k string, obj interface{}, d time.Duration) error {
-	if err := rc.codec.Set(&cache.Item{Key: k, Object: obj, Expiration: d}); err != nil {
+	if d < 0 {
+		if err := rc.Delete(k); !IsMiss(err) {
+			return err
+		}
+		return nil
+	}
+	b, err := msgpack.Marshal(obj)
+	if err == nil {
+		err = rc.ring.Set(k, b, d).Err()
+	}
+	if err != nil {
 		return &RedisErr{Msg: err.Error()}
 	}
 	return nil
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const tagPrefix = "tag:"
+
+//tagVersion returns the current version of the tag, starting a new one when the tag has none. Other errors
+//are returned, as a new version would drop every value of the tag while the cache is only failing.
+func tagVersion(tag string) (int64, error) {
+	var v int64
+	err := GetInstance().Get(tagPrefix+tag, &v)
+	if err == nil && v != 0 {
+		return v, nil
+	}
+	if err != nil && !IsMiss(err) {
+		return 0, err
+	}
+	return newTagVersion(tag)
+}
+
+//newTagVersion stores a version never used by the tag, so the keys of the previous ones are not read again
+func newTagVersion(tag string) (int64, error) {
+	v := time.Now().UnixNano()
+	return v, GetInstance().Set(tagPrefix+tag, v, 0)
+}
+
+//TaggedKey returns the key holding the value of key for the current versions of its tags.
+//Invalidating one of the tags changes the key, so the values stored before are not read anymore
+//and expire by themselves.
+func TaggedKey(key string, tags ...string) (string, error) {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, tag := range tags {
+		v, err := tagVersion(tag)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString("#" + tag + "=" + strconv.FormatInt(v, 10))
+	}
+	return b.String(), nil
+}
+
+//InvalidateTags drops, as a group, every value stored under one of the tags
+func InvalidateTags(tags ...string) error {
+	for _, tag := range tags {
+		if _, err := newTagVersion(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//GetOrLoadTagged is GetOrLoad for a key tagged with tags. When the versions of the tags cannot be read
+//the value is loaded without going through the cache and the cache error returned after it.
+func GetOrLoadTagged(key string, tags []string, object interface{}, ttl time.Duration, load Loader) error {
+	tagged, err := TaggedKey(key, tags...)
+	if err != nil {
+		if lerr := load(object); lerr != nil {
+			return lerr
+		}
+		return err
+	}
+	return GetOrLoad(tagged, object, ttl, load)
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-redis/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTaggedKey_ChangesOnInvalidation(t *testing.T) {
+	lc := NewLRU()
+	lc.Initialize(map[string]string{})
+	SetInstance(lc)
+
+	k1, err := TaggedKey("list", "a", "b")
+	assert.NoError(t, err)
+	k2, err := TaggedKey("list", "a", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, k1, k2)
+
+	assert.NoError(t, InvalidateTags("b"))
+	k3, err := TaggedKey("list", "a", "b")
+	assert.NoError(t, err)
+	assert.NotEqual(t, k1, k3)
+}
+
+func TestGetOrLoadTagged_ReloadsAfterInvalidation(t *testing.T) {
+	lc := NewLRU()
+	lc.Initialize(map[string]string{})
+	SetInstance(lc)
+	name := "first"
+	load := func(v interface{}) error {
+		*v.(*lruItem) = lruItem{Name: name}
+		return nil
+	}
+	var got lruItem
+
+	assert.NoError(t, GetOrLoadTagged("item", []string{"items"}, &got, time.Minute, load))
+	assert.Equal(t, "first", got.Name)
+
+	name = "second"
+	assert.NoError(t, GetOrLoadTagged("item", []string{"items"}, &got, time.Minute, load))
+	assert.Equal(t, "first", got.Name)
+
+	assert.NoError(t, InvalidateTags("items"))
+	assert.NoError(t, GetOrLoadTagged("item", []string{"items"}, &got, time.Minute, load))
+	assert.Equal(t, "second", got.Name)
+}
+
+func TestTaggedKey_Errors(t *testing.T) {
+	errCache := &RedisErr{Msg: "connection refused"}
+	tests := []struct {
+		name   string
+		getErr error
+		setErr error
+		err    error
+	}{
+		{"New version on a miss", &RedisErr{Msg: cache.ErrCacheMiss.Error()}, nil, nil},
+		{"Error storing the new version", &RedisErr{Msg: cache.ErrCacheMiss.Error()}, errCache, errCache},
+		{"Version kept on other errors", errCache, nil, errCache},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redisMock := &Mock{}
+			redisMock.Initialize(map[string]string{})
+			redisMock.On("Get", tagPrefix+"a", mock.Anything).Return(tt.getErr).Once()
+			if IsMiss(tt.getErr) {
+				redisMock.On("Set", tagPrefix+"a", mock.Anything, time.Duration(0)).Return(tt.setErr).Once()
+			}
+
+			_, err := TaggedKey("list", "a")
+			assert.Equal(t, tt.err, err)
+			redisMock.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+//InvalidationChannel is the redis channel where the tiered caches announce the keys they changed
+const InvalidationChannel = "cache:invalidate"
+
+//tieredImpl checks a short lived in-process tier before going to the redis ring. Every Set and
+//Delete is published on InvalidationChannel so the other replicas drop their local copy of the key.
+//The generation counts the local copies dropped, so a value read from redis before one of them is not
+//kept locally as it may be the stale one.
+type tieredImpl struct {
+	id       string
+	localTTL time.Duration
+	local    *lruImpl
+	remote   Cache
+	bus      bus
+	done     chan struct{}
+
+	mu  sync.Mutex
+	gen uint64
+}
+
+//bus carries the invalidations between the replicas
+type bus interface {
+	publish(msg string) error
+	//subscribe hands every message to handle until done is closed
+	subscribe(done <-chan struct{}, handle func(msg string))
+}
+
+//redisBus is the bus of the InvalidationChannel of the redis ring
+type redisBus struct {
+	rc *rImpl
+}
+
+//NewTiered returns a cache keeping up to localSize entries for at most localTTL in front of redis. SetInstance makes it the one returned by GetInstance.
+func NewTiered(localSize int, localTTL time.Duration) Cache {
+	rc := &rImpl{}
+	tc := &tieredImpl{localTTL: localTTL, local: &lruImpl{}, remote: rc, bus: redisBus{rc: rc}}
+	tc.local.reset(localSize)
+	return tc
+}
+
+//Initialize connects to the redis hosts and starts listening for invalidations
+func (tc *tieredImpl) Initialize(hosts map[string]string) {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	tc.id = hex.EncodeToString(id)
+	tc.done = make(chan struct{})
+	tc.remote.Initialize(hosts)
+	go tc.bus.subscribe(tc.done, tc.invalidate)
+}
+
+func (tc *tieredImpl) Get(key string, object interface{}) error {
+	if err := tc.local.Get(key, object); err == nil {
+		return nil
+	}
+	gen := tc.generation()
+	if err := tc.remote.Get(key, object); err != nil {
+		return err
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.gen == gen {
+		_ = tc.local.Set(key, object, tc.localTTL)
+	}
+	return nil
+}
+
+func (tc *tieredImpl) Set(k string, obj interface{}, d time.Duration) error {
+	if err := tc.remote.Set(k, obj, d); err != nil {
+		return err
+	}
+	ttl := tc.localTTL
+	if d != 0 && d < ttl {
+		ttl = d
+	}
+	tc.mu.Lock()
+	tc.gen++
+	_ = tc.local.Set(k, obj, ttl)
+	tc.mu.Unlock()
+	return tc.bus.publish(tc.id + "|" + k)
+}
+
+func (tc *tieredImpl) Delete(key string) error {
+	err := tc.remote.Delete(key)
+	tc.drop(key)
+	if perr := tc.bus.publish(tc.id + "|" + key); err == nil {
+		err = perr
+	}
+	return err
+}
+
+func (tc *tieredImpl) Disconnect() {
+	close(tc.done)
+	tc.remote.Disconnect()
+}
+
+func (tc *tieredImpl) generation() uint64 {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.gen
+}
+
+//drop deletes the local copy of the key as a new generation
+func (tc *tieredImpl) drop(key string) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.gen++
+	_ = tc.local.Delete(key)
+}
+
+//invalidate drops the local copy of the key announced by another replica. Messages are the id of the
+//replica and the key, so a replica ignores its own ones.
+func (tc *tieredImpl) invalidate(payload string) {
+	parts := strings.SplitN(payload, "|", 2)
+	if len(parts) != 2 || parts[0] == tc.id {
+		return
+	}
+	tc.drop(parts[1])
+}
+
+func (b redisBus) publish(msg string) error {
+	if err := b.rc.ring.Publish(InvalidationChannel, msg).Err(); err != nil {
+		return &RedisErr{Msg: err.Error()}
+	}
+	return nil
+}
+
+func (b redisBus) subscribe(done <-chan struct{}, handle func(string)) {
+	ps := b.rc.ring.Subscribe(InvalidationChannel)
+	defer ps.Close()
+	msgs := ps.Channel()
+	for {
+		select {
+		case <-done:
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			handle(msg.Payload)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTiered_GetServesLocalTier(t *testing.T) {
+	tc := NewTiered(10, time.Second).(*tieredImpl)
+	want := lruItem{Name: "local"}
+	assert.NoError(t, tc.local.Set("key", want, time.Second))
+
+	var got lruItem
+	assert.NoError(t, tc.Get("key", &got))
+	assert.Equal(t, want, got)
+}
+
+func TestTiered_InvalidateFromOtherReplicas(t *testing.T) {
+	tc := NewTiered(10, time.Minute).(*tieredImpl)
+	tc.id = "self"
+	var got lruItem
+
+	assert.NoError(t, tc.local.Set("key", lruItem{}, time.Minute))
+	tc.invalidate("self|key")
+	assert.NoError(t, tc.local.Get("key", &got))
+
+	tc.invalidate("malformed")
+	assert.NoError(t, tc.local.Get("key", &got))
+
+	tc.invalidate("other|key")
+	assert.Error(t, tc.local.Get("key", &got))
+}
+
+//memoryBus hands the messages published to every replica of the test at once
+type memoryBus struct {
+	handles []func(string)
+}
+
+func (b *memoryBus) publish(msg string) error {
+	for _, h := range b.handles {
+		h(msg)
+	}
+	return nil
+}
+
+func (b *memoryBus) subscribe(done <-chan struct{}, handle func(string)) {}
+
+//racedRemote runs during each Get the change of another replica made while the value is read
+type racedRemote struct {
+	Cache
+	during func()
+}
+
+func (r racedRemote) Get(key string, object interface{}) error {
+	err := r.Cache.Get(key, object)
+	r.during()
+	return err
+}
+
+//newReplica is a tiered cache of the id in front of the remote, sharing the bus with the other replicas
+func newReplica(id string, remote Cache, b *memoryBus) *tieredImpl {
+	tc := NewTiered(10, time.Minute).(*tieredImpl)
+	tc.id, tc.remote, tc.bus = id, remote, b
+	b.handles = append(b.handles, tc.invalidate)
+	return tc
+}
+
+func TestTiered_InvalidatesOtherReplicas(t *testing.T) {
+	remote, b := NewLRU(), &memoryBus{}
+	remote.Initialize(map[string]string{})
+	a, other := newReplica("a", remote, b), newReplica("b", remote, b)
+	var got lruItem
+
+	assert.NoError(t, a.Set("key", lruItem{Name: "old"}, time.Minute))
+	assert.NoError(t, other.Get("key", &got))
+	assert.NoError(t, other.local.Get("key", &got))
+
+	assert.NoError(t, a.Set("key", lruItem{Name: "new"}, time.Minute))
+	assert.Error(t, other.local.Get("key", &got))
+	assert.NoError(t, other.Get("key", &got))
+	assert.Equal(t, "new", got.Name)
+
+	assert.NoError(t, a.Delete("key"))
+	assert.Error(t, other.local.Get("key", &got))
+	assert.Error(t, other.Get("key", &got))
+}
+
+func TestTiered_GetKeepsNoValueReadBeforeAnInvalidation(t *testing.T) {
+	remote, b := NewLRU(), &memoryBus{}
+	remote.Initialize(map[string]string{})
+	writer := newReplica("writer", remote, b)
+	assert.NoError(t, writer.Set("key", lruItem{Name: "old"}, time.Minute))
+
+	reader := newReplica("reader", racedRemote{Cache: remote, during: func() {
+		assert.NoError(t, writer.Set("key", lruItem{Name: "new"}, time.Minute))
+	}}, b)
+	var got lruItem
+	assert.NoError(t, reader.Get("key", &got))
+	assert.Equal(t, "old", got.Name)
+	assert.Error(t, reader.local.Get("key", &got))
+
+	reader.remote = remote
+	assert.NoError(t, reader.Get("key", &got))
+	assert.Equal(t, "new", got.Name)
+	assert.NoError(t, reader.local.Get("key", &got))
+}
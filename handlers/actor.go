@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/instructorservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+//isActor tells whether the user is the actor of the request, requests without an actor are no user's
+func isActor(c echo.Context, user string) bool {
+	a := actor.FromContext(c.Request().Context())
+	return a != actor.Anonymous && a == user
+}
+
+//actsFor tells whether the actor of the request is the user, or an admin acting for every user
+func actsFor(c echo.Context, user string) bool {
+	return isActor(c, user) || actor.HasRole(c.Request().Context(), actor.Admin)
+}
+
+//ownsCourse tells whether the actor of the request is the author of the course, the user of one of its instructors
+//or an admin
+func ownsCourse(c echo.Context, cr types.Course) (bool, error) {
+	if actsFor(c, cr.Author) {
+		return true, nil
+	}
+	a := actor.FromContext(c.Request().Context())
+	if a == actor.Anonymous || len(cr.Instructors) == 0 {
+		return false, nil
+	}
+	i, err := instructorservice.GetInstance().FindByUser(a)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == storage.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, id := range cr.Instructors {
+		if id == i.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//visible tells whether the actor of the request sees the course, published courses are seen by everyone and the
+//other ones by their owners only
+func visible(c echo.Context, cr types.Course) (bool, error) {
+	if cr.Status == types.StatusPublished || cr.Status == "" {
+		return true, nil
+	}
+	return ownsCourse(c, cr)
+}
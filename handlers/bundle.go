@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/services/bundleservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+//errNotBundleAdmin for bundles created, changed or removed by someone who is not an admin
+var errNotBundleAdmin = echo.NewHTTPError(http.StatusForbidden, "only the admins manage the bundles")
+
+//GetBundles is a handler to get every bundle by name
+func GetBundles(c echo.Context) error {
+	bs, err := bundleservice.GetInstance().FindAll()
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return fail(c, http.StatusInternalServerError, err)
+	}
+	if bs == nil {
+		bs = []types.Bundle{}
+	}
+	return c.JSON(http.StatusOK, bs)
+}
+
+//GetBundle is a handler to get the bundle of the path parameter id
+func GetBundle(c echo.Context) error {
+	b, err := bundleservice.GetInstance().FindOne(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, b)
+	}
+	return bundleFail(c, err)
+}
+
+//SetBundle is a handler to create a bundle passing a types.Bundle in the body, its courses by id or slug. Only the
+//admins create bundles.
+func SetBundle(c echo.Context) error {
+	if !actor.HasRole(c.Request().Context(), actor.Admin) {
+		return bundleFail(c, errNotBundleAdmin)
+	}
+	var b types.Bundle
+	if err := c.Bind(&b); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&b); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	b, err := bundleservice.GetInstance().Create(c.Request().Context(), b)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, b)
+	}
+	return bundleFail(c, err)
+}
+
+//PutBundle is a handler to replace the bundle of the path parameter id passing a types.Bundle in the body, for the
+//admins
+func PutBundle(c echo.Context) error {
+	if !actor.HasRole(c.Request().Context(), actor.Admin) {
+		return bundleFail(c, errNotBundleAdmin)
+	}
+	var b types.Bundle
+	if err := c.Bind(&b); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	b.ID = c.Param("id")
+	if err := c.Validate(&b); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	b, err := bundleservice.GetInstance().Update(c.Request().Context(), b)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, b)
+	}
+	return bundleFail(c, err)
+}
+
+//DelBundle is a handler that removes the bundle of the path parameter id, keeping the enrollments made through it.
+//Only the admins remove bundles.
+func DelBundle(c echo.Context) error {
+	if !actor.HasRole(c.Request().Context(), actor.Admin) {
+		return bundleFail(c, errNotBundleAdmin)
+	}
+	err := bundleservice.GetInstance().Delete(c.Request().Context(), c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.NoContent(http.StatusOK)
+	}
+	return bundleFail(c, err)
+}
+
+//EnrollBundle is a handler to enroll a student in every course of the bundle of the path parameter id, answering the
+//enrollments made. The courses the student is already enrolled in are skipped. Only the student and the admins enroll
+//the student.
+func EnrollBundle(c echo.Context) error {
+	var req enrollRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return fail(c, http.StatusBadRequest, err)
+		}
+	}
+	if req.Student == "" {
+		req.Student = actor.FromContext(c.Request().Context())
+	}
+	if req.Student == actor.Anonymous {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "student is required"))
+	}
+	if !actsFor(c, req.Student) {
+		return bundleFail(c, errNotEnrollee)
+	}
+
+	es, err := bundleservice.GetInstance().Enroll(c.Request().Context(), c.Param("id"), req.Student)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, es)
+	}
+	return bundleFail(c, err)
+}
+
+func bundleFail(c echo.Context, err error) error {
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case errNotEnrollee, errNotBundleAdmin:
+		httpStatus = http.StatusForbidden
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case bundleservice.ErrNotActive, enrollmentservice.ErrNotEnrollable:
+		httpStatus = http.StatusConflict
+	case enrollmentservice.ErrPaymentRequired:
+		httpStatus = http.StatusPaymentRequired
+	case bundleservice.ErrUnknownCourse, bundleservice.ErrTooFewCourses, money.ErrUnknownCurrency:
+		httpStatus = http.StatusBadRequest
+	}
+	return fail(c, httpStatus, err)
+}
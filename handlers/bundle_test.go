@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/bundleservice"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+)
+
+var testBundle = types.Bundle{ID: "b01", Name: "Backend", Courses: []string{"id01", "id02"}, Price: testutil.USD(2990), Active: true}
+
+//mockBundles mocks the active bundles of the course of the id
+func mockBundles(courseID string, bs []types.Bundle) *bundleservice.Mock {
+	var bundleServiceMngr = &bundleservice.Mock{}
+	bundleServiceMngr.On("FindByCourse", courseID).Return(bs, nil).Once()
+	bundleServiceMngr.InitMock()
+	return bundleServiceMngr
+}
+
+func TestGetBundles(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockBs     []types.Bundle
+		mockErr    error
+		statusCode int
+		body       string
+	}{
+		{"Status ok", []types.Bundle{testBundle}, nil, http.StatusOK,
+			`[{"id":"b01","name":"Backend","description":"","courses":["id01","id02"],"price":{"amount":2990,"currency":"USD"},"active":true}]` + "\n"},
+		{"Status ok empty", nil, nil, http.StatusOK, "[]\n"},
+		{"Status ok but redis err", nil, &cache.RedisErr{}, http.StatusOK, "[]\n"},
+		{"Status internal server error", nil, mgo.ErrCursor, http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bundleServiceMngr = &bundleservice.Mock{}
+			bundleServiceMngr.On("FindAll").Return(tt.mockBs, tt.mockErr).Once()
+			bundleServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			_ = GetBundles(e.NewContext(httptest.NewRequest(http.MethodGet, "/bundles", nil), rec))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, rec.Body.String())
+			}
+			bundleServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetBundle(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok", nil, http.StatusOK},
+		{"Status ok but redis err", &cache.RedisErr{}, http.StatusOK},
+		{"Status not found", storage.ErrNotFound, http.StatusNotFound},
+		{"Status internal server error", mgo.ErrCursor, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bundleServiceMngr = &bundleservice.Mock{}
+			bundleServiceMngr.On("FindOne", "b01").Return(testBundle, tt.mockErr).Once()
+			bundleServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/bundles/b01", nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("b01")
+
+			_ = GetBundle(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			bundleServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetBundle(t *testing.T) {
+	body := `{"name":"Backend","courses":["id01","id02"],"price":{"amount":2990,"currency":"USD"},"active":true}`
+	created := types.Bundle{Name: "Backend", Courses: []string{"id01", "id02"}, Price: testutil.USD(2990), Active: true}
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", body, "root", nil, 1, http.StatusCreated},
+		{"Status created but redis err", body, "root", &cache.RedisErr{}, 1, http.StatusCreated},
+		{"Status bad request body", `{"name":1}`, "root", nil, 0, http.StatusBadRequest},
+		{"Status bad request one course", `{"name":"Backend","courses":["id01"]}`, "root", nil, 0, http.StatusBadRequest},
+		{"Status bad request currency", `{"name":"Backend","courses":["id01","id02"],"price":{"amount":2990,"currency":"EURO"}}`, "root", nil, 0, http.StatusBadRequest},
+		{"Status bad request unknown course", body, "root", bundleservice.ErrUnknownCourse, 1, http.StatusBadRequest},
+		{"Status bad request repeated courses", body, "root", bundleservice.ErrTooFewCourses, 1, http.StatusBadRequest},
+		{"Status forbidden", body, "ana", nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", body, "", nil, 0, http.StatusForbidden},
+		{"Status internal server error", body, "root", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bundleServiceMngr = &bundleservice.Mock{}
+			bundleServiceMngr.On("Create", mock.Anything, created).Return(testBundle, tt.mockErr).Maybe().Times(tt.mockTimes)
+			bundleServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/bundles", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = withActor(req, tt.actor)
+			rec := httptest.NewRecorder()
+
+			_ = SetBundle(e.NewContext(req, rec))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			bundleServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPutAndDelBundle(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", "root", nil, 1, http.StatusOK},
+		{"Status ok but redis err", "root", &cache.RedisErr{}, 1, http.StatusOK},
+		{"Status forbidden", "ana", nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", "", nil, 0, http.StatusForbidden},
+		{"Status not found", "root", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status internal server error", "root", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bundleServiceMngr = &bundleservice.Mock{}
+			bundleServiceMngr.On("Update", mock.Anything, testBundle).Return(testBundle, tt.mockErr).Maybe().Times(tt.mockTimes)
+			bundleServiceMngr.On("Delete", mock.Anything, "b01").Return(tt.mockErr).Maybe().Times(tt.mockTimes)
+			bundleServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			body := `{"id":"other","name":"Backend","courses":["id01","id02"],"price":{"amount":2990,"currency":"USD"},"active":true}`
+			req := httptest.NewRequest(http.MethodPut, "/bundles/b01", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(withActor(req, tt.actor), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("b01")
+			_ = PutBundle(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+
+			rec = httptest.NewRecorder()
+			c = e.NewContext(withActor(httptest.NewRequest(http.MethodDelete, "/bundles/b01", nil), tt.actor), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("b01")
+			_ = DelBundle(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			bundleServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEnrollBundle(t *testing.T) {
+	es := []types.Enrollment{{ID: "id01:ana", CourseID: "id01", Student: "ana", Status: types.EnrollmentActive}}
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		student    string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", `{"student":"ana"}`, "ana", "ana", nil, 1, http.StatusCreated},
+		{"Status created for the actor", "", "ana", "ana", nil, 1, http.StatusCreated},
+		{"Status created but redis err", `{"student":"ana"}`, "ana", "ana", &cache.RedisErr{}, 1, http.StatusCreated},
+		{"Status bad request anonymous", "", "", "", nil, 0, http.StatusBadRequest},
+		{"Status bad request body", `{"student":1}`, "", "", nil, 0, http.StatusBadRequest},
+		{"Status created by an admin", `{"student":"ana"}`, "root", "ana", nil, 1, http.StatusCreated},
+		{"Status forbidden another student", `{"student":"ana"}`, "eve", "ana", nil, 0, http.StatusForbidden},
+		{"Status not found", `{"student":"ana"}`, "ana", "ana", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status conflict not active", `{"student":"ana"}`, "ana", "ana", bundleservice.ErrNotActive, 1, http.StatusConflict},
+		{"Status conflict not enrollable", `{"student":"ana"}`, "ana", "ana", enrollmentservice.ErrNotEnrollable, 1, http.StatusConflict},
+		{"Status payment required", `{"student":"ana"}`, "ana", "ana", enrollmentservice.ErrPaymentRequired, 1, http.StatusPaymentRequired},
+		{"Status internal server error", `{"student":"ana"}`, "ana", "ana", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bundleServiceMngr = &bundleservice.Mock{}
+			bundleServiceMngr.On("Enroll", mock.Anything, "b01", tt.student).Return(es, tt.mockErr).Maybe().Times(tt.mockTimes)
+			bundleServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/bundles/b01/enrollments", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = withActor(req, tt.actor)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("b01")
+
+			_ = EnrollBundle(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			bundleServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourse_Bundles(t *testing.T) {
+	course := types.Course{ID: "id01", Version: 3, Name: "Go", Price: testutil.USD(1000)}
+	var courseServiceMngr = &courseservice.Mock{}
+	courseServiceMngr.On("FindOne", "id01").Return(course, nil).Times(3)
+	courseServiceMngr.InitMock()
+	e := echo.New()
+
+	get := func(bs []types.Bundle, query string) *httptest.ResponseRecorder {
+		mockBundles("id01", bs)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/courses/id01"+query, nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues("id01")
+		assert.NoError(t, GetCourse(c))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		return rec
+	}
+
+	rec := get([]types.Bundle{testBundle}, "")
+	var body map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.JSONEq(t, `[{"id":"b01","name":"Backend","description":"","courses":["id01","id02"],`+
+		`"price":{"amount":2990,"currency":"USD"},"active":true}]`, string(body["bundles"]))
+	tag := rec.Header().Get(HeaderETag)
+	assert.True(t, strings.HasPrefix(tag, `"3-`))
+
+	renamed := testBundle
+	renamed.Name = "Server side"
+	assert.NotEqual(t, tag, get([]types.Bundle{renamed}, "").Header().Get(HeaderETag))
+	priced := get([]types.Bundle{testBundle}, "?currency=USD").Header().Get(HeaderETag)
+	assert.True(t, strings.HasPrefix(priced, `W/"3:1000USD-`))
+	courseServiceMngr.AssertExpectations(t)
+}
+
+func TestDelCourse_InActiveBundle(t *testing.T) {
+	var courseServiceMngr = &courseservice.Mock{}
+	courseServiceMngr.On("Delete", mock.Anything, "go", int64(0)).Return(bundleservice.ErrInActiveBundle).Once()
+	courseServiceMngr.InitMock()
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodDelete, "/courses/go", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues("go")
+
+	assert.Equal(t, bundleservice.ErrInActiveBundle, DelCourse(c))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	courseServiceMngr.AssertExpectations(t)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/categoryservice"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+//GetCategories is a handler to get every category by name, the tree is the one of their parents
+func GetCategories(c echo.Context) error {
+	cats, err := categoryservice.GetInstance().FindAll()
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return fail(c, http.StatusInternalServerError, err)
+	}
+	if cats == nil {
+		cats = []types.Category{}
+	}
+	return c.JSON(http.StatusOK, cats)
+}
+
+//GetCategory is a handler to get the category of the path parameter id
+func GetCategory(c echo.Context) error {
+	cat, err := categoryservice.GetInstance().FindOne(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, cat)
+	}
+	return categoryFail(c, err)
+}
+
+//GetCategoryCourses is a handler to get a page of the courses of the category of the path parameter id and of its
+//descendants, accepting the same query parameters as GetCourses
+func GetCategoryCourses(c echo.Context) error {
+	_, err := categoryservice.GetInstance().FindOne(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return categoryFail(c, err)
+	}
+	return listCourses(c, courseservice.ListOptions{Category: c.Param("id")})
+}
+
+//SetCategory is a handler to create a category passing a types.Category in the body, under its parent when it has one
+func SetCategory(c echo.Context) error {
+	var cat types.Category
+	if err := c.Bind(&cat); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&cat); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	cat, err := categoryservice.GetInstance().Create(c.Request().Context(), cat)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, cat)
+	}
+	return categoryFail(c, err)
+}
+
+//PutCategory is a handler to rename and move the category of the path parameter id passing a types.Category in the body
+func PutCategory(c echo.Context) error {
+	var cat types.Category
+	if err := c.Bind(&cat); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&cat); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	cat.ID = c.Param("id")
+	cat, err := categoryservice.GetInstance().Update(c.Request().Context(), cat)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, cat)
+	}
+	return categoryFail(c, err)
+}
+
+//DelCategory is a handler that removes the category of the path parameter id, categories with subcategories are a
+//conflict
+func DelCategory(c echo.Context) error {
+	err := categoryservice.GetInstance().Delete(c.Request().Context(), c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.NoContent(http.StatusOK)
+	}
+	return categoryFail(c, err)
+}
+
+func categoryFail(c echo.Context, err error) error {
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case categoryservice.ErrUnknownParent, categoryservice.ErrInvalidParent:
+		httpStatus = http.StatusBadRequest
+	case categoryservice.ErrHasChildren:
+		httpStatus = http.StatusConflict
+	}
+	return fail(c, httpStatus, err)
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/categoryservice"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+)
+
+var testCategory = types.Category{ID: "c2", Name: "Web", Parent: "c1", Path: []string{"c1"}}
+
+func TestGetCategories(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockCats   []types.Category
+		mockErr    error
+		statusCode int
+		body       string
+	}{
+		{"Status ok", []types.Category{testCategory}, nil, http.StatusOK, `[{"id":"c2","name":"Web","parent":"c1","path":["c1"]}]` + "\n"},
+		{"Status ok empty", nil, nil, http.StatusOK, "[]\n"},
+		{"Status ok but redis err", nil, &cache.RedisErr{}, http.StatusOK, "[]\n"},
+		{"Status internal server error", nil, mgo.ErrCursor, http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var categoryServiceMngr = &categoryservice.Mock{}
+			categoryServiceMngr.On("FindAll").Return(tt.mockCats, tt.mockErr).Once()
+			categoryServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			_ = GetCategories(e.NewContext(httptest.NewRequest(http.MethodGet, "/categories", nil), rec))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, rec.Body.String())
+			}
+			categoryServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCategory(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok", nil, http.StatusOK},
+		{"Status ok but redis err", &cache.RedisErr{}, http.StatusOK},
+		{"Status not found", storage.ErrNotFound, http.StatusNotFound},
+		{"Status internal server error", mgo.ErrCursor, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var categoryServiceMngr = &categoryservice.Mock{}
+			categoryServiceMngr.On("FindOne", "c2").Return(testCategory, tt.mockErr).Once()
+			categoryServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/categories/c2", nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("c2")
+
+			_ = GetCategory(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			categoryServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetCategory(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", `{"name":"Web","parent":"c1"}`, nil, 1, http.StatusCreated},
+		{"Status bad request body", `{"name":1}`, nil, 0, http.StatusBadRequest},
+		{"Status bad request name", `{"parent":"c1"}`, nil, 0, http.StatusBadRequest},
+		{"Status bad request unknown parent", `{"name":"Web","parent":"c1"}`, categoryservice.ErrUnknownParent, 1, http.StatusBadRequest},
+		{"Status internal server error", `{"name":"Web","parent":"c1"}`, mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var categoryServiceMngr = &categoryservice.Mock{}
+			categoryServiceMngr.On("Create", mock.Anything, types.Category{Name: "Web", Parent: "c1"}).Return(testCategory, tt.mockErr).Maybe().Times(tt.mockTimes)
+			categoryServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/categories", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			_ = SetCategory(e.NewContext(req, rec))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			categoryServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPutAndDelCategory(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok", nil, http.StatusOK},
+		{"Status not found", storage.ErrNotFound, http.StatusNotFound},
+		{"Status internal server error", mgo.ErrCursor, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var categoryServiceMngr = &categoryservice.Mock{}
+			categoryServiceMngr.On("Update", mock.Anything, types.Category{ID: "c2", Name: "Web", Parent: "c1"}).Return(testCategory, tt.mockErr).Once()
+			categoryServiceMngr.On("Delete", mock.Anything, "c2").Return(tt.mockErr).Once()
+			categoryServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPut, "/categories/c2", strings.NewReader(`{"name":"Web","parent":"c1"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("c2")
+			_ = PutCategory(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+
+			rec = httptest.NewRecorder()
+			c = e.NewContext(httptest.NewRequest(http.MethodDelete, "/categories/c2", nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("c2")
+			_ = DelCategory(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			categoryServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCategoryChanges_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		handler    echo.HandlerFunc
+		mockErr    error
+		statusCode int
+	}{
+		{"Status bad request moved under itself", "Update", PutCategory, categoryservice.ErrInvalidParent, http.StatusBadRequest},
+		{"Status conflict with subcategories", "Delete", DelCategory, categoryservice.ErrHasChildren, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var categoryServiceMngr = &categoryservice.Mock{}
+			categoryServiceMngr.On("Update", mock.Anything, mock.Anything).Return(testCategory, tt.mockErr).Maybe()
+			categoryServiceMngr.On("Delete", mock.Anything, "c2").Return(tt.mockErr).Maybe()
+			categoryServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPut, "/categories/c2", strings.NewReader(`{"name":"Web","parent":"c3"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("c2")
+			_ = tt.handler(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			categoryServiceMngr.AssertCalled(t, tt.method, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetCategoryCourses(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", nil, 1, http.StatusOK},
+		{"Status not found", storage.ErrNotFound, 0, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var categoryServiceMngr = &categoryservice.Mock{}
+			categoryServiceMngr.On("FindOne", "c2").Return(testCategory, tt.mockErr).Once()
+			categoryServiceMngr.InitMock()
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindAll", courseservice.ListOptions{Limit: defaultPageLimit, Category: "c2", Tags: []string{"go"}}).
+				Return(courseservice.Page{Courses: []types.Course{{ID: "id01", Name: "Go"}}, Total: 1}, nil).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/categories/c2/courses?category=other&tag=go", nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("c2")
+
+			_ = GetCategoryCourses(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			categoryServiceMngr.AssertExpectations(t)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
@@ -1,114 +1,541 @@
 package handlers
 
 import (
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/bundleservice"
 	"github.com/ednesic/coursemanagement/services/courseservice"
 	"github.com/ednesic/coursemanagement/storage"
 	"github.com/ednesic/coursemanagement/types"
 	"github.com/labstack/echo/v4"
+	"gopkg.in/go-playground/validator.v9"
 )
 
-//GetCourse is a handler to get course passing a query parameter name
-func GetCourse(c echo.Context) error {
-	name := c.Param("name")
-	cr, err := courseservice.GetInstance().FindOne(name)
-	httpStatus := http.StatusOK
+//courseView is the body of a course along with the active bundles including it and, when it is asked for in a
+//currency, its price in the currency
+type courseView struct {
+	types.Course
+	ResolvedPrice *types.Money   `json:"resolvedPrice,omitempty"`
+	Bundles       []types.Bundle `json:"bundles,omitempty"`
+}
 
+//GetCourse is a handler to get course passing its id or slug as the path parameter id, along with the active bundles
+//including it. It answers the ETag of the course version and 304 when the If-None-Match header already has it.
+//With the currency query parameter the course is answered along with its price in the currency. Courses which are
+//not published are only found by their owners.
+func GetCourse(c echo.Context) error {
+	cr, err := courseservice.GetInstance().FindOne(c.Param("id"))
 	if serr, ok := err.(*cache.RedisErr); ok {
 		c.Logger().Warn(serr)
 		err = nil
 	}
 	if err == nil {
-		return c.JSON(httpStatus, cr)
+		var ok bool
+		if ok, err = visible(c, cr); err == nil && !ok {
+			err = storage.ErrNotFound
+		}
 	}
-	httpStatus = http.StatusInternalServerError
-	if err == storage.ErrNotFound {
-		httpStatus = http.StatusNotFound
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		if err == storage.ErrNotFound {
+			httpStatus = http.StatusNotFound
+		}
+		return fail(c, httpStatus, err)
 	}
-	_ = c.NoContent(httpStatus)
-	return err
+
+	bs, err := bundleservice.GetInstance().FindByCourse(cr.ID)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return fail(c, http.StatusInternalServerError, err)
+	}
+	view, tag := courseView{Course: cr, Bundles: bs}, bundledETag(cr, bs)
+	if c.QueryParam("currency") != "" {
+		price, err := courseservice.PriceIn(cr, c.QueryParam("currency"))
+		if err != nil {
+			return fail(c, http.StatusBadRequest, err)
+		}
+		view.ResolvedPrice, tag = &price, pricedETag(cr, price, bs)
+	}
+	c.Response().Header().Set(HeaderETag, tag)
+	if notModified(c, tag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSON(http.StatusOK, view)
 }
 
-//GetCourses is a handler to get all courses
+//facetedPage is the body of a page of courses asking for the facets
+type facetedPage struct {
+	Courses []types.Course                  `json:"courses"`
+	Facets  map[string][]storage.FacetCount `json:"facets"`
+}
+
+//GetCourses is a handler to get a page of courses. It accepts the limit, cursor or offset, sort,
+//minPrice, maxPrice, which are decimal amounts of the currency query parameter, status, category and tag query
+//parameters and answers the total, the next page and the ETag of
+//the page in the headers, or 304 when the If-None-Match header already has the ETag. Only published courses are
+//public, authors list their own courses of the other statuses. With the facets query parameter the page is answered
+//along with the counts of the courses by category and tag.
 func GetCourses(c echo.Context) error {
-	cs, err := courseservice.GetInstance().FindAll()
+	return listCourses(c, courseservice.ListOptions{})
+}
 
+//GetTrash is a handler to get a page of the courses in the trash, the same way GetCourses does. Authors list their
+//own courses only.
+func GetTrash(c echo.Context) error {
+	return listCourses(c, courseservice.ListOptions{Deleted: true})
+}
+
+//listCourses answers the page of courses of the query parameters, within the trash, the instructor or the category
+//of scope
+func listCourses(c echo.Context, scope courseservice.ListOptions) error {
+	opts, err := listOptions(c)
+	if err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	opts.Deleted, opts.Instructor = scope.Deleted, scope.Instructor
+	if scope.Category != "" {
+		opts.Category = scope.Category
+	}
+	if err := authorOnly(c, &opts); err != nil {
+		return fail(c, http.StatusForbidden, err)
+	}
+
+	p, err := courseservice.GetInstance().FindAll(opts)
 	if serr, ok := err.(*cache.RedisErr); ok {
 		c.Logger().Warn(serr)
 		err = nil
 	}
-	if cs == nil {
-		cs = []types.Course{}
+	if p.Courses == nil {
+		p.Courses = []types.Course{}
 	}
 	if err == nil {
-		return c.JSON(http.StatusOK, cs)
+		setPageHeaders(c, opts, p)
+		tag := pageETag(p)
+		c.Response().Header().Set(HeaderETag, tag)
+		if notModified(c, tag) {
+			return c.NoContent(http.StatusNotModified)
+		}
+		if opts.Facets {
+			return c.JSON(http.StatusOK, facetedPage{Courses: p.Courses, Facets: p.Facets})
+		}
+		return c.JSON(http.StatusOK, p.Courses)
 	}
-	_ = c.NoContent(http.StatusInternalServerError)
-	return err
+	return fail(c, http.StatusInternalServerError, err)
 }
 
-//SetCourse is a handler to create a course passing a type.Course in the body
+//SearchCourses is a handler to search courses by name and description passing the query parameter q.
+//It accepts the same pagination and filters as GetCourses, results are ranked by relevance.
+func SearchCourses(c echo.Context) error {
+	text := c.QueryParam("q")
+	if text == "" {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "q is required"))
+	}
+	opts, err := listOptions(c)
+	if err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := authorOnly(c, &opts); err != nil {
+		return fail(c, http.StatusForbidden, err)
+	}
+
+	hits, err := courseservice.GetInstance().Search(text, opts)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if hits == nil {
+		hits = []types.CourseHit{}
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, hits)
+	}
+	return fail(c, http.StatusInternalServerError, err)
+}
+
+//SetCourse is a handler to create a course passing a type.Course in the body. Duplicated names are a conflict
+//and unknown instructors a bad request.
 func SetCourse(c echo.Context) error {
 	var cr types.Course
 
 	if err := c.Bind(&cr); err != nil {
-		_ = c.NoContent(http.StatusBadRequest)
-		return err
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&cr); err != nil {
+		return fail(c, http.StatusBadRequest, err)
 	}
 
-	err := courseservice.GetInstance().Create(cr)
+	cr, err := courseservice.GetInstance().Create(c.Request().Context(), cr)
 	if serr, ok := err.(*cache.RedisErr); ok {
 		c.Logger().Warn(serr)
 		err = nil
 	}
 	if err == nil {
+		c.Response().Header().Set(HeaderETag, courseETag(cr))
 		return c.JSON(http.StatusOK, cr)
 	}
 
-	_ = c.NoContent(http.StatusInternalServerError)
-	return err
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrDuplicate:
+		httpStatus = http.StatusConflict
+	case courseservice.ErrUnknownInstructor, courseservice.ErrUnknownCategory, courseservice.ErrInvalidPrices:
+		httpStatus = http.StatusBadRequest
+	}
+	return fail(c, httpStatus, err)
 }
 
-//PutCourse is a handler to update a course passing a type.Course in the body
+//PutCourse is a handler to update the course of the path parameter id passing a type.Course in the body.
+//When the If-Match header is sent the course is only updated if it still has that ETag.
 func PutCourse(c echo.Context) error {
 	var cr types.Course
 
 	if err := c.Bind(&cr); err != nil {
-		_ = c.NoContent(http.StatusBadRequest)
-		return err
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&cr); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		return fail(c, http.StatusPreconditionFailed, err)
 	}
 
-	err := courseservice.GetInstance().Update(cr)
+	cr.ID, cr.Version = c.Param("id"), version
+	cr, err = courseservice.GetInstance().Update(c.Request().Context(), cr)
 	if serr, ok := err.(*cache.RedisErr); ok {
 		c.Logger().Warn(serr)
 		err = nil
 	}
 	if err == nil {
+		c.Response().Header().Set(HeaderETag, courseETag(cr))
 		return c.JSON(http.StatusCreated, cr)
 	}
 
-	_ = c.NoContent(http.StatusInternalServerError)
-	return err
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case storage.ErrDuplicate:
+		httpStatus = http.StatusConflict
+	case courseservice.ErrUnknownInstructor, courseservice.ErrUnknownCategory, courseservice.ErrInvalidPrices:
+		httpStatus = http.StatusBadRequest
+	case courseservice.ErrNotOwner:
+		httpStatus = http.StatusForbidden
+	case courseservice.ErrVersionMismatch:
+		httpStatus = http.StatusPreconditionFailed
+	}
+	return fail(c, httpStatus, err)
+}
+
+//PatchCourse is a handler to change some fields of the course which id or slug is the path parameter id.
+//The body is a merge patch (application/merge-patch+json) or a JSON patch (application/json-patch+json).
+//When the If-Match header is sent the course is only changed if it still has that ETag.
+func PatchCourse(c echo.Context) error {
+	patchType, _, err := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if err != nil {
+		return fail(c, http.StatusUnsupportedMediaType, err)
+	}
+	patch, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		return fail(c, http.StatusPreconditionFailed, err)
+	}
+
+	cr, err := courseservice.GetInstance().Patch(c.Request().Context(), c.Param("id"), version, patchType, patch, c.Validate)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		c.Response().Header().Set(HeaderETag, courseETag(cr))
+		return c.JSON(http.StatusOK, cr)
+	}
+
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case storage.ErrDuplicate:
+		httpStatus = http.StatusConflict
+	case courseservice.ErrUnknownInstructor, courseservice.ErrUnknownCategory, courseservice.ErrInvalidPrices,
+		courseservice.ErrInvalidPatch:
+		httpStatus = http.StatusBadRequest
+	case courseservice.ErrUnsupportedPatch:
+		httpStatus = http.StatusUnsupportedMediaType
+	case courseservice.ErrNotOwner:
+		httpStatus = http.StatusForbidden
+	case courseservice.ErrVersionMismatch:
+		httpStatus = http.StatusPreconditionFailed
+	}
+	if _, ok := err.(validator.ValidationErrors); ok {
+		httpStatus = http.StatusBadRequest
+	}
+	return fail(c, httpStatus, err)
 }
 
-//DelCourse is a handler that deletes a course tha has a the query parameter name
+//DelCourse is a handler that moves to the trash the course which id or slug is the path parameter id.
+//When the If-Match header is sent the course is only deleted if it still has that ETag.
+//Courses sold in an active bundle are a conflict, they are deleted once out of the bundle or the bundle is inactive.
 func DelCourse(c echo.Context) error {
-	name := c.Param("name")
-	httpStatus := http.StatusOK
+	version, err := ifMatch(c)
+	if err != nil {
+		return fail(c, http.StatusPreconditionFailed, err)
+	}
 
-	err := courseservice.GetInstance().Delete(name)
+	err = courseservice.GetInstance().Delete(c.Request().Context(), c.Param("id"), version)
 	if serr, ok := err.(*cache.RedisErr); ok {
 		c.Logger().Warn(serr)
 		err = nil
 	}
+	if err == nil {
+		return c.NoContent(http.StatusOK)
+	}
+
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case courseservice.ErrNotOwner:
+		httpStatus = http.StatusForbidden
+	case courseservice.ErrVersionMismatch:
+		httpStatus = http.StatusPreconditionFailed
+	case bundleservice.ErrInActiveBundle:
+		httpStatus = http.StatusConflict
+	}
+	return fail(c, httpStatus, err)
+}
+
+//RestoreCourse is a handler that takes out of the trash the course which id or slug is the path parameter id. Courses
+//named like a live course are a conflict.
+func RestoreCourse(c echo.Context) error {
+	cr, err := courseservice.GetInstance().Restore(c.Request().Context(), c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		c.Response().Header().Set(HeaderETag, courseETag(cr))
+		return c.JSON(http.StatusOK, cr)
+	}
+
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case courseservice.ErrNotOwner:
+		httpStatus = http.StatusForbidden
+	case courseservice.ErrVersionMismatch, storage.ErrDuplicate:
+		httpStatus = http.StatusConflict
+	}
+	return fail(c, httpStatus, err)
+}
+
+//GetCourseHistory is a handler to get the audit entries of the course which id or slug is the path parameter id.
+//The history, its diffs and revisions are seen by the ones checkHistory lets see them.
+func GetCourseHistory(c echo.Context) error {
+	var entries []types.AuditEntry
+	err := checkHistory(c, c.Param("id"))
+	if err == nil {
+		entries, err = courseservice.GetInstance().History(c.Param("id"))
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, entries)
+	}
+
+	httpStatus := http.StatusInternalServerError
+	if err == storage.ErrNotFound {
+		httpStatus = http.StatusNotFound
+	}
+	return fail(c, httpStatus, err)
+}
+
+//GetCourseDiff is a handler to get the fields changed between the versions of the query parameters from and to
+//of the course which id or slug is the path parameter id
+func GetCourseDiff(c echo.Context) error {
+	from, ferr := strconv.ParseInt(c.QueryParam("from"), 10, 64)
+	to, terr := strconv.ParseInt(c.QueryParam("to"), 10, 64)
+	if ferr != nil || terr != nil {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "from and to must be versions"))
+	}
+
+	var changes []types.FieldChange
+	err := checkHistory(c, c.Param("id"))
+	if err == nil {
+		changes, err = courseservice.GetInstance().Diff(c.Param("id"), from, to)
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, changes)
+	}
+
+	httpStatus := http.StatusInternalServerError
+	if err == storage.ErrNotFound {
+		httpStatus = http.StatusNotFound
+	}
+	return fail(c, httpStatus, err)
+}
+
+//GetCourseRevision is a handler to get the revision, path parameter rev, of the course which id or slug is the path parameter id
+func GetCourseRevision(c echo.Context) error {
+	rev, err := strconv.ParseInt(c.Param("rev"), 10, 64)
 	if err != nil {
-		httpStatus = http.StatusInternalServerError
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "rev must be a version"))
+	}
+
+	var r types.Revision
+	err = checkHistory(c, c.Param("id"))
+	if err == nil {
+		r, err = courseservice.GetInstance().Revision(c.Param("id"), rev)
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, r)
 	}
+
+	httpStatus := http.StatusInternalServerError
 	if err == storage.ErrNotFound {
 		httpStatus = http.StatusNotFound
 	}
-	_ = c.NoContent(httpStatus)
+	return fail(c, httpStatus, err)
+}
+
+//checkHistory fails with storage.ErrNotFound when the actor of the request does not see the history of the course
+//which id or slug is ref. The history of a live course is seen by the ones who see the course, the one of a course
+//in the trash or purged by its owners only, as they are for its last version.
+func checkHistory(c echo.Context, ref string) error {
+	cr, err := courseservice.GetInstance().FindOne(ref)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	var ok bool
+	switch {
+	case err == nil:
+		ok, err = visible(c, cr)
+	case err == storage.ErrNotFound:
+		var entries []types.AuditEntry
+		if entries, err = courseservice.GetInstance().History(ref); err == nil && len(entries) > 0 {
+			last := entries[len(entries)-1]
+			if last.After != nil {
+				cr = *last.After
+			} else {
+				cr = *last.Before
+			}
+			ok, err = ownsCourse(c, cr)
+		}
+	}
+	if err == nil && !ok {
+		err = storage.ErrNotFound
+	}
 	return err
 }
+
+//RevertCourse is a handler that saves the revision, path parameter rev, of the course which id or slug is the path
+//parameter id as its next version. When the If-Match header is sent the course is only reverted if it still has that ETag.
+func RevertCourse(c echo.Context) error {
+	rev, err := strconv.ParseInt(c.Param("rev"), 10, 64)
+	if err != nil {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "rev must be a version"))
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		return fail(c, http.StatusPreconditionFailed, err)
+	}
+
+	cr, err := courseservice.GetInstance().Revert(c.Request().Context(), c.Param("id"), rev, version)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		c.Response().Header().Set(HeaderETag, courseETag(cr))
+		return c.JSON(http.StatusOK, cr)
+	}
+
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case storage.ErrDuplicate:
+		httpStatus = http.StatusConflict
+	case courseservice.ErrUnknownInstructor, courseservice.ErrUnknownCategory, courseservice.ErrInvalidPrices:
+		httpStatus = http.StatusBadRequest
+	case courseservice.ErrNotOwner:
+		httpStatus = http.StatusForbidden
+	case courseservice.ErrVersionMismatch:
+		httpStatus = http.StatusPreconditionFailed
+	}
+	return fail(c, httpStatus, err)
+}
+
+//SubmitCourse is a handler that sends to review the draft which id or slug is the path parameter id
+func SubmitCourse(c echo.Context) error {
+	return transitionCourse(c, types.StatusInReview)
+}
+
+//PublishCourse is a handler that publishes the course in review which id or slug is the path parameter id
+func PublishCourse(c echo.Context) error {
+	return transitionCourse(c, types.StatusPublished)
+}
+
+//UnpublishCourse is a handler that moves back to draft the course which id or slug is the path parameter id
+func UnpublishCourse(c echo.Context) error {
+	return transitionCourse(c, types.StatusDraft)
+}
+
+//ArchiveCourse is a handler that archives the course which id or slug is the path parameter id
+func ArchiveCourse(c echo.Context) error {
+	return transitionCourse(c, types.StatusArchived)
+}
+
+//transitionRequest is the body of the transitions, which is optional. A future at schedules the transition.
+type transitionRequest struct {
+	At time.Time `json:"at"`
+}
+
+//transitionCourse moves the course of the path parameter id to the status. Transitions the lifecycle does not
+//allow are a conflict. When the If-Match header is sent the course only moves if it still has that ETag.
+func transitionCourse(c echo.Context, status string) error {
+	var req transitionRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return fail(c, http.StatusBadRequest, err)
+		}
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		return fail(c, http.StatusPreconditionFailed, err)
+	}
+
+	cr, err := courseservice.GetInstance().Transition(c.Request().Context(), c.Param("id"), status, req.At, version)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		c.Response().Header().Set(HeaderETag, courseETag(cr))
+		return c.JSON(http.StatusOK, cr)
+	}
+
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case courseservice.ErrInvalidTransition:
+		httpStatus = http.StatusConflict
+	case courseservice.ErrNotOwner:
+		httpStatus = http.StatusForbidden
+	case courseservice.ErrVersionMismatch:
+		httpStatus = http.StatusPreconditionFailed
+	}
+	return fail(c, httpStatus, err)
+}
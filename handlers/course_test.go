@@ -4,21 +4,31 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ednesic/coursemanagement/actor"
 	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/services/bundleservice"
 	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/instructorservice"
 	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
 	"github.com/ednesic/coursemanagement/types"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gopkg.in/go-playground/validator.v9"
 	"gopkg.in/mgo.v2"
 )
 
+//usd is an amount of cents
 func TestGetCourse(t *testing.T) {
 	type fields struct {
 		name    string
@@ -34,8 +44,8 @@ func TestGetCourse(t *testing.T) {
 		fields fields
 		want   wants
 	}{
-		{"Status ok", fields{name: "nameTest"}, wants{course: types.Course{Name: "nameTest", Price: 10, Picture: "pic.png", PreviewURLVideo: "http://video"}, statusCode: http.StatusOK, err: nil}},
-		{"Status ok but redis err", fields{mockErr: &cache.RedisErr{}, name: "nameTest"}, wants{course: types.Course{Name: "nameTest", Price: 10, Picture: "pic.png", PreviewURLVideo: "http://video"}, statusCode: http.StatusOK, err: nil}},
+		{"Status ok", fields{name: "nameTest"}, wants{course: types.Course{Name: "nameTest", Price: testutil.USD(10), Picture: "pic.png", PreviewURLVideo: "http://video"}, statusCode: http.StatusOK, err: nil}},
+		{"Status ok but redis err", fields{mockErr: &cache.RedisErr{}, name: "nameTest"}, wants{course: types.Course{Name: "nameTest", Price: testutil.USD(10), Picture: "pic.png", PreviewURLVideo: "http://video"}, statusCode: http.StatusOK, err: nil}},
 		{"Status notFound", fields{mockErr: storage.ErrNotFound, name: "nameNotFound"}, wants{course: types.Course{}, statusCode: http.StatusNotFound, err: storage.ErrNotFound}},
 		{"Status internal server error", fields{mockErr: mgo.ErrCursor, name: "nameInternal"}, wants{course: types.Course{}, statusCode: http.StatusInternalServerError, err: mgo.ErrCursor}},
 	}
@@ -44,12 +54,13 @@ func TestGetCourse(t *testing.T) {
 			var courseServiceMngr = &courseservice.Mock{}
 			courseServiceMngr.On("FindOne", tt.fields.name).Return(tt.want.course, tt.fields.mockErr).Once()
 			courseServiceMngr.InitMock()
+			bundleServiceMngr := mockBundles("", nil)
 
 			e := echo.New()
 			req := httptest.NewRequest(http.MethodGet, "/course", nil)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			c.SetParamNames("name")
+			c.SetParamNames("id")
 			c.SetParamValues(tt.fields.name)
 
 			out, err := json.Marshal(tt.want.course)
@@ -58,6 +69,52 @@ func TestGetCourse(t *testing.T) {
 			assert.Equal(t, tt.want.statusCode, rec.Code)
 			if tt.want.err == nil {
 				assert.Equal(t, fmt.Sprintf("%s\n", out), rec.Body.String())
+				bundleServiceMngr.AssertExpectations(t)
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourse_Currency(t *testing.T) {
+	rates := filepath.Join(t.TempDir(), "rates.json")
+	assert.NoError(t, ioutil.WriteFile(rates, []byte(`{"base": "USD", "rates": {"EUR": 0.92, "GBP": 0.8}}`), 0600))
+	assert.NoError(t, money.NewStatic().Initialize(map[string]string{"file": rates}))
+	course := types.Course{ID: "id01", Version: 3, Name: "Go", Price: testutil.USD(1000), Prices: []types.Money{{Amount: 900, Currency: "EUR"}}}
+	tests := []struct {
+		name       string
+		currency   string
+		statusCode int
+		resolved   string
+		etag       string
+	}{
+		{"Price point", "EUR", http.StatusOK, `{"amount":900,"currency":"EUR"}`, `W/"3:900EUR"`},
+		{"Converted", "gbp", http.StatusOK, `{"amount":800,"currency":"GBP"}`, `W/"3:800GBP"`},
+		{"Own currency", "USD", http.StatusOK, `{"amount":1000,"currency":"USD"}`, `W/"3:1000USD"`},
+		{"No rate", "BRL", http.StatusBadRequest, "", ""},
+		{"Unknown currency", "XYZ", http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindOne", "id01").Return(course, nil).Once()
+			courseServiceMngr.InitMock()
+			mockBundles("id01", nil)
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/courses/id01?currency="+tt.currency, nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = GetCourse(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			assert.Equal(t, tt.etag, rec.Header().Get(HeaderETag))
+			if tt.statusCode == http.StatusOK {
+				var body map[string]json.RawMessage
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.JSONEq(t, tt.resolved, string(body["resolvedPrice"]))
+				assert.JSONEq(t, `{"amount":1000,"currency":"USD"}`, string(body["price"]))
 			}
 			courseServiceMngr.AssertExpectations(t)
 		})
@@ -68,12 +125,15 @@ func BenchmarkGetCourse(b *testing.B) {
 	var courseServiceMngr = &courseservice.Mock{}
 	courseServiceMngr.On("FindOne", mock.Anything).Return(types.Course{Name: "bench"}, nil)
 	courseServiceMngr.InitMock()
+	var bundleServiceMngr = &bundleservice.Mock{}
+	bundleServiceMngr.On("FindByCourse", mock.Anything).Return([]types.Bundle(nil), nil)
+	bundleServiceMngr.InitMock()
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/course", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.SetParamNames("name")
+	c.SetParamNames("id")
 	c.SetParamValues("Bench")
 
 	for i := 0; i < b.N; i++ {
@@ -104,7 +164,8 @@ func TestGetCourses(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var courseServiceMngr = &courseservice.Mock{}
-			courseServiceMngr.On("FindAll").Return(tt.mock.courses, tt.mock.err).Once()
+			courseServiceMngr.On("FindAll", courseservice.ListOptions{Limit: defaultPageLimit}).
+				Return(courseservice.Page{Courses: tt.mock.courses, Total: int64(len(tt.mock.courses))}, tt.mock.err).Once()
 			courseServiceMngr.InitMock()
 
 			e := echo.New()
@@ -124,9 +185,82 @@ func TestGetCourses(t *testing.T) {
 	}
 }
 
+func TestGetCourses_Pagination(t *testing.T) {
+	minPrice, maxYen := int64(500), int64(1500)
+	tests := []struct {
+		name       string
+		query      string
+		opts       courseservice.ListOptions
+		page       courseservice.Page
+		statusCode int
+		total      string
+		next       string
+	}{
+		{"First page", "?limit=2&sort=-price&minPrice=5", courseservice.ListOptions{Limit: 2, Sort: "-price", MinPrice: &minPrice}, courseservice.Page{Courses: []types.Course{{}, {}}, Total: 5}, http.StatusOK, "5", encodeCursor(2)},
+		{"Cursor page", "?limit=2&cursor=" + encodeCursor(2), courseservice.ListOptions{Limit: 2, Offset: 2}, courseservice.Page{Courses: []types.Course{{}, {}}, Total: 5}, http.StatusOK, "5", encodeCursor(4)},
+		{"Last page", "?limit=2&offset=4", courseservice.ListOptions{Limit: 2, Offset: 4}, courseservice.Page{Courses: []types.Course{{}}, Total: 5}, http.StatusOK, "5", ""},
+		{"Bad limit", "?limit=1000", courseservice.ListOptions{}, courseservice.Page{}, http.StatusBadRequest, "", ""},
+		{"Bad cursor", "?cursor=not*a*cursor", courseservice.ListOptions{}, courseservice.Page{}, http.StatusBadRequest, "", ""},
+		{"Bad sort", "?sort=picture", courseservice.ListOptions{}, courseservice.Page{}, http.StatusBadRequest, "", ""},
+		{"Price in a currency", "?maxPrice=1500&currency=jpy", courseservice.ListOptions{Limit: defaultPageLimit, MaxPrice: &maxYen, Currency: "JPY"}, courseservice.Page{}, http.StatusOK, "0", ""},
+		{"Bad price", "?maxPrice=ten", courseservice.ListOptions{}, courseservice.Page{}, http.StatusBadRequest, "", ""},
+		{"Bad price decimal places", "?minPrice=1.5&currency=JPY", courseservice.ListOptions{}, courseservice.Page{}, http.StatusBadRequest, "", ""},
+		{"Unknown currency", "?currency=XYZ", courseservice.ListOptions{}, courseservice.Page{}, http.StatusBadRequest, "", ""},
+		{"Category and tags", "?category=c1&tag=Go&tag=web", courseservice.ListOptions{Limit: defaultPageLimit, Category: "c1", Tags: []string{"go", "web"}}, courseservice.Page{}, http.StatusOK, "0", ""},
+		{"Bad facets", "?facets=maybe", courseservice.ListOptions{}, courseservice.Page{}, http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			if tt.statusCode == http.StatusOK {
+				courseServiceMngr.On("FindAll", tt.opts).Return(tt.page, nil).Once()
+			}
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/courses"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			_ = GetCourses(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			assert.Equal(t, tt.total, rec.Header().Get(HeaderTotalCount))
+			assert.Equal(t, tt.next, rec.Header().Get(HeaderNextCursor))
+			if tt.next != "" {
+				assert.Contains(t, rec.Header().Get(HeaderLink), "cursor="+tt.next)
+				assert.Contains(t, rec.Header().Get(HeaderLink), `rel="next"`)
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourses_Facets(t *testing.T) {
+	page := courseservice.Page{
+		Courses: []types.Course{{ID: "id01", Name: "Go", Version: 1}},
+		Total:   1,
+		Facets:  map[string][]storage.FacetCount{"category": {{Value: "c1", Count: 1}}, "tag": {}},
+	}
+	var courseServiceMngr = &courseservice.Mock{}
+	courseServiceMngr.On("FindAll", courseservice.ListOptions{Limit: defaultPageLimit, Facets: true}).Return(page, nil).Once()
+	courseServiceMngr.InitMock()
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	_ = GetCourses(e.NewContext(httptest.NewRequest(http.MethodGet, "/courses?facets=true", nil), rec))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"courses":[{"id":"id01","version":1,"name":"Go","price":{"amount":0,"currency":""},"picture":"","preview-url-video":"","description":"","duration":0}],`+
+		`"facets":{"category":[{"value":"c1","count":1}],"tag":[]}}`, rec.Body.String())
+
+	tag := pageETag(page)
+	page.Facets["tag"] = []storage.FacetCount{{Value: "go", Count: 1}}
+	assert.NotEqual(t, tag, pageETag(page))
+	courseServiceMngr.AssertExpectations(t)
+}
+
 func BenchmarkGetCourses(b *testing.B) {
 	var courseServiceMngr = &courseservice.Mock{}
-	courseServiceMngr.On("FindAll").Return([]types.Course{}, nil)
+	courseServiceMngr.On("FindAll", mock.Anything).Return(courseservice.Page{}, nil)
 	courseServiceMngr.InitMock()
 
 	e := echo.New()
@@ -139,6 +273,49 @@ func BenchmarkGetCourses(b *testing.B) {
 	}
 }
 
+func TestSearchCourses(t *testing.T) {
+	hits := []types.CourseHit{{Course: types.Course{Name: "Go"}, Highlights: map[string]string{"name": "<em>Go</em>"}}}
+	tests := []struct {
+		name       string
+		query      string
+		hits       []types.CourseHit
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok", "?q=go", hits, nil, http.StatusOK},
+		{"Status ok(empty)", "?q=go", nil, nil, http.StatusOK},
+		{"Status ok but redis err", "?q=go", hits, &cache.RedisErr{}, http.StatusOK},
+		{"Status bad request", "", nil, nil, http.StatusBadRequest},
+		{"Status internal server error", "?q=go", nil, mgo.ErrCursor, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("Search", "go", courseservice.ListOptions{Limit: defaultPageLimit}).
+				Return(tt.hits, tt.mockErr).Maybe()
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/courses/search"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			_ = SearchCourses(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.statusCode == http.StatusOK {
+				want := tt.hits
+				if want == nil {
+					want = []types.CourseHit{}
+				}
+				out, err := json.Marshal(want)
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf("%s\n", out), rec.Body.String())
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
 func TestSetCourse(t *testing.T) {
 	type wants struct {
 		err        error
@@ -158,21 +335,24 @@ func TestSetCourse(t *testing.T) {
 		mock  mocks
 		field fields
 	}{
-		{"Status ok", wants{statusCode: http.StatusOK}, mocks{mongoMockTimes: 1, course: types.Course{Name: "Test123", Price: 10, Picture: "test.png", PreviewURLVideo: "http://video"}}, fields{types.Course{Name: "Test123", Price: 10, Picture: "test.png", PreviewURLVideo: "http://video"}}},
-		{"Status ok but redis err", wants{statusCode: http.StatusOK}, mocks{err: &cache.RedisErr{}, mongoMockTimes: 1, course: types.Course{Name: "Test123", Price: 10, Picture: "test.png", PreviewURLVideo: "http://video"}}, fields{types.Course{Name: "Test123", Price: 10, Picture: "test.png", PreviewURLVideo: "http://video"}}},
+		{"Status ok", wants{statusCode: http.StatusOK}, mocks{mongoMockTimes: 1, course: types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}, fields{types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}},
+		{"Status ok but redis err", wants{statusCode: http.StatusOK}, mocks{err: &cache.RedisErr{}, mongoMockTimes: 1, course: types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}, fields{types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}},
 		{"Status bad request", wants{statusCode: http.StatusBadRequest, err: &echo.HTTPError{}}, mocks{}, fields{"{err}"}},
-		{"Status internal server error", wants{statusCode: http.StatusInternalServerError, err: errors.New("")}, mocks{mongoMockTimes: 1, err: mgo.ErrCursor}, fields{types.Course{Name: "Test123", Price: 10, Picture: "test.png", PreviewURLVideo: "http://video"}}},
+		{"Status bad request invalid course", wants{statusCode: http.StatusBadRequest, err: validator.ValidationErrors{}}, mocks{}, fields{types.Course{Price: testutil.USD(-1), Picture: "test.png"}}},
+		{"Status internal server error", wants{statusCode: http.StatusInternalServerError, err: errors.New("")}, mocks{mongoMockTimes: 1, err: mgo.ErrCursor}, fields{types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}},
+		{"Status conflict", wants{statusCode: http.StatusConflict, err: errors.New("")}, mocks{mongoMockTimes: 1, err: storage.ErrDuplicate}, fields{types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var courseServiceMngr = &courseservice.Mock{}
-			courseServiceMngr.On("Create", tt.field.body).Return(tt.mock.err).Maybe().Times(tt.mock.mongoMockTimes)
+			courseServiceMngr.On("Create", mock.Anything, tt.field.body).Return(tt.mock.course, tt.mock.err).Maybe().Times(tt.mock.mongoMockTimes)
 			courseServiceMngr.InitMock()
 
 			out, err := json.Marshal(tt.field.body)
 			assert.NoError(t, err)
 
 			e := echo.New()
+			e.Validator = NewValidator()
 			req := httptest.NewRequest(http.MethodPost, "/course", strings.NewReader(string(out)))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
@@ -193,11 +373,12 @@ func TestSetCourse(t *testing.T) {
 
 func BenchmarkSetCourse(b *testing.B) {
 	var courseServiceMngr = &courseservice.Mock{}
-	courseServiceMngr.On("Create", mock.Anything).Return(nil)
+	courseServiceMngr.On("Create", mock.Anything, mock.Anything).Return(types.Course{}, nil)
 	courseServiceMngr.InitMock()
 
-	out, _ := json.Marshal(types.Course{Name: "BEnch1", Price: 10, Picture: "bench", PreviewURLVideo: "bench"})
+	out, _ := json.Marshal(types.Course{Name: "BEnch1", Price: testutil.USD(10), Picture: "bench", PreviewURLVideo: "bench"})
 	e := echo.New()
+	e.Validator = NewValidator()
 	req := httptest.NewRequest(http.MethodPost, "/course", strings.NewReader(string(out)))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -227,21 +408,25 @@ func TestPutCourse(t *testing.T) {
 		mock  mocks
 		field fields
 	}{
-		{"Status ok", wants{statusCode: http.StatusCreated}, mocks{mongoMockTimes: 1, course: types.Course{Name: "Test123", Price: 10, Picture: "test.png", PreviewURLVideo: "http://video"}}, fields{types.Course{Name: "Test123", Price: 10, Picture: "test.png", PreviewURLVideo: "http://video"}}},
-		{"Status ok but redis err", wants{statusCode: http.StatusCreated}, mocks{err: &cache.RedisErr{}, mongoMockTimes: 1, course: types.Course{Name: "Test123", Price: 10, Picture: "test.png", PreviewURLVideo: "http://video"}}, fields{types.Course{Name: "Test123", Price: 10, Picture: "test.png", PreviewURLVideo: "http://video"}}},
+		{"Status ok", wants{statusCode: http.StatusCreated}, mocks{mongoMockTimes: 1, course: types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}, fields{types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}},
+		{"Status ok but redis err", wants{statusCode: http.StatusCreated}, mocks{err: &cache.RedisErr{}, mongoMockTimes: 1, course: types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}, fields{types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}},
 		{"Status bad request", wants{statusCode: http.StatusBadRequest, err: &echo.HTTPError{}}, mocks{}, fields{"{err}"}},
-		{"Status internal server error", wants{statusCode: http.StatusInternalServerError, err: errors.New("")}, mocks{mongoMockTimes: 1, err: mgo.ErrCursor}, fields{types.Course{Name: "Test123", Price: 10, Picture: "test.png", PreviewURLVideo: "http://video"}}},
+		{"Status bad request invalid course", wants{statusCode: http.StatusBadRequest, err: validator.ValidationErrors{}}, mocks{}, fields{types.Course{Price: testutil.USD(-1), Picture: "test.png"}}},
+		{"Status internal server error", wants{statusCode: http.StatusInternalServerError, err: errors.New("")}, mocks{mongoMockTimes: 1, err: mgo.ErrCursor}, fields{types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}},
+		{"Status not found", wants{statusCode: http.StatusNotFound, err: errors.New("")}, mocks{mongoMockTimes: 1, err: storage.ErrNotFound}, fields{types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}},
+		{"Status conflict", wants{statusCode: http.StatusConflict, err: errors.New("")}, mocks{mongoMockTimes: 1, err: storage.ErrDuplicate}, fields{types.Course{Name: "Test123", Price: testutil.USD(10), Picture: "http://cdn/test.png", PreviewURLVideo: "http://video"}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var courseServiceMngr = &courseservice.Mock{}
-			courseServiceMngr.On("Update", tt.field.body).Return(tt.mock.err).Maybe().Times(tt.mock.mongoMockTimes)
+			courseServiceMngr.On("Update", mock.Anything, tt.field.body).Return(tt.mock.course, tt.mock.err).Maybe().Times(tt.mock.mongoMockTimes)
 			courseServiceMngr.InitMock()
 
 			out, err := json.Marshal(tt.field.body)
 			assert.NoError(t, err)
 
 			e := echo.New()
+			e.Validator = NewValidator()
 			req := httptest.NewRequest(http.MethodPut, "/course", strings.NewReader(string(out)))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
@@ -262,11 +447,12 @@ func TestPutCourse(t *testing.T) {
 
 func BenchmarkPutCourse(b *testing.B) {
 	var courseServiceMngr = &courseservice.Mock{}
-	courseServiceMngr.On("Update", mock.Anything).Return(nil)
+	courseServiceMngr.On("Update", mock.Anything, mock.Anything).Return(types.Course{}, nil)
 	courseServiceMngr.InitMock()
 
-	out, _ := json.Marshal(types.Course{Name: "BEnch1", Price: 10, Picture: "bench", PreviewURLVideo: "bench"})
+	out, _ := json.Marshal(types.Course{Name: "BEnch1", Price: testutil.USD(10), Picture: "bench", PreviewURLVideo: "bench"})
 	e := echo.New()
+	e.Validator = NewValidator()
 	req := httptest.NewRequest(http.MethodPut, "/course", strings.NewReader(string(out)))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
@@ -292,22 +478,22 @@ func TestDelCourse(t *testing.T) {
 		fields fields
 		want   wants
 	}{
-		{"Status ok", fields{name: "nameTest"}, wants{course: types.Course{Name: "nameTest", Price: 10, Picture: "pic.png", PreviewURLVideo: "http://video"}, statusCode: http.StatusOK, err: nil}},
-		{"Status ok but redis err", fields{err: &cache.RedisErr{}, name: "nameTest"}, wants{course: types.Course{Name: "nameTest", Price: 10, Picture: "pic.png", PreviewURLVideo: "http://video"}, statusCode: http.StatusOK, err: nil}},
+		{"Status ok", fields{name: "nameTest"}, wants{course: types.Course{Name: "nameTest", Price: testutil.USD(10), Picture: "pic.png", PreviewURLVideo: "http://video"}, statusCode: http.StatusOK, err: nil}},
+		{"Status ok but redis err", fields{err: &cache.RedisErr{}, name: "nameTest"}, wants{course: types.Course{Name: "nameTest", Price: testutil.USD(10), Picture: "pic.png", PreviewURLVideo: "http://video"}, statusCode: http.StatusOK, err: nil}},
 		{"Status notFound", fields{err: storage.ErrNotFound, name: "nameNotFound"}, wants{course: types.Course{}, statusCode: http.StatusNotFound, err: storage.ErrNotFound}},
 		{"Status internal server error", fields{err: mgo.ErrCursor, name: "nameInternal"}, wants{course: types.Course{}, statusCode: http.StatusInternalServerError, err: mgo.ErrCursor}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var courseServiceMngr = &courseservice.Mock{}
-			courseServiceMngr.On("Delete", tt.fields.name).Return(tt.fields.err).Once()
+			courseServiceMngr.On("Delete", mock.Anything, tt.fields.name, int64(0)).Return(tt.fields.err).Once()
 			courseServiceMngr.InitMock()
 
 			e := echo.New()
 			req := httptest.NewRequest(http.MethodDelete, "/course", nil)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			c.SetParamNames("name")
+			c.SetParamNames("id")
 			c.SetParamValues(tt.fields.name)
 
 			assert.Equal(t, DelCourse(c), tt.want.err)
@@ -319,17 +505,676 @@ func TestDelCourse(t *testing.T) {
 
 func BenchmarkDelCourse(b *testing.B) {
 	var courseServiceMngr = &courseservice.Mock{}
-	courseServiceMngr.On("Delete", mock.Anything).Return(nil)
+	courseServiceMngr.On("Delete", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	courseServiceMngr.InitMock()
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodDelete, "/course", nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
-	c.SetParamNames("name")
+	c.SetParamNames("id")
 	c.SetParamValues("Bench")
 
 	for i := 0; i < b.N; i++ {
 		_ = DelCourse(c)
 	}
 }
+
+func TestSetCourse_InvalidFields(t *testing.T) {
+	e := echo.New()
+	e.Validator = NewValidator()
+	body := `{"price": {"amount": -1, "currency": "usd"}, "prices": [{"amount": 900, "currency": "EURO"}], "picture": "test.png"}`
+	req := httptest.NewRequest(http.MethodPost, "/course", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Response().Header().Set(echo.HeaderXRequestID, "req01")
+
+	assert.IsType(t, validator.ValidationErrors{}, SetCourse(c))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var res ErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, ErrorResponse{
+		Code:    CodeInvalid,
+		Message: "invalid fields",
+		Fields: []FieldError{
+			{Field: "name", Message: "is required"},
+			{Field: "price.amount", Message: "must be greater than or equal to 0"},
+			{Field: "prices[0].currency", Message: "must be an ISO 4217 currency code"},
+			{Field: "picture", Message: "must be an absolute URL"},
+		},
+		RequestID: "req01",
+	}, res)
+}
+
+func TestGetCourse_ErrorResponse(t *testing.T) {
+	var courseServiceMngr = &courseservice.Mock{}
+	courseServiceMngr.On("FindOne", "missing").Return(types.Course{}, storage.ErrNotFound).Once()
+	courseServiceMngr.InitMock()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/course", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req02")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("missing")
+
+	assert.Equal(t, storage.ErrNotFound, GetCourse(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, `{"code":"not_found","message":"Not Found","requestId":"req02"}`+"\n", rec.Body.String())
+	courseServiceMngr.AssertExpectations(t)
+}
+
+func TestPatchCourse(t *testing.T) {
+	course := types.Course{ID: "id01", Slug: "go", Name: "Go"}
+	tests := []struct {
+		name        string
+		contentType string
+		mockErr     error
+		mockTimes   int
+		statusCode  int
+		err         error
+	}{
+		{"Status ok", courseservice.MergePatch, nil, 1, http.StatusOK, nil},
+		{"Status ok with charset", courseservice.JSONPatch + "; charset=utf-8", nil, 1, http.StatusOK, nil},
+		{"Status ok but redis err", courseservice.MergePatch, &cache.RedisErr{}, 1, http.StatusOK, nil},
+		{"Status unsupported media type", "", nil, 0, http.StatusUnsupportedMediaType, errors.New("")},
+		{"Status unsupported patch", echo.MIMEApplicationJSON, courseservice.ErrUnsupportedPatch, 1, http.StatusUnsupportedMediaType, courseservice.ErrUnsupportedPatch},
+		{"Status bad request invalid patch", courseservice.MergePatch, courseservice.ErrInvalidPatch, 1, http.StatusBadRequest, courseservice.ErrInvalidPatch},
+		{"Status bad request invalid course", courseservice.MergePatch, validator.ValidationErrors{}, 1, http.StatusBadRequest, validator.ValidationErrors{}},
+		{"Status not found", courseservice.MergePatch, storage.ErrNotFound, 1, http.StatusNotFound, storage.ErrNotFound},
+		{"Status conflict", courseservice.MergePatch, storage.ErrDuplicate, 1, http.StatusConflict, storage.ErrDuplicate},
+		{"Status internal server error", courseservice.MergePatch, mgo.ErrCursor, 1, http.StatusInternalServerError, mgo.ErrCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patchType := strings.Split(tt.contentType, ";")[0]
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("Patch", mock.Anything, course.ID, int64(0), patchType, []byte(`{"name":"Go"}`), mock.Anything).
+				Return(course, tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPatch, "/course", strings.NewReader(`{"name":"Go"}`))
+			req.Header.Set(echo.HeaderContentType, tt.contentType)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(course.ID)
+
+			err := PatchCourse(c)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.IsType(t, tt.err, err)
+			}
+			assert.Equal(t, tt.statusCode, rec.Code)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourse_ETag(t *testing.T) {
+	course := types.Course{ID: "id01", Version: 3, Name: "Go"}
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		statusCode  int
+	}{
+		{"Status ok without If-None-Match", "", http.StatusOK},
+		{"Status ok with other ETag", `"2"`, http.StatusOK},
+		{"Status not modified", `"1", "3"`, http.StatusNotModified},
+		{"Status not modified weak", `W/"3"`, http.StatusNotModified},
+		{"Status not modified any", "*", http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindOne", course.ID).Return(course, nil).Once()
+			courseServiceMngr.InitMock()
+			mockBundles(course.ID, nil)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/course", nil)
+			req.Header.Set(HeaderIfNoneMatch, tt.ifNoneMatch)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(course.ID)
+
+			assert.NoError(t, GetCourse(c))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			assert.Equal(t, `"3"`, rec.Header().Get(HeaderETag))
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourse_ETagRated(t *testing.T) {
+	course := types.Course{ID: "id01", Version: 3, Name: "Go"}
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		var courseServiceMngr = &courseservice.Mock{}
+		courseServiceMngr.On("FindOne", course.ID).Return(course, nil).Once()
+		courseServiceMngr.InitMock()
+		mockBundles(course.ID, []types.Bundle{{ID: "b01", Courses: []string{course.ID, "id02"}, Active: true}})
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/course", nil)
+		req.Header.Set(HeaderIfNoneMatch, ifNoneMatch)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(course.ID)
+		assert.NoError(t, GetCourse(c))
+		courseServiceMngr.AssertExpectations(t)
+		return rec
+	}
+
+	tag := get("").Header().Get(HeaderETag)
+	assert.Equal(t, http.StatusNotModified, get(tag).Code)
+
+	course.Rating = &types.Rating{Average: 4.5, Count: 2, Stars: 9}
+	rec := get(tag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rated := rec.Header().Get(HeaderETag)
+	assert.NotEqual(t, tag, rated)
+	assert.Equal(t, http.StatusNotModified, get(rated).Code)
+
+	course.Rating = &types.Rating{Average: 4, Count: 3, Stars: 12}
+	assert.Equal(t, http.StatusOK, get(rated).Code)
+}
+
+func TestGetCourses_ETag(t *testing.T) {
+	page := courseservice.Page{Courses: []types.Course{{ID: "id01", Version: 1}}, Total: 1}
+	var courseServiceMngr = &courseservice.Mock{}
+	courseServiceMngr.On("FindAll", mock.Anything).Return(page, nil).Twice()
+	courseServiceMngr.InitMock()
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/course", nil)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, GetCourses(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	tag := rec.Header().Get(HeaderETag)
+	assert.True(t, strings.HasPrefix(tag, `W/"`))
+
+	req = httptest.NewRequest(http.MethodGet, "/course", nil)
+	req.Header.Set(HeaderIfNoneMatch, tag)
+	rec = httptest.NewRecorder()
+	assert.NoError(t, GetCourses(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	page.Courses[0].Version = 2
+	assert.NotEqual(t, tag, pageETag(page))
+	rated := pageETag(page)
+	page.Courses[0].Rating = &types.Rating{Average: 4, Count: 1, Stars: 4}
+	assert.NotEqual(t, rated, pageETag(page))
+	courseServiceMngr.AssertExpectations(t)
+}
+
+func TestCourseChanges_IfMatch(t *testing.T) {
+	course := types.Course{ID: "id01", Version: 3, Name: "Go"}
+	tests := []struct {
+		name       string
+		ifMatch    string
+		version    int64
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Matching version", `"3"`, 3, nil, 1, http.StatusOK},
+		{"Any version", "*", 0, nil, 1, http.StatusOK},
+		{"Other version", `"2"`, 2, courseservice.ErrVersionMismatch, 1, http.StatusPreconditionFailed},
+		{"Weak ETag", `W/"3"`, 0, nil, 0, http.StatusPreconditionFailed},
+		{"Malformed ETag", "3", 0, nil, 0, http.StatusPreconditionFailed},
+		{"Matching version with bundles", `"3-9f2c"`, 3, nil, 1, http.StatusOK},
+		{"Matching version with rating", `"3-r9.2-9f2c"`, 3, nil, 1, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("Update", mock.Anything, types.Course{ID: course.ID, Version: tt.version, Name: "Go"}).
+				Return(course, tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.On("Patch", mock.Anything, course.ID, tt.version, courseservice.MergePatch, []byte(`{"name":"Go"}`), mock.Anything).
+				Return(course, tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.On("Delete", mock.Anything, course.ID, tt.version).
+				Return(tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			for _, h := range []struct {
+				handler     echo.HandlerFunc
+				contentType string
+				okStatus    int
+			}{
+				{PutCourse, echo.MIMEApplicationJSON, http.StatusCreated},
+				{PatchCourse, courseservice.MergePatch, http.StatusOK},
+				{DelCourse, "", http.StatusOK},
+			} {
+				req := httptest.NewRequest(http.MethodPut, "/course", strings.NewReader(`{"name":"Go"}`))
+				req.Header.Set(echo.HeaderContentType, h.contentType)
+				req.Header.Set(HeaderIfMatch, tt.ifMatch)
+				rec := httptest.NewRecorder()
+				c := e.NewContext(req, rec)
+				c.SetParamNames("id")
+				c.SetParamValues(course.ID)
+
+				err := h.handler(c)
+				want := tt.statusCode
+				if want == http.StatusOK {
+					want = h.okStatus
+					assert.NoError(t, err)
+				} else {
+					assert.Equal(t, courseservice.ErrVersionMismatch, err)
+				}
+				assert.Equal(t, want, rec.Code)
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetTrash(t *testing.T) {
+	page := courseservice.Page{Courses: []types.Course{{ID: "id01", Name: "Go"}}, Total: 1}
+	tests := []struct {
+		name       string
+		actor      string
+		author     string
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok for the author", "ana", "ana", 1, http.StatusOK},
+		{"Status ok for an admin", "root", "", 1, http.StatusOK},
+		{"Status forbidden anonymous", "", "", 0, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindAll", courseservice.ListOptions{Limit: defaultPageLimit, Deleted: true, Author: tt.author}).
+				Return(page, nil).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := withActor(httptest.NewRequest(http.MethodGet, "/courses/trash", nil), tt.actor)
+			rec := httptest.NewRecorder()
+
+			_ = GetTrash(e.NewContext(req, rec))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Equal(t, "1", rec.Header().Get(HeaderTotalCount))
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourse_Draft(t *testing.T) {
+	course := types.Course{ID: "id01", Version: 1, Name: "Go", Status: types.StatusDraft, Author: "ana", Instructors: []string{"i1"}}
+	tests := []struct {
+		name       string
+		actor      string
+		instructor string
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok for the author", "ana", "", nil, http.StatusOK},
+		{"Status ok for an admin", "root", "", nil, http.StatusOK},
+		{"Status ok for an instructor", "bob", "i1", nil, http.StatusOK},
+		{"Status not found for another instructor", "eve", "i2", nil, http.StatusNotFound},
+		{"Status not found for a student", "joe", "", storage.ErrNotFound, http.StatusNotFound},
+		{"Status not found anonymous", "", "", nil, http.StatusNotFound},
+		{"Status internal server error", "joe", "", mgo.ErrCursor, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindOne", course.ID).Return(course, nil).Once()
+			courseServiceMngr.InitMock()
+			var instructorServiceMngr = &instructorservice.Mock{}
+			instructorServiceMngr.On("FindByUser", tt.actor).Return(types.Instructor{ID: tt.instructor, User: tt.actor}, tt.mockErr).Maybe()
+			instructorServiceMngr.InitMock()
+			if tt.statusCode == http.StatusOK {
+				mockBundles(course.ID, nil)
+			}
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(withActor(httptest.NewRequest(http.MethodGet, "/courses/id01", nil), tt.actor), rec)
+			c.SetParamNames("id")
+			c.SetParamValues(course.ID)
+
+			_ = GetCourse(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRestoreCourse(t *testing.T) {
+	course := types.Course{ID: "id01", Version: 3, Name: "Go"}
+	tests := []struct {
+		name       string
+		mockErr    error
+		statusCode int
+		err        error
+	}{
+		{"Status ok", nil, http.StatusOK, nil},
+		{"Status ok but redis err", &cache.RedisErr{}, http.StatusOK, nil},
+		{"Status not found", storage.ErrNotFound, http.StatusNotFound, storage.ErrNotFound},
+		{"Status conflict", courseservice.ErrVersionMismatch, http.StatusConflict, courseservice.ErrVersionMismatch},
+		{"Status conflict name taken", storage.ErrDuplicate, http.StatusConflict, storage.ErrDuplicate},
+		{"Status internal server error", mgo.ErrCursor, http.StatusInternalServerError, mgo.ErrCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("Restore", mock.Anything, course.ID).Return(course, tt.mockErr).Once()
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/courses/id01/restore", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(course.ID)
+
+			assert.Equal(t, tt.err, RestoreCourse(c))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.err == nil {
+				assert.Equal(t, `"3"`, rec.Header().Get(HeaderETag))
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourseHistory(t *testing.T) {
+	entries := []types.AuditEntry{{ID: "a1", CourseID: "id01", Version: 1, Action: "create", Actor: "ana"}}
+	tests := []struct {
+		name       string
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok", nil, http.StatusOK},
+		{"Status not found", storage.ErrNotFound, http.StatusNotFound},
+		{"Status internal server error", mgo.ErrCursor, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindOne", "id01").Return(types.Course{ID: "id01"}, nil).Once()
+			courseServiceMngr.On("History", "id01").Return(entries, tt.mockErr).Once()
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/courses/id01/history", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			assert.Equal(t, tt.mockErr, GetCourseHistory(c))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourseDiff(t *testing.T) {
+	changes := []types.FieldChange{{Field: "price", From: 10.0, To: 0.0}}
+	tests := []struct {
+		name       string
+		query      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", "?from=1&to=2", nil, 1, http.StatusOK},
+		{"Status bad request", "?from=1", nil, 0, http.StatusBadRequest},
+		{"Status not found", "?from=1&to=9", storage.ErrNotFound, 1, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindOne", "id01").Return(types.Course{ID: "id01"}, nil).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.On("Diff", "id01", int64(1), mock.Anything).Return(changes, tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/courses/id01/history/diff"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = GetCourseDiff(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Equal(t, `[{"field":"price","from":10,"to":0}]`+"\n", rec.Body.String())
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourseRevision(t *testing.T) {
+	r := types.Revision{ID: "r1", CourseID: "id01", Version: 2, Course: types.Course{ID: "id01", Version: 2, Name: "Go"}}
+	tests := []struct {
+		name       string
+		rev        string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", "2", nil, 1, http.StatusOK},
+		{"Status bad request", "last", nil, 0, http.StatusBadRequest},
+		{"Status not found", "2", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status internal server error", "2", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindOne", "id01").Return(types.Course{ID: "id01"}, nil).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.On("Revision", "id01", int64(2)).Return(r, tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/courses/id01/revisions/"+tt.rev, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "rev")
+			c.SetParamValues("id01", tt.rev)
+
+			_ = GetCourseRevision(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCourseHistory_Owners(t *testing.T) {
+	draft := types.Course{ID: "id01", Version: 2, Name: "Go", Status: types.StatusDraft, Author: "ana"}
+	trashed := types.Course{ID: "id01", Version: 2, Name: "Go", Status: types.StatusPublished, Author: "ana"}
+	r := types.Revision{ID: "r1", CourseID: "id01", Version: 1, Course: draft}
+	tests := []struct {
+		name       string
+		actor      string
+		live       bool
+		statusCode int
+	}{
+		{"Status ok for the author of a draft", "ana", true, http.StatusOK},
+		{"Status not found for others on a draft", "eve", true, http.StatusNotFound},
+		{"Status ok for the author of a course in the trash", "ana", false, http.StatusOK},
+		{"Status not found anonymous on a course in the trash", "", false, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			entries := []types.AuditEntry{{CourseID: "id01", Version: 1, Action: "create", After: &draft}}
+			if tt.live {
+				courseServiceMngr.On("FindOne", "id01").Return(draft, nil).Times(3)
+			} else {
+				entries = append(entries, types.AuditEntry{CourseID: "id01", Version: 2, Action: "delete", Before: &draft, After: &trashed})
+				courseServiceMngr.On("FindOne", "id01").Return(types.Course{}, storage.ErrNotFound).Times(3)
+				courseServiceMngr.On("History", "id01").Return(entries, nil).Times(3)
+			}
+			if tt.statusCode == http.StatusOK {
+				courseServiceMngr.On("History", "id01").Return(entries, nil).Once()
+				courseServiceMngr.On("Diff", "id01", int64(1), int64(2)).Return([]types.FieldChange{}, nil).Once()
+				courseServiceMngr.On("Revision", "id01", int64(1)).Return(r, nil).Once()
+			}
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			for _, h := range []echo.HandlerFunc{GetCourseHistory, GetCourseDiff, GetCourseRevision} {
+				rec := httptest.NewRecorder()
+				c := e.NewContext(withActor(httptest.NewRequest(http.MethodGet, "/courses/id01/history?from=1&to=2", nil), tt.actor), rec)
+				c.SetParamNames("id", "rev")
+				c.SetParamValues("id01", "1")
+
+				_ = h(c)
+				assert.Equal(t, tt.statusCode, rec.Code)
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRevertCourse(t *testing.T) {
+	course := types.Course{ID: "id01", Version: 4, Name: "Go"}
+	tests := []struct {
+		name       string
+		rev        string
+		ifMatch    string
+		version    int64
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", "1", "", 0, nil, 1, http.StatusOK},
+		{"Status ok with If-Match", "1", `"3"`, 3, nil, 1, http.StatusOK},
+		{"Status ok but redis err", "1", "", 0, &cache.RedisErr{}, 1, http.StatusOK},
+		{"Status bad request", "first", "", 0, nil, 0, http.StatusBadRequest},
+		{"Status not found", "1", "", 0, storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status conflict", "1", "", 0, storage.ErrDuplicate, 1, http.StatusConflict},
+		{"Status precondition failed", "1", `"2"`, 2, courseservice.ErrVersionMismatch, 1, http.StatusPreconditionFailed},
+		{"Status internal server error", "1", "", 0, mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("Revert", mock.Anything, "id01", int64(1), tt.version).Return(course, tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/courses/id01/revisions/"+tt.rev+"/restore", nil)
+			if tt.ifMatch != "" {
+				req.Header.Set(HeaderIfMatch, tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "rev")
+			c.SetParamValues("id01", tt.rev)
+
+			_ = RevertCourse(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Equal(t, `"4"`, rec.Header().Get(HeaderETag))
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourses_Status(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		actor      string
+		opts       courseservice.ListOptions
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok published", "?status=published", "", courseservice.ListOptions{Limit: defaultPageLimit, Status: types.StatusPublished}, 1, http.StatusOK},
+		{"Status ok drafts of the author", "?status=draft", "ana", courseservice.ListOptions{Limit: defaultPageLimit, Status: types.StatusDraft, Author: "ana"}, 1, http.StatusOK},
+		{"Status forbidden anonymous drafts", "?status=draft", "", courseservice.ListOptions{}, 0, http.StatusForbidden},
+		{"Status bad request", "?status=hidden", "ana", courseservice.ListOptions{}, 0, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindAll", tt.opts).Return(courseservice.Page{}, nil).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/courses"+tt.query, nil)
+			if tt.actor != "" {
+				req = req.WithContext(actor.NewContext(req.Context(), tt.actor))
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			_ = GetCourses(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTransitionCourse(t *testing.T) {
+	course := types.Course{ID: "id01", Version: 3, Name: "Go", Status: types.StatusPublished}
+	at := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name       string
+		handler    echo.HandlerFunc
+		status     string
+		body       string
+		ifMatch    string
+		at         time.Time
+		version    int64
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok submit", SubmitCourse, types.StatusInReview, "", "", time.Time{}, 0, nil, 1, http.StatusOK},
+		{"Status ok publish", PublishCourse, types.StatusPublished, "", `"2"`, time.Time{}, 2, nil, 1, http.StatusOK},
+		{"Status ok scheduled publish", PublishCourse, types.StatusPublished, `{"at":"2030-01-02T03:04:05Z"}`, "", at, 0, nil, 1, http.StatusOK},
+		{"Status ok but redis err", UnpublishCourse, types.StatusDraft, "", "", time.Time{}, 0, &cache.RedisErr{}, 1, http.StatusOK},
+		{"Status bad request", UnpublishCourse, types.StatusDraft, `{"at":"tomorrow"}`, "", time.Time{}, 0, nil, 0, http.StatusBadRequest},
+		{"Status not found", ArchiveCourse, types.StatusArchived, "", "", time.Time{}, 0, storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status conflict", PublishCourse, types.StatusPublished, "", "", time.Time{}, 0, courseservice.ErrInvalidTransition, 1, http.StatusConflict},
+		{"Status precondition failed", ArchiveCourse, types.StatusArchived, "", `"1"`, time.Time{}, 1, courseservice.ErrVersionMismatch, 1, http.StatusPreconditionFailed},
+		{"Status internal server error", SubmitCourse, types.StatusInReview, "", "", time.Time{}, 0, mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("Transition", mock.Anything, "id01", tt.status, tt.at, tt.version).Return(course, tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/courses/id01/transition", strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			if tt.ifMatch != "" {
+				req.Header.Set(HeaderIfMatch, tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = tt.handler(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.Equal(t, `"3"`, rec.Header().Get(HeaderETag))
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+//orderRequest is the body of the reorderings, the ids of every module or lesson in their new order
+type orderRequest struct {
+	IDs []string `json:"ids"`
+}
+
+//GetModules is a handler to get in order the modules, and their lessons, of the course which id or slug is the path parameter id.
+//The URLs of the lessons which are not free previews are left out unless the actor of the request owns the course or
+//is one of its students.
+func GetModules(c echo.Context) error {
+	open, err := openLessons(c)
+	if err != nil {
+		return curriculumFail(c, err)
+	}
+	ms, err := courseservice.GetInstance().Curriculum(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		previews := []types.Module{}
+		for _, m := range ms {
+			previews = append(previews, preview(m, open))
+		}
+		return c.JSON(http.StatusOK, previews)
+	}
+	return curriculumFail(c, err)
+}
+
+//GetModule is a handler to get the module of the path parameter module of the course of the path parameter id, the
+//same way GetModules does
+func GetModule(c echo.Context) error {
+	m, err := findModule(c)
+	if err == nil {
+		return c.JSON(http.StatusOK, m)
+	}
+	return curriculumFail(c, err)
+}
+
+//SetModule is a handler to add to the course of the path parameter id the module, passing a types.Module in the body
+func SetModule(c echo.Context) error {
+	var m types.Module
+	if err := c.Bind(&m); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&m); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	m, err := courseservice.GetInstance().AddModule(c.Request().Context(), c.Param("id"), m)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, m)
+	}
+	return curriculumFail(c, err)
+}
+
+//PutModule is a handler to rename, or move with a non zero order, the module of the path parameter module,
+//passing a types.Module in the body. Its lessons are not changed.
+func PutModule(c echo.Context) error {
+	var m types.Module
+	if err := c.Bind(&m); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&m); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	m.ID = c.Param("module")
+	m, err := courseservice.GetInstance().UpdateModule(c.Request().Context(), c.Param("id"), m)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, m)
+	}
+	return curriculumFail(c, err)
+}
+
+//DelModule is a handler that removes, with its lessons, the module of the path parameter module
+func DelModule(c echo.Context) error {
+	err := courseservice.GetInstance().DeleteModule(c.Request().Context(), c.Param("id"), c.Param("module"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.NoContent(http.StatusOK)
+	}
+	return curriculumFail(c, err)
+}
+
+//ReorderModules is a handler to sort the modules of the course of the path parameter id passing every module id in the body
+func ReorderModules(c echo.Context) error {
+	var req orderRequest
+	if err := c.Bind(&req); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	ms, err := courseservice.GetInstance().ReorderModules(c.Request().Context(), c.Param("id"), req.IDs)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, ms)
+	}
+	return curriculumFail(c, err)
+}
+
+//GetLesson is a handler to get the lesson of the path parameter lesson of the module of the path parameter module,
+//the same way GetModules does
+func GetLesson(c echo.Context) error {
+	m, err := findModule(c)
+	if err != nil {
+		return curriculumFail(c, err)
+	}
+	for _, l := range m.Lessons {
+		if l.ID == c.Param("lesson") {
+			return c.JSON(http.StatusOK, l)
+		}
+	}
+	return curriculumFail(c, storage.ErrNotFound)
+}
+
+//SetLesson is a handler to add to the module of the path parameter module the lesson, passing a types.Lesson in the body
+func SetLesson(c echo.Context) error {
+	var l types.Lesson
+	if err := c.Bind(&l); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&l); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	l, err := courseservice.GetInstance().AddLesson(c.Request().Context(), c.Param("id"), c.Param("module"), l)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, l)
+	}
+	return curriculumFail(c, err)
+}
+
+//PutLesson is a handler to replace the lesson of the path parameter lesson passing a types.Lesson in the body.
+//A non zero order moves it.
+func PutLesson(c echo.Context) error {
+	var l types.Lesson
+	if err := c.Bind(&l); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&l); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	l.ID = c.Param("lesson")
+	l, err := courseservice.GetInstance().UpdateLesson(c.Request().Context(), c.Param("id"), c.Param("module"), l)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, l)
+	}
+	return curriculumFail(c, err)
+}
+
+//DelLesson is a handler that removes the lesson of the path parameter lesson
+func DelLesson(c echo.Context) error {
+	err := courseservice.GetInstance().DeleteLesson(c.Request().Context(), c.Param("id"), c.Param("module"), c.Param("lesson"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.NoContent(http.StatusOK)
+	}
+	return curriculumFail(c, err)
+}
+
+//ReorderLessons is a handler to sort the lessons of the module of the path parameter module passing every lesson id in the body
+func ReorderLessons(c echo.Context) error {
+	var req orderRequest
+	if err := c.Bind(&req); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	m, err := courseservice.GetInstance().ReorderLessons(c.Request().Context(), c.Param("id"), c.Param("module"), req.IDs)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, m)
+	}
+	return curriculumFail(c, err)
+}
+
+//findModule finds the module of the path parameter module in the curriculum of the course of the path parameter id,
+//as the actor of the request sees it
+func findModule(c echo.Context) (types.Module, error) {
+	open, err := openLessons(c)
+	if err != nil {
+		return types.Module{}, err
+	}
+	ms, err := courseservice.GetInstance().Curriculum(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return types.Module{}, err
+	}
+	for _, m := range ms {
+		if m.ID == c.Param("module") {
+			return preview(m, open), nil
+		}
+	}
+	return types.Module{}, storage.ErrNotFound
+}
+
+//openLessons tells whether the actor of the request opens every lesson of the course of the path parameter id, as
+//one of its owners or of its active students. Courses which are not published are only found by their owners.
+func openLessons(c echo.Context) (bool, error) {
+	cr, err := courseservice.GetInstance().FindOne(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if owns, err := ownsCourse(c, cr); err != nil || owns {
+		return owns, err
+	}
+	if cr.Status != types.StatusPublished && cr.Status != "" {
+		return false, storage.ErrNotFound
+	}
+	student := actor.FromContext(c.Request().Context())
+	if student == actor.Anonymous {
+		return false, nil
+	}
+	es, err := enrollmentservice.GetInstance().FindByStudent(student)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	for _, e := range es {
+		if e.CourseID == cr.ID && e.Status == types.EnrollmentActive {
+			return true, err
+		}
+	}
+	return false, err
+}
+
+//preview leaves out of the module the URLs of its lessons which are not free previews, unless they are open
+func preview(m types.Module, open bool) types.Module {
+	if open {
+		return m
+	}
+	lessons := make([]types.Lesson, len(m.Lessons))
+	for i, l := range m.Lessons {
+		if !l.FreePreview {
+			l.URL = ""
+		}
+		lessons[i] = l
+	}
+	m.Lessons = lessons
+	return m
+}
+
+//curriculumFail answers the error of a curriculum request. Concurrent changes of the curriculum are a conflict.
+func curriculumFail(c echo.Context, err error) error {
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case courseservice.ErrInvalidOrder:
+		httpStatus = http.StatusBadRequest
+	case courseservice.ErrNotOwner:
+		httpStatus = http.StatusForbidden
+	case courseservice.ErrVersionMismatch:
+		httpStatus = http.StatusConflict
+	}
+	return fail(c, httpStatus, err)
+}
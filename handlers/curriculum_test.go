@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+)
+
+var testModules = []types.Module{
+	{ID: "m1", CourseID: "id01", Title: "Intro", Order: 1, Duration: 60, Lessons: []types.Lesson{
+		{ID: "l1", Title: "Welcome", Order: 1, Duration: 60, ContentType: types.ContentVideo, FreePreview: true},
+	}},
+}
+
+func TestGetModules(t *testing.T) {
+	tests := []struct {
+		name       string
+		module     string
+		lesson     string
+		handler    echo.HandlerFunc
+		findErr    error
+		mockErr    error
+		statusCode int
+		body       interface{}
+	}{
+		{"Status ok modules", "", "", GetModules, nil, nil, http.StatusOK, testModules},
+		{"Status ok modules but redis err", "", "", GetModules, &cache.RedisErr{}, &cache.RedisErr{}, http.StatusOK, testModules},
+		{"Status ok module", "m1", "", GetModule, nil, nil, http.StatusOK, testModules[0]},
+		{"Status ok lesson", "m1", "l1", GetLesson, nil, nil, http.StatusOK, testModules[0].Lessons[0]},
+		{"Status not found course", "", "", GetModules, storage.ErrNotFound, nil, http.StatusNotFound, nil},
+		{"Status not found module", "m9", "", GetModule, nil, nil, http.StatusNotFound, nil},
+		{"Status not found lesson", "m1", "l9", GetLesson, nil, nil, http.StatusNotFound, nil},
+		{"Status internal server error", "m1", "", GetModule, nil, mgo.ErrCursor, http.StatusInternalServerError, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindOne", "id01").Return(types.Course{ID: "id01", Name: "Go"}, tt.findErr).Once()
+			courseServiceMngr.On("Curriculum", "id01").Return(testModules, tt.mockErr).Maybe()
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/courses/id01/modules", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "module", "lesson")
+			c.SetParamValues("id01", tt.module, tt.lesson)
+
+			_ = tt.handler(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.body != nil {
+				out, err := json.Marshal(tt.body)
+				assert.NoError(t, err)
+				assert.Equal(t, string(out)+"\n", rec.Body.String())
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetModules_Preview(t *testing.T) {
+	ms := []types.Module{{ID: "m1", CourseID: "id01", Title: "Intro", Lessons: []types.Lesson{
+		{ID: "l1", Title: "Welcome", ContentType: types.ContentVideo, URL: "https://videos.example.com/l1", FreePreview: true},
+		{ID: "l2", Title: "Setup", ContentType: types.ContentVideo, URL: "https://videos.example.com/l2"},
+	}}}
+	published := types.Course{ID: "id01", Name: "Go", Status: types.StatusPublished, Author: "ana"}
+	draft := published
+	draft.Status = types.StatusDraft
+	tests := []struct {
+		name       string
+		actor      string
+		course     types.Course
+		status     string
+		statusCode int
+		urls       []string
+	}{
+		{"Status ok previews anonymous", "", published, "", http.StatusOK, []string{"https://videos.example.com/l1", ""}},
+		{"Status ok previews not enrolled", "joe", published, "", http.StatusOK, []string{"https://videos.example.com/l1", ""}},
+		{"Status ok previews waitlisted", "joe", published, types.EnrollmentWaitlisted, http.StatusOK, []string{"https://videos.example.com/l1", ""}},
+		{"Status ok every lesson enrolled", "joe", published, types.EnrollmentActive, http.StatusOK, []string{"https://videos.example.com/l1", "https://videos.example.com/l2"}},
+		{"Status ok every lesson author", "ana", draft, "", http.StatusOK, []string{"https://videos.example.com/l1", "https://videos.example.com/l2"}},
+		{"Status not found draft", "joe", draft, "", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindOne", "id01").Return(tt.course, nil).Once()
+			courseServiceMngr.On("Curriculum", "id01").Return(ms, nil).Maybe()
+			courseServiceMngr.InitMock()
+			var es []types.Enrollment
+			if tt.status != "" {
+				es = append(es, types.Enrollment{ID: "id01:joe", CourseID: "id01", Student: "joe", Status: tt.status})
+			}
+			var enrollmentServiceMngr = &enrollmentservice.Mock{}
+			enrollmentServiceMngr.On("FindByStudent", "joe").Return(es, nil).Maybe()
+			enrollmentServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(withActor(httptest.NewRequest(http.MethodGet, "/courses/id01/modules", nil), tt.actor), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = GetModules(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.urls != nil {
+				var got []types.Module
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+				assert.Equal(t, tt.urls, []string{got[0].Lessons[0].URL, got[0].Lessons[1].URL})
+				assert.Equal(t, "https://videos.example.com/l2", ms[0].Lessons[1].URL)
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetModule(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", `{"title":"Intro"}`, nil, 1, http.StatusCreated},
+		{"Status created but redis err", `{"title":"Intro"}`, &cache.RedisErr{}, 1, http.StatusCreated},
+		{"Status bad request", `{"title":1}`, nil, 0, http.StatusBadRequest},
+		{"Status bad request invalid", `{"title":""}`, nil, 0, http.StatusBadRequest},
+		{"Status not found", `{"title":"Intro"}`, storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status conflict", `{"title":"Intro"}`, courseservice.ErrVersionMismatch, 1, http.StatusConflict},
+		{"Status internal server error", `{"title":"Intro"}`, mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("AddModule", mock.Anything, "id01", types.Module{Title: "Intro"}).
+				Return(testModules[0], tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/courses/id01/modules", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = SetModule(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPutModule(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok", nil, http.StatusOK},
+		{"Status not found", storage.ErrNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("UpdateModule", mock.Anything, "id01", types.Module{ID: "m1", Title: "Start", Order: 2}).
+				Return(testModules[0], tt.mockErr).Once()
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPut, "/courses/id01/modules/m1", strings.NewReader(`{"title":"Start","order":2}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "module")
+			c.SetParamValues("id01", "m1")
+
+			_ = PutModule(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDelModuleAndLesson(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		handler    echo.HandlerFunc
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok module", "DeleteModule", DelModule, nil, http.StatusOK},
+		{"Status ok module but redis err", "DeleteModule", DelModule, &cache.RedisErr{}, http.StatusOK},
+		{"Status not found module", "DeleteModule", DelModule, storage.ErrNotFound, http.StatusNotFound},
+		{"Status ok lesson", "DeleteLesson", DelLesson, nil, http.StatusOK},
+		{"Status internal server error lesson", "DeleteLesson", DelLesson, mgo.ErrCursor, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			if tt.method == "DeleteModule" {
+				courseServiceMngr.On("DeleteModule", mock.Anything, "id01", "m1").Return(tt.mockErr).Once()
+			} else {
+				courseServiceMngr.On("DeleteLesson", mock.Anything, "id01", "m1", "l1").Return(tt.mockErr).Once()
+			}
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/courses/id01/modules/m1", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "module", "lesson")
+			c.SetParamValues("id01", "m1", "l1")
+
+			_ = tt.handler(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReorderModules(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", `{"ids":["m2","m1"]}`, nil, 1, http.StatusOK},
+		{"Status bad request", `{"ids":"m2"}`, nil, 0, http.StatusBadRequest},
+		{"Status bad request invalid order", `{"ids":["m2","m1"]}`, courseservice.ErrInvalidOrder, 1, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("ReorderModules", mock.Anything, "id01", []string{"m2", "m1"}).
+				Return(testModules, tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/courses/id01/modules/order", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = ReorderModules(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetLesson(t *testing.T) {
+	lesson := types.Lesson{Title: "Welcome", Duration: 60, ContentType: types.ContentVideo, FreePreview: true}
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", `{"title":"Welcome","duration":60,"contentType":"video","freePreview":true}`, nil, 1, http.StatusCreated},
+		{"Status not found", `{"title":"Welcome","duration":60,"contentType":"video","freePreview":true}`, storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status bad request invalid", `{"title":"Welcome","duration":-1,"contentType":"podcast"}`, nil, 0, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("AddLesson", mock.Anything, "id01", "m1", lesson).
+				Return(testModules[0].Lessons[0], tt.mockErr).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/courses/id01/modules/m1/lessons", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "module")
+			c.SetParamValues("id01", "m1")
+
+			_ = SetLesson(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.name == "Status bad request invalid" {
+				var res ErrorResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				assert.Equal(t, []FieldError{
+					{Field: "duration", Message: "must be greater than or equal to 0"},
+					{Field: "contentType", Message: "must be one of video, article, quiz, download"},
+				}, res.Fields)
+			}
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPutAndReorderLessons(t *testing.T) {
+	var courseServiceMngr = &courseservice.Mock{}
+	lesson := types.Lesson{ID: "l1", Title: "Hello", Duration: 30, ContentType: types.ContentArticle}
+	courseServiceMngr.On("UpdateLesson", mock.Anything, "id01", "m1", lesson).Return(lesson, nil).Once()
+	courseServiceMngr.On("ReorderLessons", mock.Anything, "id01", "m1", []string{"l1"}).Return(testModules[0], nil).Once()
+	courseServiceMngr.InitMock()
+
+	e := echo.New()
+	e.Validator = NewValidator()
+	req := httptest.NewRequest(http.MethodPut, "/courses/id01/modules/m1/lessons/l1", strings.NewReader(`{"title":"Hello","duration":30,"contentType":"article"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id", "module", "lesson")
+	c.SetParamValues("id01", "m1", "l1")
+	assert.NoError(t, PutLesson(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPut, "/courses/id01/modules/m1/lessons/order", strings.NewReader(`{"ids":["l1"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	c.SetParamNames("id", "module")
+	c.SetParamValues("id01", "m1")
+	assert.NoError(t, ReorderLessons(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	courseServiceMngr.AssertExpectations(t)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+//enrollRequest is the body of an enrollment, the student is the actor of the request when it is left out
+type enrollRequest struct {
+	Student string `json:"student"`
+}
+
+//errNotEnrollee for enrollments made or cancelled by someone who is neither their student nor an admin
+var errNotEnrollee = echo.NewHTTPError(http.StatusForbidden, "only the student and the admins enroll the student")
+
+//EnrollCourse is a handler to enroll a student in the course which id or slug is the path parameter id.
+//Students are waitlisted when the course is at capacity. Only the student and the admins enroll the student.
+func EnrollCourse(c echo.Context) error {
+	var req enrollRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return fail(c, http.StatusBadRequest, err)
+		}
+	}
+	if req.Student == "" {
+		req.Student = actor.FromContext(c.Request().Context())
+	}
+	if req.Student == actor.Anonymous {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "student is required"))
+	}
+	if !actsFor(c, req.Student) {
+		return enrollmentFail(c, errNotEnrollee)
+	}
+
+	e, err := enrollmentservice.GetInstance().Enroll(c.Request().Context(), c.Param("id"), req.Student)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, e)
+	}
+	return enrollmentFail(c, err)
+}
+
+//CancelEnrollment is a handler that cancels the enrollment of the student of the path parameter student in the course
+//of the path parameter id, the seat goes to the first student of the waitlist. Only the student and the admins cancel it.
+func CancelEnrollment(c echo.Context) error {
+	if !actsFor(c, c.Param("student")) {
+		return enrollmentFail(c, errNotEnrollee)
+	}
+	err := enrollmentservice.GetInstance().Cancel(c.Request().Context(), c.Param("id"), c.Param("student"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.NoContent(http.StatusOK)
+	}
+	return enrollmentFail(c, err)
+}
+
+//GetCourseEnrollments is a handler to get the students enrolled in and waiting for the course of the path parameter id
+func GetCourseEnrollments(c echo.Context) error {
+	es, err := enrollmentservice.GetInstance().FindByCourse(c.Param("id"))
+	return enrollments(c, es, err)
+}
+
+//GetStudentEnrollments is a handler to get the enrollments of the student of the path parameter id
+func GetStudentEnrollments(c echo.Context) error {
+	es, err := enrollmentservice.GetInstance().FindByStudent(c.Param("id"))
+	return enrollments(c, es, err)
+}
+
+//GetCourseSeats is a handler to get the capacity of the course of the path parameter id and how many students are
+//enrolled in and waiting for it
+func GetCourseSeats(c echo.Context) error {
+	seats, err := enrollmentservice.GetInstance().Seats(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, seats)
+	}
+	return enrollmentFail(c, err)
+}
+
+func enrollments(c echo.Context, es []types.Enrollment, err error) error {
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return enrollmentFail(c, err)
+	}
+	if es == nil {
+		es = []types.Enrollment{}
+	}
+	return c.JSON(http.StatusOK, es)
+}
+
+func enrollmentFail(c echo.Context, err error) error {
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case errNotEnrollee:
+		httpStatus = http.StatusForbidden
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case enrollmentservice.ErrAlreadyEnrolled, enrollmentservice.ErrNotEnrollable:
+		httpStatus = http.StatusConflict
+	case enrollmentservice.ErrPaymentRequired:
+		httpStatus = http.StatusPaymentRequired
+	}
+	return fail(c, httpStatus, err)
+}
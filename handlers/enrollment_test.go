@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+)
+
+func TestEnrollCourse(t *testing.T) {
+	enrollment := types.Enrollment{ID: "id01:ana", CourseID: "id01", Student: "ana", Status: types.EnrollmentActive}
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", `{"student":"ana"}`, "ana", nil, 1, http.StatusCreated},
+		{"Status created as the actor", "", "ana", nil, 1, http.StatusCreated},
+		{"Status created but redis err", `{"student":"ana"}`, "ana", &cache.RedisErr{}, 1, http.StatusCreated},
+		{"Status bad request", "", "", nil, 0, http.StatusBadRequest},
+		{"Status bad request body", `{"student":1}`, "", nil, 0, http.StatusBadRequest},
+		{"Status created by an admin", `{"student":"ana"}`, "root", nil, 1, http.StatusCreated},
+		{"Status forbidden another student", `{"student":"ana"}`, "eve", nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", `{"student":"ana"}`, "", nil, 0, http.StatusForbidden},
+		{"Status not found", `{"student":"ana"}`, "ana", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status conflict already enrolled", `{"student":"ana"}`, "ana", enrollmentservice.ErrAlreadyEnrolled, 1, http.StatusConflict},
+		{"Status conflict not enrollable", `{"student":"ana"}`, "ana", enrollmentservice.ErrNotEnrollable, 1, http.StatusConflict},
+		{"Status payment required", `{"student":"ana"}`, "ana", enrollmentservice.ErrPaymentRequired, 1, http.StatusPaymentRequired},
+		{"Status internal server error", `{"student":"ana"}`, "ana", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var enrollmentServiceMngr = &enrollmentservice.Mock{}
+			enrollmentServiceMngr.On("Enroll", mock.Anything, "id01", "ana").Return(enrollment, tt.mockErr).Maybe().Times(tt.mockTimes)
+			enrollmentServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/courses/id01/enrollments", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = withActor(req, tt.actor)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = EnrollCourse(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			enrollmentServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCancelEnrollment(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", "ana", nil, 1, http.StatusOK},
+		{"Status ok for an admin", "root", nil, 1, http.StatusOK},
+		{"Status forbidden another student", "eve", nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", "", nil, 0, http.StatusForbidden},
+		{"Status not found", "ana", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status internal server error", "ana", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var enrollmentServiceMngr = &enrollmentservice.Mock{}
+			enrollmentServiceMngr.On("Cancel", mock.Anything, "id01", "ana").Return(tt.mockErr).Maybe().Times(tt.mockTimes)
+			enrollmentServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(withActor(httptest.NewRequest(http.MethodDelete, "/courses/id01/enrollments/ana", nil), tt.actor), rec)
+			c.SetParamNames("id", "student")
+			c.SetParamValues("id01", "ana")
+
+			_ = CancelEnrollment(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			enrollmentServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetEnrollments(t *testing.T) {
+	es := []types.Enrollment{{ID: "id01:ana", CourseID: "id01", Student: "ana", Status: types.EnrollmentActive}}
+	tests := []struct {
+		name       string
+		method     string
+		handler    echo.HandlerFunc
+		mockEs     []types.Enrollment
+		mockErr    error
+		statusCode int
+		body       string
+	}{
+		{"Status ok course", "FindByCourse", GetCourseEnrollments, es, nil, http.StatusOK, ""},
+		{"Status ok student empty", "FindByStudent", GetStudentEnrollments, nil, nil, http.StatusOK, "[]\n"},
+		{"Status ok but redis err", "FindByStudent", GetStudentEnrollments, es, &cache.RedisErr{}, http.StatusOK, ""},
+		{"Status not found", "FindByCourse", GetCourseEnrollments, nil, storage.ErrNotFound, http.StatusNotFound, ""},
+		{"Status internal server error", "FindByStudent", GetStudentEnrollments, nil, mgo.ErrCursor, http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var enrollmentServiceMngr = &enrollmentservice.Mock{}
+			enrollmentServiceMngr.On(tt.method, "id01").Return(tt.mockEs, tt.mockErr).Once()
+			enrollmentServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = tt.handler(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, rec.Body.String())
+			}
+			enrollmentServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCourseSeats(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok", nil, http.StatusOK},
+		{"Status not found", storage.ErrNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var enrollmentServiceMngr = &enrollmentservice.Mock{}
+			enrollmentServiceMngr.On("Seats", "id01").Return(types.Seats{CourseID: "id01", Capacity: 10, Enrolled: 3}, tt.mockErr).Once()
+			enrollmentServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/courses/id01/seats", nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = GetCourseSeats(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.mockErr == nil {
+				assert.Equal(t, `{"courseId":"id01","capacity":10,"enrolled":3,"waitlisted":0}`+"\n", rec.Body.String())
+			}
+			enrollmentServiceMngr.AssertExpectations(t)
+		})
+	}
+}
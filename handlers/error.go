@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	//CodeBadRequest is the code of a request that could not be read
+	CodeBadRequest = "bad_request"
+	//CodeInvalid is the code of a body breaking the validation rules of its fields
+	CodeInvalid = "invalid"
+	//CodePaymentRequired is the code of an order which payment was declined
+	CodePaymentRequired = "payment_required"
+	//CodeForbidden is the code of a request the actor making it is not allowed to make
+	CodeForbidden = "forbidden"
+	//CodeNotFound is the code of a request to a resource that does not exist
+	CodeNotFound = "not_found"
+	//CodeMethodNotAllowed is the code of a request with a method its route does not answer
+	CodeMethodNotAllowed = "method_not_allowed"
+	//CodeConflict is the code of a request conflicting with the state of a resource
+	CodeConflict = "conflict"
+	//CodePreconditionFailed is the code of a change to a resource that does not have the ETag of If-Match anymore
+	CodePreconditionFailed = "precondition_failed"
+	//CodeRequestTooLarge is the code of a body over the size limit
+	CodeRequestTooLarge = "request_too_large"
+	//CodeUnsupportedMediaType is the code of a body of a media type the handler does not read
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	//CodeInternal is the code of a request that failed for an unexpected error
+	CodeInternal = "internal"
+)
+
+//ErrorResponse is the body of every error answered by the handlers
+type ErrorResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+//FieldError is why the value of a field of the body is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+//fail answers the status with the ErrorResponse of err and returns err, so it still reaches echo.
+//Messages of server errors are not answered, as they may show internals.
+func fail(c echo.Context, status int, err error) error {
+	res := ErrorResponse{Code: statusCode(status), Message: http.StatusText(status), RequestID: requestID(c)}
+
+	switch e := err.(type) {
+	case validator.ValidationErrors:
+		res.Code = CodeInvalid
+		res.Message = "invalid fields"
+		for _, fe := range e {
+			res.Fields = append(res.Fields, FieldError{Field: fieldName(fe), Message: fieldMessage(fe)})
+		}
+	case *echo.HTTPError:
+		if status < http.StatusInternalServerError {
+			res.Message = fmt.Sprint(e.Message)
+		}
+	}
+	_ = c.JSON(status, res)
+	return err
+}
+
+//HTTPErrorHandler is the echo.HTTPErrorHandler answering with an ErrorResponse the errors of echo itself, like routes
+//that do not exist, methods they do not answer, bodies over the limit and recovered panics. The errors the handlers
+//answered already are left as they are.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	status := http.StatusInternalServerError
+	if he, ok := err.(*echo.HTTPError); ok {
+		status = he.Code
+	}
+	if c.Request().Method == http.MethodHead {
+		_ = c.NoContent(status)
+		return
+	}
+	_ = fail(c, status, err)
+}
+
+//statusCode is the code of the ErrorResponse of the status, the code of its class for the statuses without their own
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusPaymentRequired:
+		return CodePaymentRequired
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return CodeRequestTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMediaType
+	}
+	if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
+
+//requestID is the id middleware.RequestID gave to the request, or the one sent by the client
+func requestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+//fieldName is the path of the field from the body, like price.amount for the fields of nested structs
+func fieldName(fe validator.FieldError) string {
+	if i := strings.Index(fe.Namespace(), "."); i >= 0 {
+		return fe.Namespace()[i+1:]
+	}
+	return fe.Field()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	}
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "url":
+		return "must be an absolute URL"
+	case "currency":
+		return "must be an ISO 4217 currency code"
+	case "oneof":
+		return "must be one of " + strings.Replace(fe.Param(), " ", ", ", -1)
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "min":
+		if unit == "" {
+			return "must be greater than or equal to " + fe.Param()
+		}
+		return "must have at least " + fe.Param() + unit
+	case "max":
+		if unit == "" {
+			return "must be less than or equal to " + fe.Param()
+		}
+		return "must have at most " + fe.Param() + unit
+	}
+	return "is invalid"
+}
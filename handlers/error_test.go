@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(middleware.Recover())
+	e.Use(middleware.BodyLimit("1K"))
+	e.GET("/courses/:id", func(c echo.Context) error {
+		return fail(c, http.StatusConflict, echo.NewHTTPError(http.StatusConflict, "answered"))
+	})
+	e.POST("/courses", func(c echo.Context) error {
+		var body map[string]interface{}
+		return c.Bind(&body)
+	})
+	e.GET("/panic", func(c echo.Context) error {
+		panic("internals")
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"Route not found", http.MethodGet, "/missing", "", http.StatusNotFound,
+			`{"code":"not_found","message":"Not Found","requestId":"req01"}`},
+		{"Method not allowed", http.MethodDelete, "/courses", "", http.StatusMethodNotAllowed,
+			`{"code":"method_not_allowed","message":"Method Not Allowed","requestId":"req01"}`},
+		{"Body too large", http.MethodPost, "/courses", `{"name":"` + strings.Repeat("a", 2048) + `"}`, http.StatusRequestEntityTooLarge,
+			`{"code":"request_too_large","message":"Request Entity Too Large","requestId":"req01"}`},
+		{"Body of another media type", http.MethodPost, "/courses", "name", http.StatusUnsupportedMediaType,
+			`{"code":"unsupported_media_type","message":"Unsupported Media Type","requestId":"req01"}`},
+		{"Recovered panic", http.MethodGet, "/panic", "", http.StatusInternalServerError,
+			`{"code":"internal","message":"Internal Server Error","requestId":"req01"}`},
+		{"Answered by the handler", http.MethodGet, "/courses/id01", "", http.StatusConflict,
+			`{"code":"conflict","message":"answered","requestId":"req01"}`},
+		{"Head", http.MethodHead, "/missing", "", http.StatusNotFound, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderXRequestID, "req01")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.want, strings.TrimSpace(rec.Body.String()))
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, CodeNotFound, statusCode(http.StatusNotFound))
+	assert.Equal(t, CodeBadRequest, statusCode(http.StatusTooManyRequests))
+	assert.Equal(t, CodeInternal, statusCode(http.StatusServiceUnavailable))
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	//HeaderETag is the header with the ETag of the representation answered
+	HeaderETag = "ETag"
+	//HeaderIfMatch is the header with the ETag a change expects the resource to have
+	HeaderIfMatch = "If-Match"
+	//HeaderIfNoneMatch is the header with the ETags of the representations the client already has
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+//courseETag is the strong ETag of the version and the rating of a course
+func courseETag(c types.Course) string {
+	return strconv.Quote(courseTag(c))
+}
+
+//bundledETag is the strong ETag of a course shown with the bundles including it, as bundles change without a new
+//version of their courses. Only its version is compared with the If-Match header.
+func bundledETag(c types.Course, bs []types.Bundle) string {
+	if len(bs) == 0 {
+		return courseETag(c)
+	}
+	return strconv.Quote(fmt.Sprintf("%s-%x", courseTag(c), bundlesHash(bs)))
+}
+
+//pricedETag is the weak ETag of a course priced in another currency, as exchange rates change the price without a
+//new version. It never matches the If-Match header.
+func pricedETag(c types.Course, price types.Money, bs []types.Bundle) string {
+	if len(bs) == 0 {
+		return fmt.Sprintf(`W/"%s:%d%s"`, courseTag(c), price.Amount, price.Currency)
+	}
+	return fmt.Sprintf(`W/"%s:%d%s-%x"`, courseTag(c), price.Amount, price.Currency, bundlesHash(bs))
+}
+
+//courseTag is the version of a course followed by its rating, as reviews rate courses without a new version
+func courseTag(c types.Course) string {
+	if c.Rating == nil {
+		return strconv.FormatInt(c.Version, 10)
+	}
+	return fmt.Sprintf("%d-r%d.%d", c.Version, c.Rating.Stars, c.Rating.Count)
+}
+
+//bundlesHash changes with any field of the bundles
+func bundlesHash(bs []types.Bundle) uint64 {
+	h := fnv.New64a()
+	for _, b := range bs {
+		_, _ = fmt.Fprintf(h, ";%+v", b)
+	}
+	return h.Sum64()
+}
+
+//pageETag is the weak ETag of a page of courses, changing with the total, the versions and ratings of its
+//courses, as reviews rate courses without a new version, and its facets
+func pageETag(p courseservice.Page) string {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%d", p.Total)
+	for _, c := range p.Courses {
+		_, _ = fmt.Fprintf(h, ";%s:%d", c.ID, c.Version)
+		if c.Rating != nil {
+			_, _ = fmt.Fprintf(h, ":%d/%d", c.Rating.Stars, c.Rating.Count)
+		}
+	}
+	for _, field := range []string{"category", "tag"} {
+		for _, f := range p.Facets[field] {
+			_, _ = fmt.Fprintf(h, ";%s=%s:%d", field, f.Value, f.Count)
+		}
+	}
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
+}
+
+//ifMatch reads the course version required by the If-Match header, 0 when it is missing or "*", leaving out the
+//rating and the bundles of the ETags of a course. Weak or malformed ETags never match a course, ErrVersionMismatch is returned for them.
+func ifMatch(c echo.Context) (int64, error) {
+	tag := strings.TrimSpace(c.Request().Header.Get(HeaderIfMatch))
+	if tag == "" || tag == "*" {
+		return 0, nil
+	}
+	v, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, courseservice.ErrVersionMismatch
+	}
+	if i := strings.IndexByte(v, '-'); i > 0 {
+		v = v[:i]
+	}
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || version < 1 {
+		return 0, courseservice.ErrVersionMismatch
+	}
+	return version, nil
+}
+
+//notModified reports whether one of the ETags of the If-None-Match header matches tag, comparing them weakly
+func notModified(c echo.Context, tag string) bool {
+	for _, t := range strings.Split(c.Request().Header.Get(HeaderIfNoneMatch), ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
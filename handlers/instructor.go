@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/instructorservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+//GetInstructors is a handler to get every instructor by name
+func GetInstructors(c echo.Context) error {
+	is, err := instructorservice.GetInstance().FindAll()
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return fail(c, http.StatusInternalServerError, err)
+	}
+	if is == nil {
+		is = []types.Instructor{}
+	}
+	for j := range is {
+		is[j] = private(c, is[j])
+	}
+	return c.JSON(http.StatusOK, is)
+}
+
+//GetInstructor is a handler to get the instructor of the path parameter id
+func GetInstructor(c echo.Context) error {
+	i, err := instructorservice.GetInstance().FindOne(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, private(c, i))
+	}
+	return instructorFail(c, err)
+}
+
+//GetInstructorCourses is a handler to get a page of the courses of the instructor of the path parameter id,
+//accepting the same query parameters as GetCourses
+func GetInstructorCourses(c echo.Context) error {
+	_, err := instructorservice.GetInstance().FindOne(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return instructorFail(c, err)
+	}
+	return listCourses(c, courseservice.ListOptions{Instructor: c.Param("id")})
+}
+
+//SetInstructor is a handler to create an instructor passing a types.Instructor in the body. A user is a single instructor,
+//duplicates are a conflict. Only the user and the admins create it.
+func SetInstructor(c echo.Context) error {
+	var i types.Instructor
+	if err := c.Bind(&i); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&i); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	i, err := instructorservice.GetInstance().Create(c.Request().Context(), i)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, i)
+	}
+	return instructorFail(c, err)
+}
+
+//PutInstructor is a handler to update the instructor of the path parameter id passing a types.Instructor in the body.
+//Only its user and the admins update it, and its user is kept.
+func PutInstructor(c echo.Context) error {
+	var i types.Instructor
+	if err := c.Bind(&i); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&i); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	i.ID = c.Param("id")
+	i, err := instructorservice.GetInstance().Update(c.Request().Context(), i)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, i)
+	}
+	return instructorFail(c, err)
+}
+
+//DelInstructor is a handler that removes the instructor of the path parameter id, for its user or an admin
+func DelInstructor(c echo.Context) error {
+	err := instructorservice.GetInstance().Delete(c.Request().Context(), c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.NoContent(http.StatusOK)
+	}
+	return instructorFail(c, err)
+}
+
+//private leaves out the payout of the instructor unless the actor of the request is the instructor
+func private(c echo.Context, i types.Instructor) types.Instructor {
+	if actor.FromContext(c.Request().Context()) != i.User {
+		i.Payout = nil
+	}
+	return i
+}
+
+func instructorFail(c echo.Context, err error) error {
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case storage.ErrDuplicate:
+		httpStatus = http.StatusConflict
+	case instructorservice.ErrNotUser:
+		httpStatus = http.StatusForbidden
+	}
+	return fail(c, httpStatus, err)
+}
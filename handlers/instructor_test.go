@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/instructorservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+)
+
+var testInstructor = types.Instructor{ID: "i1", User: "ana", Name: "Ana", Payout: &types.Payout{Method: types.PayoutBank, Account: "001"}}
+
+func TestGetInstructor(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		mockErr    error
+		statusCode int
+		payout     bool
+	}{
+		{"Status ok", "", nil, http.StatusOK, false},
+		{"Status ok as the instructor", "ana", nil, http.StatusOK, true},
+		{"Status ok but redis err", "bob", &cache.RedisErr{}, http.StatusOK, false},
+		{"Status not found", "", storage.ErrNotFound, http.StatusNotFound, false},
+		{"Status internal server error", "", mgo.ErrCursor, http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var instructorServiceMngr = &instructorservice.Mock{}
+			instructorServiceMngr.On("FindOne", "i1").Return(testInstructor, tt.mockErr).Once()
+			instructorServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/instructors/i1", nil)
+			if tt.actor != "" {
+				req = req.WithContext(actor.NewContext(req.Context(), tt.actor))
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("i1")
+
+			_ = GetInstructor(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.statusCode == http.StatusOK {
+				var i types.Instructor
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &i))
+				assert.Equal(t, tt.payout, i.Payout != nil)
+			}
+			instructorServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetInstructors(t *testing.T) {
+	var instructorServiceMngr = &instructorservice.Mock{}
+	instructorServiceMngr.On("FindAll").Return([]types.Instructor{testInstructor}, nil).Once()
+	instructorServiceMngr.InitMock()
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/instructors", nil), rec)
+
+	assert.NoError(t, GetInstructors(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `[{"id":"i1","user":"ana","name":"Ana","bio":"","avatar":""}]`+"\n", rec.Body.String())
+	instructorServiceMngr.AssertExpectations(t)
+}
+
+func TestSetInstructor(t *testing.T) {
+	body := `{"user":"ana","name":"Ana","payout":{"method":"bank","account":"001"}}`
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", body, nil, 1, http.StatusCreated},
+		{"Status created but redis err", body, &cache.RedisErr{}, 1, http.StatusCreated},
+		{"Status bad request", `{"user":1}`, nil, 0, http.StatusBadRequest},
+		{"Status bad request invalid", `{"user":"ana","name":"Ana","payout":{"method":"cash","account":"001"}}`, nil, 0, http.StatusBadRequest},
+		{"Status forbidden", body, instructorservice.ErrNotUser, 1, http.StatusForbidden},
+		{"Status conflict", body, storage.ErrDuplicate, 1, http.StatusConflict},
+		{"Status internal server error", body, mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := testInstructor
+			i.ID = ""
+			var instructorServiceMngr = &instructorservice.Mock{}
+			instructorServiceMngr.On("Create", mock.Anything, i).Return(testInstructor, tt.mockErr).Maybe().Times(tt.mockTimes)
+			instructorServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/instructors", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			_ = SetInstructor(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			instructorServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPutAndDelInstructor(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok", nil, http.StatusOK},
+		{"Status forbidden", instructorservice.ErrNotUser, http.StatusForbidden},
+		{"Status not found", storage.ErrNotFound, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var instructorServiceMngr = &instructorservice.Mock{}
+			instructorServiceMngr.On("Update", mock.Anything, types.Instructor{ID: "i1", User: "ana", Name: "Ana"}).Return(testInstructor, tt.mockErr).Once()
+			instructorServiceMngr.On("Delete", mock.Anything, "i1").Return(tt.mockErr).Once()
+			instructorServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPut, "/instructors/i1", strings.NewReader(`{"user":"ana","name":"Ana"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("i1")
+			_ = PutInstructor(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+
+			rec = httptest.NewRecorder()
+			c = e.NewContext(httptest.NewRequest(http.MethodDelete, "/instructors/i1", nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("i1")
+			_ = DelInstructor(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			instructorServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetInstructorCourses(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", nil, 1, http.StatusOK},
+		{"Status not found", storage.ErrNotFound, 0, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var instructorServiceMngr = &instructorservice.Mock{}
+			instructorServiceMngr.On("FindOne", "i1").Return(testInstructor, tt.mockErr).Once()
+			instructorServiceMngr.InitMock()
+			var courseServiceMngr = &courseservice.Mock{}
+			courseServiceMngr.On("FindAll", courseservice.ListOptions{Limit: defaultPageLimit, Instructor: "i1"}).
+				Return(courseservice.Page{Courses: []types.Course{{ID: "id01", Name: "Go"}}, Total: 1}, nil).Maybe().Times(tt.mockTimes)
+			courseServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/instructors/i1/courses", nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("i1")
+
+			_ = GetInstructorCourses(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			instructorServiceMngr.AssertExpectations(t)
+			courseServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCourseChanges_NotOwner(t *testing.T) {
+	var courseServiceMngr = &courseservice.Mock{}
+	courseServiceMngr.On("Delete", mock.Anything, "id01", int64(0)).Return(courseservice.ErrNotOwner).Once()
+	courseServiceMngr.InitMock()
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodDelete, "/courses/id01", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues("id01")
+
+	assert.Equal(t, courseservice.ErrNotOwner, DelCourse(c))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	var res ErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, CodeForbidden, res.Code)
+	courseServiceMngr.AssertExpectations(t)
+}
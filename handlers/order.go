@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/payment"
+	"github.com/ednesic/coursemanagement/services/bundleservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/services/orderservice"
+	"github.com/ednesic/coursemanagement/services/promotionservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+//HeaderIdempotencyKey is the header with the key of an order, sending it again retries the order
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+//refundRequest is the body of a refund, every line of the order is refunded when both the course and the bundle are
+//left out
+type refundRequest struct {
+	CourseID string `json:"courseId"`
+	BundleID string `json:"bundleId"`
+}
+
+//errNotOrderStudent for orders seen or refunded by someone who is neither their student nor an admin
+var errNotOrderStudent = echo.NewHTTPError(http.StatusForbidden, "only the student of the order and the admins see it")
+
+//CreateOrder is a handler to order the courses and bundles of a types.Cart passed in the body for the actor of the request, only
+//the admins order for the student of the cart. The order is paid from the source of the cart and its student enrolled
+//in its courses and the ones of its bundles. Orders sent again with the same Idempotency-Key header are answered without being paid again.
+func CreateOrder(c echo.Context) error {
+	var cart types.Cart
+	if err := c.Bind(&cart); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if cart.Student == "" || !actsFor(c, cart.Student) {
+		cart.Student = actor.FromContext(c.Request().Context())
+	}
+	if cart.Student == actor.Anonymous {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "student is required"))
+	}
+	if err := c.Validate(&cart); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if len(cart.Courses)+len(cart.Bundles) == 0 {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "cart is empty"))
+	}
+	key := c.Request().Header.Get(HeaderIdempotencyKey)
+	if len(key) > 255 {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "idempotency key is too long"))
+	}
+
+	o, err := orderservice.GetInstance().Create(c.Request().Context(), cart, key)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, o)
+	}
+	return orderFail(c, err)
+}
+
+//GetOrder is a handler to get the order of the path parameter id, for its student or an admin
+func GetOrder(c echo.Context) error {
+	o, err := findOrder(c, c.Param("id"))
+	if err == nil {
+		return c.JSON(http.StatusOK, o)
+	}
+	return orderFail(c, err)
+}
+
+//GetStudentOrders is a handler to get the orders of the student of the path parameter id, the latest first. Only the
+//student and the admins get them.
+func GetStudentOrders(c echo.Context) error {
+	if !actsFor(c, c.Param("id")) {
+		return orderFail(c, errNotOrderStudent)
+	}
+	orders, err := orderservice.GetInstance().FindByStudent(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return orderFail(c, err)
+	}
+	if orders == nil {
+		orders = []types.Order{}
+	}
+	return c.JSON(http.StatusOK, orders)
+}
+
+//RefundOrder is a handler giving back the payment of the course or bundle of the body in the order of the path
+//parameter id, or of all its lines without a body. The student is no longer enrolled in the courses refunded. Only the student
+//of the order and the admins refund it.
+func RefundOrder(c echo.Context) error {
+	var req refundRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return fail(c, http.StatusBadRequest, err)
+		}
+	}
+	if _, err := findOrder(c, c.Param("id")); err != nil {
+		return orderFail(c, err)
+	}
+
+	ref := req.CourseID
+	if ref == "" {
+		ref = req.BundleID
+	}
+	rs, err := orderservice.GetInstance().Refund(c.Request().Context(), c.Param("id"), ref)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, rs)
+	}
+	return orderFail(c, err)
+}
+
+//GetOrderRefunds is a handler to get the refunds of the order of the path parameter id, for its student or an admin
+func GetOrderRefunds(c echo.Context) error {
+	if _, err := findOrder(c, c.Param("id")); err != nil {
+		return orderFail(c, err)
+	}
+	rs, err := orderservice.GetInstance().FindRefunds(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return orderFail(c, err)
+	}
+	if rs == nil {
+		rs = []types.Refund{}
+	}
+	return c.JSON(http.StatusOK, rs)
+}
+
+//findOrder finds the order of the id when the actor of the request is its student or an admin
+func findOrder(c echo.Context, id string) (types.Order, error) {
+	o, err := orderservice.GetInstance().FindOne(id)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil && !actsFor(c, o.Student) {
+		err = errNotOrderStudent
+	}
+	return o, err
+}
+
+func orderFail(c echo.Context, err error) error {
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case errNotOrderStudent:
+		httpStatus = http.StatusForbidden
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case payment.ErrDeclined:
+		httpStatus = http.StatusPaymentRequired
+	case orderservice.ErrKeyReused, orderservice.ErrNotRefundable, orderservice.ErrAlreadyRefunded, orderservice.ErrCourseFull,
+		enrollmentservice.ErrAlreadyEnrolled, enrollmentservice.ErrNotEnrollable, bundleservice.ErrNotActive,
+		promotionservice.ErrCouponNotApplicable, promotionservice.ErrCouponExhausted:
+		httpStatus = http.StatusConflict
+	case orderservice.ErrUnknownCourse, orderservice.ErrUnknownBundle, orderservice.ErrNoSource, promotionservice.ErrUnknownCoupon,
+		money.ErrUnknownCurrency, money.ErrNoRate:
+		httpStatus = http.StatusBadRequest
+	}
+	return fail(c, httpStatus, err)
+}
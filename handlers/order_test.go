@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/payment"
+	"github.com/ednesic/coursemanagement/services/bundleservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/services/orderservice"
+	"github.com/ednesic/coursemanagement/services/promotionservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+)
+
+var testOrder = types.Order{ID: "o01", Key: "key1", Student: "ana", Status: types.OrderPaid, Total: testutil.USD(1000), Refunded: testutil.USD(0),
+	Items: []types.LineItem{{CourseID: "id01", Price: testutil.USD(1000), Discount: testutil.USD(0), Total: testutil.USD(1000)}}}
+
+func TestCreateOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		key        string
+		cart       types.Cart
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", `{"student":"ana","courses":["id01"],"source":"tok_visa"}`, "ana", "key1",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Source: "tok_visa"}, nil, 1, http.StatusCreated},
+		{"Status created for the actor", `{"courses":["id01"],"coupon":"LAUNCH","currency":"usd","source":"tok_visa"}`, "ana", "",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Coupon: "LAUNCH", Currency: "usd", Source: "tok_visa"}, nil, 1, http.StatusCreated},
+		{"Status created bundle", `{"bundles":["b01"],"source":"tok_visa"}`, "ana", "",
+			types.Cart{Student: "ana", Bundles: []string{"b01"}, Source: "tok_visa"}, nil, 1, http.StatusCreated},
+		{"Status created but redis err", `{"student":"ana","courses":["id01"],"source":"tok_visa"}`, "ana", "key1",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Source: "tok_visa"}, &cache.RedisErr{}, 1, http.StatusCreated},
+		{"Status created for the actor not the body", `{"student":"bob","courses":["id01"],"source":"tok_visa"}`, "ana", "key1",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Source: "tok_visa"}, nil, 1, http.StatusCreated},
+		{"Status created by an admin for the body", `{"student":"bob","courses":["id01"],"source":"tok_visa"}`, "root", "key1",
+			types.Cart{Student: "bob", Courses: []string{"id01"}, Source: "tok_visa"}, nil, 1, http.StatusCreated},
+		{"Status bad request anonymous", `{"courses":["id01"]}`, "", "", types.Cart{}, nil, 0, http.StatusBadRequest},
+		{"Status bad request anonymous for the body", `{"student":"ana","courses":["id01"]}`, "", "", types.Cart{}, nil, 0, http.StatusBadRequest},
+		{"Status bad request body", `{"courses":"id01"}`, "ana", "", types.Cart{}, nil, 0, http.StatusBadRequest},
+		{"Status bad request empty cart", `{"courses":[]}`, "ana", "", types.Cart{}, nil, 0, http.StatusBadRequest},
+		{"Status bad request empty bundle", `{"bundles":[""]}`, "ana", "", types.Cart{}, nil, 0, http.StatusBadRequest},
+		{"Status bad request unknown bundle", `{"bundles":["b01"],"source":"tok_visa"}`, "ana", "",
+			types.Cart{Student: "ana", Bundles: []string{"b01"}, Source: "tok_visa"}, orderservice.ErrUnknownBundle, 1, http.StatusBadRequest},
+		{"Status bad request currency", `{"courses":["id01"],"currency":"EURO"}`, "ana", "", types.Cart{}, nil, 0, http.StatusBadRequest},
+		{"Status bad request key", `{"courses":["id01"]}`, "ana", strings.Repeat("k", 256), types.Cart{}, nil, 0, http.StatusBadRequest},
+		{"Status bad request no source", `{"student":"ana","courses":["id01"],"source":"tok_visa"}`, "ana", "key1",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Source: "tok_visa"}, orderservice.ErrNoSource, 1, http.StatusBadRequest},
+		{"Status payment required", `{"student":"ana","courses":["id01"],"source":"tok_visa"}`, "ana", "key1",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Source: "tok_visa"}, payment.ErrDeclined, 1, http.StatusPaymentRequired},
+		{"Status conflict key reused", `{"student":"ana","courses":["id01"],"source":"tok_visa"}`, "ana", "key1",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Source: "tok_visa"}, orderservice.ErrKeyReused, 1, http.StatusConflict},
+		{"Status conflict already enrolled", `{"student":"ana","courses":["id01"],"source":"tok_visa"}`, "ana", "key1",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Source: "tok_visa"}, enrollmentservice.ErrAlreadyEnrolled, 1, http.StatusConflict},
+		{"Status conflict course full", `{"courses":["id01"],"source":"tok_visa"}`, "ana", "",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Source: "tok_visa"}, orderservice.ErrCourseFull, 1, http.StatusConflict},
+		{"Status conflict bundle not active", `{"bundles":["b01"],"source":"tok_visa"}`, "ana", "",
+			types.Cart{Student: "ana", Bundles: []string{"b01"}, Source: "tok_visa"}, bundleservice.ErrNotActive, 1, http.StatusConflict},
+		{"Status conflict coupon", `{"student":"ana","courses":["id01"],"source":"tok_visa"}`, "ana", "key1",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Source: "tok_visa"}, promotionservice.ErrCouponExhausted, 1, http.StatusConflict},
+		{"Status internal server error", `{"student":"ana","courses":["id01"],"source":"tok_visa"}`, "ana", "key1",
+			types.Cart{Student: "ana", Courses: []string{"id01"}, Source: "tok_visa"}, mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var orderServiceMngr = &orderservice.Mock{}
+			orderServiceMngr.On("Create", mock.Anything, tt.cart, tt.key).Return(testOrder, tt.mockErr).Maybe().Times(tt.mockTimes)
+			orderServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(HeaderIdempotencyKey, tt.key)
+			req = withActor(req, tt.actor)
+			rec := httptest.NewRecorder()
+
+			_ = CreateOrder(e.NewContext(req, rec))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.statusCode == http.StatusPaymentRequired {
+				var res ErrorResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				assert.Equal(t, CodePaymentRequired, res.Code)
+			}
+			orderServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok", "ana", nil, http.StatusOK},
+		{"Status ok for an admin", "root", nil, http.StatusOK},
+		{"Status ok but redis err", "ana", &cache.RedisErr{}, http.StatusOK},
+		{"Status forbidden", "bob", nil, http.StatusForbidden},
+		{"Status forbidden anonymous", "", nil, http.StatusForbidden},
+		{"Status not found", "ana", storage.ErrNotFound, http.StatusNotFound},
+		{"Status internal server error", "ana", mgo.ErrCursor, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var orderServiceMngr = &orderservice.Mock{}
+			orderServiceMngr.On("FindOne", "o01").Return(testOrder, tt.mockErr).Once()
+			orderServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(withActor(httptest.NewRequest(http.MethodGet, "/orders/o01", nil), tt.actor), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("o01")
+
+			_ = GetOrder(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			orderServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetStudentOrdersAndRefunds(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		mockOrders []types.Order
+		mockRs     []types.Refund
+		mockErr    error
+		mockTimes  int
+		statusCode int
+		empty      bool
+	}{
+		{"Status ok", "ana", []types.Order{testOrder}, []types.Refund{{ID: "o01:id01", OrderID: "o01", CourseID: "id01", Amount: testutil.USD(1000)}}, nil, 1, http.StatusOK, false},
+		{"Status ok for an admin", "root", []types.Order{testOrder}, nil, nil, 1, http.StatusOK, false},
+		{"Status ok empty", "ana", nil, nil, nil, 1, http.StatusOK, true},
+		{"Status ok but redis err", "ana", nil, nil, &cache.RedisErr{}, 1, http.StatusOK, true},
+		{"Status forbidden", "bob", nil, nil, nil, 0, http.StatusForbidden, false},
+		{"Status forbidden anonymous", "", nil, nil, nil, 0, http.StatusForbidden, false},
+		{"Status internal server error", "ana", nil, nil, mgo.ErrCursor, 1, http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var orderServiceMngr = &orderservice.Mock{}
+			orderServiceMngr.On("FindByStudent", "ana").Return(tt.mockOrders, tt.mockErr).Maybe().Times(tt.mockTimes)
+			orderServiceMngr.On("FindOne", "o01").Return(testOrder, nil).Once()
+			orderServiceMngr.On("FindRefunds", "o01").Return(tt.mockRs, tt.mockErr).Maybe().Times(tt.mockTimes)
+			orderServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(withActor(httptest.NewRequest(http.MethodGet, "/students/ana/orders", nil), tt.actor), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("ana")
+			_ = GetStudentOrders(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.empty {
+				assert.Equal(t, "[]\n", rec.Body.String())
+			}
+
+			rec = httptest.NewRecorder()
+			c = e.NewContext(withActor(httptest.NewRequest(http.MethodGet, "/orders/o01/refunds", nil), tt.actor), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("o01")
+			_ = GetOrderRefunds(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.empty {
+				assert.Equal(t, "[]\n", rec.Body.String())
+			}
+			orderServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRefundOrder(t *testing.T) {
+	rs := []types.Refund{{ID: "o01:id01", OrderID: "o01", CourseID: "id01", Amount: testutil.USD(1000)}}
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		courseID   string
+		findErr    error
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", `{"courseId":"id01"}`, "ana", "id01", nil, nil, 1, http.StatusCreated},
+		{"Status created bundle", `{"bundleId":"b01"}`, "ana", "b01", nil, nil, 1, http.StatusCreated},
+		{"Status created every course", "", "ana", "", nil, nil, 1, http.StatusCreated},
+		{"Status created by an admin", "", "root", "", nil, nil, 1, http.StatusCreated},
+		{"Status created but redis err", "", "ana", "", &cache.RedisErr{}, &cache.RedisErr{}, 1, http.StatusCreated},
+		{"Status bad request body", `{"courseId":1}`, "ana", "", nil, nil, 0, http.StatusBadRequest},
+		{"Status forbidden", "", "bob", "", nil, nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", "", "", "", nil, nil, 0, http.StatusForbidden},
+		{"Status not found", `{"courseId":"id01"}`, "ana", "id01", storage.ErrNotFound, nil, 0, http.StatusNotFound},
+		{"Status conflict not paid", "", "ana", "", nil, orderservice.ErrNotRefundable, 1, http.StatusConflict},
+		{"Status conflict already refunded", `{"courseId":"id01"}`, "ana", "id01", nil, orderservice.ErrAlreadyRefunded, 1, http.StatusConflict},
+		{"Status internal server error", "", "ana", "", nil, mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var orderServiceMngr = &orderservice.Mock{}
+			orderServiceMngr.On("FindOne", "o01").Return(testOrder, tt.findErr).Maybe()
+			orderServiceMngr.On("Refund", mock.Anything, "o01", tt.courseID).Return(rs, tt.mockErr).Maybe().Times(tt.mockTimes)
+			orderServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/orders/o01/refunds", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = withActor(req, tt.actor)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("o01")
+
+			_ = RefundOrder(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			orderServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+//withActor makes the actor the one of the request, the root actor being an admin and the mod actor a moderator
+func withActor(req *http.Request, a string) *http.Request {
+	if a == "" {
+		return req
+	}
+	ctx := actor.NewContext(req.Context(), a)
+	switch a {
+	case "root":
+		ctx = actor.WithRoles(ctx, actor.Admin)
+	case "mod":
+		ctx = actor.WithRoles(ctx, actor.Moderator)
+	}
+	return req.WithContext(ctx)
+}
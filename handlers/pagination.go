@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+
+	//HeaderTotalCount is the header holding the total of documents matching a list request
+	HeaderTotalCount = "X-Total-Count"
+	//HeaderNextCursor is the header holding the cursor of the next page of a list request
+	HeaderNextCursor = "X-Next-Cursor"
+	//HeaderLink is the header holding the web link (RFC 8288) of the next page of a list request
+	HeaderLink = "Link"
+)
+
+//listOptions reads the pagination, sorting, filtering and facets query parameters of a list request
+func listOptions(c echo.Context) (opts courseservice.ListOptions, err error) {
+	opts.Limit = defaultPageLimit
+	if v := c.QueryParam("limit"); v != "" {
+		if opts.Limit, err = strconv.ParseInt(v, 10, 64); err != nil || opts.Limit < 1 || opts.Limit > maxPageLimit {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+		}
+	}
+
+	if v := c.QueryParam("cursor"); v != "" {
+		if opts.Offset, err = decodeCursor(v); err != nil {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+		}
+	} else if v := c.QueryParam("offset"); v != "" {
+		if opts.Offset, err = strconv.ParseInt(v, 10, 64); err != nil || opts.Offset < 0 {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, "offset must be a positive number")
+		}
+	}
+
+	opts.Sort = c.QueryParam("sort")
+	if opts.Sort != "" && !courseservice.SortFields[strings.TrimPrefix(opts.Sort, "-")] {
+		return opts, echo.NewHTTPError(http.StatusBadRequest, "invalid sort field")
+	}
+
+	opts.Currency = strings.ToUpper(c.QueryParam("currency"))
+	if opts.Currency != "" && !money.IsCurrency(opts.Currency) {
+		return opts, echo.NewHTTPError(http.StatusBadRequest, "unknown currency")
+	}
+	if opts.MinPrice, err = priceParam(c, "minPrice", opts.Currency); err != nil {
+		return opts, err
+	}
+	if opts.MaxPrice, err = priceParam(c, "maxPrice", opts.Currency); err != nil {
+		return opts, err
+	}
+
+	opts.Status = c.QueryParam("status")
+	switch opts.Status {
+	case "", types.StatusDraft, types.StatusInReview, types.StatusPublished, types.StatusArchived:
+	default:
+		return opts, echo.NewHTTPError(http.StatusBadRequest, "invalid status")
+	}
+
+	opts.Category = c.QueryParam("category")
+	for _, t := range c.QueryParams()["tag"] {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			opts.Tags = append(opts.Tags, t)
+		}
+	}
+	if v := c.QueryParam("facets"); v != "" {
+		if opts.Facets, err = strconv.ParseBool(v); err != nil {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, "facets must be a boolean")
+		}
+	}
+	return opts, nil
+}
+
+//authorOnly restricts the list to the courses of the actor of the request unless it lists the published ones out of
+//the trash, which are public, or the actor is an admin. Anonymous requests are forbidden to list other courses.
+func authorOnly(c echo.Context, opts *courseservice.ListOptions) error {
+	if !opts.Deleted && (opts.Status == "" || opts.Status == types.StatusPublished) {
+		return nil
+	}
+	if actor.HasRole(c.Request().Context(), actor.Admin) {
+		return nil
+	}
+	opts.Author = actor.FromContext(c.Request().Context())
+	if opts.Author == actor.Anonymous {
+		return echo.NewHTTPError(http.StatusForbidden, "only authors list courses which are not published or in the trash")
+	}
+	return nil
+}
+
+//priceParam reads a decimal amount of the currency, the default one when it is empty, in its minor units
+func priceParam(c echo.Context, name, currency string) (*int64, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return nil, nil
+	}
+	if currency == "" {
+		currency = types.DefaultCurrency
+	}
+	m, err := money.Parse(v, currency)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, name+" must be an amount of "+currency)
+	}
+	return &m.Amount, nil
+}
+
+//setPageHeaders writes the total count and, when there is one, the cursor and link of the next page
+func setPageHeaders(c echo.Context, opts courseservice.ListOptions, p courseservice.Page) {
+	h := c.Response().Header()
+	h.Set(HeaderTotalCount, strconv.FormatInt(p.Total, 10))
+
+	next := opts.Offset + int64(len(p.Courses))
+	if len(p.Courses) == 0 || next >= p.Total {
+		return
+	}
+	cursor := encodeCursor(next)
+	u := *c.Request().URL
+	q := u.Query()
+	q.Del("offset")
+	q.Set("cursor", cursor)
+	u.RawQuery = q.Encode()
+	h.Set(HeaderNextCursor, cursor)
+	h.Set(HeaderLink, fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
+
+//encodeCursor turns an offset in an opaque cursor
+func encodeCursor(offset int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(offset, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.ParseInt(string(b), 10, 64)
+	if err == nil && offset < 0 {
+		err = fmt.Errorf("negative cursor %d", offset)
+	}
+	return offset, err
+}
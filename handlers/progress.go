@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/progressservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+//progressRequest is the body recording the progress of a student in a lesson, watched is the percent of its video
+type progressRequest struct {
+	Watched   float64 `json:"watched" validate:"gte=0,lte=100"`
+	Completed bool    `json:"completed"`
+}
+
+//errNotProgressStudent for progress seen or recorded by someone who is neither its student nor an admin
+var errNotProgressStudent = echo.NewHTTPError(http.StatusForbidden, "only the student and the admins follow the progress of the student")
+
+//GetProgress is a handler to get the progress of the student of the path parameter id in the course of the path
+//parameter course. Only the student and the admins get it.
+func GetProgress(c echo.Context) error {
+	if !actsFor(c, c.Param("id")) {
+		return progressFail(c, errNotProgressStudent)
+	}
+	p, err := progressservice.GetInstance().Progress(c.Param("id"), c.Param("course"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, p)
+	}
+	return progressFail(c, err)
+}
+
+//RecordProgress is a handler to record the progress of the student of the path parameter id in the lesson of the
+//path parameter lesson, passing a progressRequest in the body. Completing the course issues its certificate. Only the
+//student and the admins record it.
+func RecordProgress(c echo.Context) error {
+	if !actsFor(c, c.Param("id")) {
+		return progressFail(c, errNotProgressStudent)
+	}
+	var req progressRequest
+	if err := c.Bind(&req); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	p, err := progressservice.GetInstance().Record(c.Request().Context(), c.Param("id"), c.Param("course"), c.Param("lesson"), req.Watched, req.Completed)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, p)
+	}
+	return progressFail(c, err)
+}
+
+//GetCertificate is a handler to get the completion certificate of the path parameter id
+func GetCertificate(c echo.Context) error {
+	cert, err := progressservice.GetInstance().Certificate(c.Param("id"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, cert)
+	}
+	return progressFail(c, err)
+}
+
+//VerifyCertificate is a handler that tells whether the types.Certificate in the body was issued as it is
+func VerifyCertificate(c echo.Context) error {
+	var cert types.Certificate
+	if err := c.Bind(&cert); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"valid": progressservice.GetInstance().Verify(cert)})
+}
+
+func progressFail(c echo.Context, err error) error {
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case errNotProgressStudent, progressservice.ErrNotEnrolled:
+		httpStatus = http.StatusForbidden
+	}
+	return fail(c, httpStatus, err)
+}
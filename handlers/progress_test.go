@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/progressservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+)
+
+func TestGetProgress(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", "ana", nil, 1, http.StatusOK},
+		{"Status ok for an admin", "root", nil, 1, http.StatusOK},
+		{"Status ok but redis err", "ana", &cache.RedisErr{}, 1, http.StatusOK},
+		{"Status not found", "ana", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status forbidden", "ana", progressservice.ErrNotEnrolled, 1, http.StatusForbidden},
+		{"Status forbidden another student", "bob", nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", "", nil, 0, http.StatusForbidden},
+		{"Status internal server error", "ana", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var progressServiceMngr = &progressservice.Mock{}
+			progressServiceMngr.On("Progress", "ana", "id01").Return(types.Progress{ID: "id01:ana"}, tt.mockErr).Maybe().Times(tt.mockTimes)
+			progressServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(withActor(httptest.NewRequest(http.MethodGet, "/students/ana/courses/id01/progress", nil), tt.actor), rec)
+			c.SetParamNames("id", "course")
+			c.SetParamValues("ana", "id01")
+
+			_ = GetProgress(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			progressServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecordProgress(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", `{"watched":50}`, "ana", nil, 1, http.StatusOK},
+		{"Status ok for an admin", `{"watched":50}`, "root", nil, 1, http.StatusOK},
+		{"Status ok but redis err", `{"watched":50}`, "ana", &cache.RedisErr{}, 1, http.StatusOK},
+		{"Status bad request body", `{"watched":"50"}`, "ana", nil, 0, http.StatusBadRequest},
+		{"Status bad request watched", `{"watched":150}`, "ana", nil, 0, http.StatusBadRequest},
+		{"Status not found", `{"watched":50}`, "ana", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status forbidden", `{"watched":50}`, "ana", progressservice.ErrNotEnrolled, 1, http.StatusForbidden},
+		{"Status forbidden another student", `{"watched":50}`, "bob", nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", `{"watched":50}`, "", nil, 0, http.StatusForbidden},
+		{"Status internal server error", `{"watched":50}`, "ana", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var progressServiceMngr = &progressservice.Mock{}
+			progressServiceMngr.On("Record", mock.Anything, "ana", "id01", "l01", float64(50), false).Return(types.Progress{}, tt.mockErr).Maybe().Times(tt.mockTimes)
+			progressServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/students/ana/courses/id01/lessons/l01/progress", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = withActor(req, tt.actor)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "course", "lesson")
+			c.SetParamValues("ana", "id01", "l01")
+
+			_ = RecordProgress(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			progressServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCertificate(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		statusCode int
+	}{
+		{"Status ok", nil, http.StatusOK},
+		{"Status not found", storage.ErrNotFound, http.StatusNotFound},
+		{"Status internal server error", mgo.ErrCursor, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var progressServiceMngr = &progressservice.Mock{}
+			progressServiceMngr.On("Certificate", "c01").Return(types.Certificate{ID: "c01"}, tt.mockErr).Once()
+			progressServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/certificates/c01", nil), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("c01")
+
+			_ = GetCertificate(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			progressServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		valid      bool
+		mockTimes  int
+		statusCode int
+		response   string
+	}{
+		{"Status ok valid", `{"id":"c01"}`, true, 1, http.StatusOK, `{"valid":true}` + "\n"},
+		{"Status ok forged", `{"id":"c01"}`, false, 1, http.StatusOK, `{"valid":false}` + "\n"},
+		{"Status bad request", `{"id":1}`, false, 0, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var progressServiceMngr = &progressservice.Mock{}
+			progressServiceMngr.On("Verify", types.Certificate{ID: "c01"}).Return(tt.valid).Maybe().Times(tt.mockTimes)
+			progressServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/certificates/verify", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			_ = VerifyCertificate(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.response != "" {
+				assert.Equal(t, tt.response, rec.Body.String())
+			}
+			progressServiceMngr.AssertExpectations(t)
+		})
+	}
+}
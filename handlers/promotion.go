@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/services/promotionservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+//quoteRequest is the body of a quote, the coupon and the currency are optional
+type quoteRequest struct {
+	Coupon   string `json:"coupon"`
+	Currency string `json:"currency" validate:"omitempty,currency"`
+}
+
+//errNotCouponAdmin for coupons seen or managed by someone who is not an admin
+var errNotCouponAdmin = echo.NewHTTPError(http.StatusForbidden, "only the admins manage the coupons")
+
+//GetCoupons is a handler to get every coupon by code, for the admins
+func GetCoupons(c echo.Context) error {
+	if !actor.HasRole(c.Request().Context(), actor.Admin) {
+		return promotionFail(c, errNotCouponAdmin)
+	}
+	cps, err := promotionservice.GetInstance().FindAll()
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return fail(c, http.StatusInternalServerError, err)
+	}
+	if cps == nil {
+		cps = []types.Coupon{}
+	}
+	return c.JSON(http.StatusOK, cps)
+}
+
+//GetCoupon is a handler to get the coupon of the path parameter code, for the admins
+func GetCoupon(c echo.Context) error {
+	if !actor.HasRole(c.Request().Context(), actor.Admin) {
+		return promotionFail(c, errNotCouponAdmin)
+	}
+	cp, err := promotionservice.GetInstance().FindOne(c.Param("code"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, cp)
+	}
+	return promotionFail(c, err)
+}
+
+//SetCoupon is a handler to create a coupon passing a types.Coupon in the body. Codes in use are a conflict. Only the
+//admins create coupons.
+func SetCoupon(c echo.Context) error {
+	if !actor.HasRole(c.Request().Context(), actor.Admin) {
+		return promotionFail(c, errNotCouponAdmin)
+	}
+	var cp types.Coupon
+	if err := c.Bind(&cp); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&cp); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	cp, err := promotionservice.GetInstance().Create(c.Request().Context(), cp)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, cp)
+	}
+	return promotionFail(c, err)
+}
+
+//PutCoupon is a handler to change the terms of the coupon of the path parameter code passing a types.Coupon in the body.
+//Only the admins change them.
+func PutCoupon(c echo.Context) error {
+	if !actor.HasRole(c.Request().Context(), actor.Admin) {
+		return promotionFail(c, errNotCouponAdmin)
+	}
+	var cp types.Coupon
+	if err := c.Bind(&cp); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	cp.Code = c.Param("code")
+	if err := c.Validate(&cp); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	cp, err := promotionservice.GetInstance().Update(c.Request().Context(), cp)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, cp)
+	}
+	return promotionFail(c, err)
+}
+
+//DelCoupon is a handler that removes the coupon of the path parameter code, for the admins
+func DelCoupon(c echo.Context) error {
+	if !actor.HasRole(c.Request().Context(), actor.Admin) {
+		return promotionFail(c, errNotCouponAdmin)
+	}
+	err := promotionservice.GetInstance().Delete(c.Request().Context(), c.Param("code"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.NoContent(http.StatusOK)
+	}
+	return promotionFail(c, err)
+}
+
+//QuoteCourse is a handler answering the final price of the course of the path parameter id with the coupon of the
+//body, in the currency of the body or the one of the course. The limits per student are checked for the actor.
+func QuoteCourse(c echo.Context) error {
+	var req quoteRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return fail(c, http.StatusBadRequest, err)
+		}
+	}
+	if err := c.Validate(&req); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	student := actor.FromContext(c.Request().Context())
+	if student == actor.Anonymous {
+		student = ""
+	}
+
+	q, err := promotionservice.GetInstance().Quote(c.Param("id"), req.Coupon, student, req.Currency)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, q)
+	}
+	return promotionFail(c, err)
+}
+
+func promotionFail(c echo.Context, err error) error {
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case errNotCouponAdmin:
+		httpStatus = http.StatusForbidden
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case storage.ErrDuplicate, promotionservice.ErrCouponNotApplicable, promotionservice.ErrCouponExhausted:
+		httpStatus = http.StatusConflict
+	case promotionservice.ErrInvalidCoupon, promotionservice.ErrUnknownCoupon, money.ErrUnknownCurrency, money.ErrNoRate:
+		httpStatus = http.StatusBadRequest
+	}
+	return fail(c, httpStatus, err)
+}
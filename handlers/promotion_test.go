@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/services/promotionservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+)
+
+var testCoupon = types.Coupon{Code: "LAUNCH", Kind: types.CouponPercent, Percent: 20}
+
+func TestGetCoupons(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		mockCps    []types.Coupon
+		mockErr    error
+		mockTimes  int
+		statusCode int
+		body       string
+	}{
+		{"Status ok", "root", []types.Coupon{testCoupon}, nil, 1, http.StatusOK, `[{"code":"LAUNCH","kind":"percent","percent":20,"uses":0}]` + "\n"},
+		{"Status ok empty", "root", nil, nil, 1, http.StatusOK, "[]\n"},
+		{"Status ok but redis err", "root", nil, &cache.RedisErr{}, 1, http.StatusOK, "[]\n"},
+		{"Status forbidden", "ana", nil, nil, 0, http.StatusForbidden, ""},
+		{"Status forbidden anonymous", "", nil, nil, 0, http.StatusForbidden, ""},
+		{"Status internal server error", "root", nil, mgo.ErrCursor, 1, http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var promotionServiceMngr = &promotionservice.Mock{}
+			promotionServiceMngr.On("FindAll").Return(tt.mockCps, tt.mockErr).Maybe().Times(tt.mockTimes)
+			promotionServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			_ = GetCoupons(e.NewContext(withActor(httptest.NewRequest(http.MethodGet, "/coupons", nil), tt.actor), rec))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, rec.Body.String())
+			}
+			promotionServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCoupon(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", "root", nil, 1, http.StatusOK},
+		{"Status ok but redis err", "root", &cache.RedisErr{}, 1, http.StatusOK},
+		{"Status forbidden", "ana", nil, 0, http.StatusForbidden},
+		{"Status not found", "root", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status internal server error", "root", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var promotionServiceMngr = &promotionservice.Mock{}
+			promotionServiceMngr.On("FindOne", "LAUNCH").Return(testCoupon, tt.mockErr).Maybe().Times(tt.mockTimes)
+			promotionServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(withActor(httptest.NewRequest(http.MethodGet, "/coupons/LAUNCH", nil), tt.actor), rec)
+			c.SetParamNames("code")
+			c.SetParamValues("LAUNCH")
+
+			_ = GetCoupon(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			promotionServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetCoupon(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", `{"code":"LAUNCH","kind":"percent","percent":20}`, "root", nil, 1, http.StatusCreated},
+		{"Status bad request body", `{"code":1}`, "root", nil, 0, http.StatusBadRequest},
+		{"Status bad request kind", `{"code":"LAUNCH","kind":"free"}`, "root", nil, 0, http.StatusBadRequest},
+		{"Status bad request currency", `{"code":"LAUNCH","kind":"fixed","amount":{"amount":500,"currency":"EURO"}}`, "root", nil, 0, http.StatusBadRequest},
+		{"Status bad request invalid coupon", `{"code":"LAUNCH","kind":"percent","percent":20}`, "root", promotionservice.ErrInvalidCoupon, 1, http.StatusBadRequest},
+		{"Status forbidden", `{"code":"LAUNCH","kind":"percent","percent":20}`, "ana", nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", `{"code":"LAUNCH","kind":"percent","percent":20}`, "", nil, 0, http.StatusForbidden},
+		{"Status conflict", `{"code":"LAUNCH","kind":"percent","percent":20}`, "root", storage.ErrDuplicate, 1, http.StatusConflict},
+		{"Status internal server error", `{"code":"LAUNCH","kind":"percent","percent":20}`, "root", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var promotionServiceMngr = &promotionservice.Mock{}
+			promotionServiceMngr.On("Create", mock.Anything, testCoupon).Return(testCoupon, tt.mockErr).Maybe().Times(tt.mockTimes)
+			promotionServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/coupons", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = withActor(req, tt.actor)
+			rec := httptest.NewRecorder()
+
+			_ = SetCoupon(e.NewContext(req, rec))
+			assert.Equal(t, tt.statusCode, rec.Code)
+			promotionServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPutAndDelCoupon(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", "root", nil, 1, http.StatusOK},
+		{"Status ok but redis err", "root", &cache.RedisErr{}, 1, http.StatusOK},
+		{"Status forbidden", "ana", nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", "", nil, 0, http.StatusForbidden},
+		{"Status not found", "root", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status internal server error", "root", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var promotionServiceMngr = &promotionservice.Mock{}
+			promotionServiceMngr.On("Update", mock.Anything, testCoupon).Return(testCoupon, tt.mockErr).Maybe().Times(tt.mockTimes)
+			promotionServiceMngr.On("Delete", mock.Anything, "LAUNCH").Return(tt.mockErr).Maybe().Times(tt.mockTimes)
+			promotionServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPut, "/coupons/LAUNCH", strings.NewReader(`{"code":"OTHER","kind":"percent","percent":20}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(withActor(req, tt.actor), rec)
+			c.SetParamNames("code")
+			c.SetParamValues("LAUNCH")
+			_ = PutCoupon(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+
+			rec = httptest.NewRecorder()
+			c = e.NewContext(withActor(httptest.NewRequest(http.MethodDelete, "/coupons/LAUNCH", nil), tt.actor), rec)
+			c.SetParamNames("code")
+			c.SetParamValues("LAUNCH")
+			_ = DelCoupon(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			promotionServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestQuoteCourse(t *testing.T) {
+	quote := types.Quote{CourseID: "id01", Coupon: "LAUNCH", Price: testutil.USD(1000), Discount: testutil.USD(200), Total: testutil.USD(800)}
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		student    string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", `{"coupon":"LAUNCH","currency":"usd"}`, "ana", "ana", nil, 1, http.StatusOK},
+		{"Status ok anonymous", `{"coupon":"LAUNCH","currency":"usd"}`, "", "", nil, 1, http.StatusOK},
+		{"Status ok but redis err", `{"coupon":"LAUNCH","currency":"usd"}`, "", "", &cache.RedisErr{}, 1, http.StatusOK},
+		{"Status bad request body", `{"coupon":1}`, "", "", nil, 0, http.StatusBadRequest},
+		{"Status bad request currency", `{"coupon":"LAUNCH","currency":"EURO"}`, "", "", nil, 0, http.StatusBadRequest},
+		{"Status bad request unknown coupon", `{"coupon":"LAUNCH","currency":"usd"}`, "", "", promotionservice.ErrUnknownCoupon, 1, http.StatusBadRequest},
+		{"Status bad request no rate", `{"coupon":"LAUNCH","currency":"usd"}`, "", "", money.ErrNoRate, 1, http.StatusBadRequest},
+		{"Status not found", `{"coupon":"LAUNCH","currency":"usd"}`, "", "", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status conflict not applicable", `{"coupon":"LAUNCH","currency":"usd"}`, "", "", promotionservice.ErrCouponNotApplicable, 1, http.StatusConflict},
+		{"Status conflict exhausted", `{"coupon":"LAUNCH","currency":"usd"}`, "ana", "ana", promotionservice.ErrCouponExhausted, 1, http.StatusConflict},
+		{"Status internal server error", `{"coupon":"LAUNCH","currency":"usd"}`, "", "", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var promotionServiceMngr = &promotionservice.Mock{}
+			promotionServiceMngr.On("Quote", "id01", "LAUNCH", tt.student, "usd").Return(quote, tt.mockErr).Maybe().Times(tt.mockTimes)
+			promotionServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/courses/id01/quote", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.actor != "" {
+				req = req.WithContext(actor.NewContext(req.Context(), tt.actor))
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = QuoteCourse(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.statusCode == http.StatusOK {
+				assert.JSONEq(t, `{"courseId":"id01","coupon":"LAUNCH","price":{"amount":1000,"currency":"USD"},`+
+					`"discount":{"amount":200,"currency":"USD"},"total":{"amount":800,"currency":"USD"}}`, rec.Body.String())
+			}
+			promotionServiceMngr.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/reviewservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+)
+
+//errNotReviewer for reviews written, changed or removed by someone who is not their student
+var errNotReviewer = echo.NewHTTPError(http.StatusForbidden, "only the student of the review writes it")
+
+//errNotModerator for reviews moderated, or hidden ones seen, by someone who is not a moderator
+var errNotModerator = echo.NewHTTPError(http.StatusForbidden, "only the moderators moderate the reviews")
+
+//moderationRequest is the body of a moderation, the status the review moves to
+type moderationRequest struct {
+	Status string `json:"status"`
+}
+
+//GetReviews is a handler to get the reviews of the course of the path parameter id, the visible ones unless the
+//query parameter status is hidden. Only the moderators get the hidden ones.
+func GetReviews(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status != "" && status != types.ReviewVisible && status != types.ReviewHidden {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "invalid status"))
+	}
+	if status == types.ReviewHidden && !actor.HasRole(c.Request().Context(), actor.Moderator) {
+		return reviewFail(c, errNotModerator)
+	}
+	rs, err := reviewservice.GetInstance().FindByCourse(c.Param("id"), status)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err != nil {
+		return reviewFail(c, err)
+	}
+	if rs == nil {
+		rs = []types.Review{}
+	}
+	return c.JSON(http.StatusOK, rs)
+}
+
+//SetReview is a handler to review the course of the path parameter id, passing a types.Review in the body. The
+//student is the actor of the request, who reviews for no one else.
+func SetReview(c echo.Context) error {
+	var r types.Review
+	if err := c.Bind(&r); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	a := actor.FromContext(c.Request().Context())
+	if a == actor.Anonymous {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "student is required"))
+	}
+	if r.Student == "" {
+		r.Student = a
+	}
+	if r.Student != a {
+		return reviewFail(c, errNotReviewer)
+	}
+	if err := c.Validate(&r); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	r, err := reviewservice.GetInstance().Create(c.Request().Context(), c.Param("id"), r)
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusCreated, r)
+	}
+	return reviewFail(c, err)
+}
+
+//PutReview is a handler to change the review of the student of the path parameter student of the course of the
+//path parameter id, passing a types.Review in the body. Only the student changes it.
+func PutReview(c echo.Context) error {
+	var r types.Review
+	if err := c.Bind(&r); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	r.Student = c.Param("student")
+	if !isActor(c, r.Student) {
+		return reviewFail(c, errNotReviewer)
+	}
+	if err := c.Validate(&r); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+
+	r, err := reviewservice.GetInstance().Update(c.Request().Context(), c.Param("id"), r)
+	return review(c, r, err)
+}
+
+//DelReview is a handler to remove the review of the student of the path parameter student of the course of the
+//path parameter id. Only the student and the moderators remove it.
+func DelReview(c echo.Context) error {
+	if !isActor(c, c.Param("student")) && !actor.HasRole(c.Request().Context(), actor.Moderator) {
+		return reviewFail(c, errNotReviewer)
+	}
+	err := reviewservice.GetInstance().Delete(c.Request().Context(), c.Param("id"), c.Param("student"))
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.NoContent(http.StatusOK)
+	}
+	return reviewFail(c, err)
+}
+
+//FlagReview is a handler reporting to the moderators the review of the student of the path parameter student of
+//the course of the path parameter id
+func FlagReview(c echo.Context) error {
+	if actor.FromContext(c.Request().Context()) == actor.Anonymous {
+		return fail(c, http.StatusBadRequest, echo.NewHTTPError(http.StatusBadRequest, "only users flag reviews"))
+	}
+	r, err := reviewservice.GetInstance().Flag(c.Request().Context(), c.Param("id"), c.Param("student"))
+	return review(c, r, err)
+}
+
+//ModerateReview is a handler that moves the review of the student of the path parameter student of the course of
+//the path parameter id to the status of the moderationRequest in the body. Only the moderators moderate them.
+func ModerateReview(c echo.Context) error {
+	if !actor.HasRole(c.Request().Context(), actor.Moderator) {
+		return reviewFail(c, errNotModerator)
+	}
+	var req moderationRequest
+	if err := c.Bind(&req); err != nil {
+		return fail(c, http.StatusBadRequest, err)
+	}
+	r, err := reviewservice.GetInstance().Moderate(c.Request().Context(), c.Param("id"), c.Param("student"), req.Status)
+	return review(c, r, err)
+}
+
+func review(c echo.Context, r types.Review, err error) error {
+	if serr, ok := err.(*cache.RedisErr); ok {
+		c.Logger().Warn(serr)
+		err = nil
+	}
+	if err == nil {
+		return c.JSON(http.StatusOK, r)
+	}
+	return reviewFail(c, err)
+}
+
+func reviewFail(c echo.Context, err error) error {
+	httpStatus := http.StatusInternalServerError
+	switch err {
+	case storage.ErrNotFound:
+		httpStatus = http.StatusNotFound
+	case reviewservice.ErrInvalidStatus:
+		httpStatus = http.StatusBadRequest
+	case reviewservice.ErrNotEnrolled, errNotReviewer, errNotModerator:
+		httpStatus = http.StatusForbidden
+	case reviewservice.ErrAlreadyReviewed:
+		httpStatus = http.StatusConflict
+	}
+	return fail(c, httpStatus, err)
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/reviewservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/mgo.v2"
+)
+
+func TestGetReviews(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		actor      string
+		mockRs     []types.Review
+		mockErr    error
+		mockTimes  int
+		statusCode int
+		body       string
+	}{
+		{"Status ok", "", "", []types.Review{{ID: "id01:ana", Rating: 5}}, nil, 1, http.StatusOK, ""},
+		{"Status ok empty", "", "", nil, nil, 1, http.StatusOK, "[]\n"},
+		{"Status ok hidden", types.ReviewHidden, "mod", nil, nil, 1, http.StatusOK, "[]\n"},
+		{"Status ok but redis err", "", "", nil, &cache.RedisErr{}, 1, http.StatusOK, "[]\n"},
+		{"Status bad request", "flagged", "", nil, nil, 0, http.StatusBadRequest, ""},
+		{"Status forbidden hidden", types.ReviewHidden, "ana", nil, nil, 0, http.StatusForbidden, ""},
+		{"Status forbidden hidden anonymous", types.ReviewHidden, "", nil, nil, 0, http.StatusForbidden, ""},
+		{"Status not found", "", "", nil, storage.ErrNotFound, 1, http.StatusNotFound, ""},
+		{"Status internal server error", "", "", nil, mgo.ErrCursor, 1, http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reviewServiceMngr = &reviewservice.Mock{}
+			reviewServiceMngr.On("FindByCourse", "id01", tt.status).Return(tt.mockRs, tt.mockErr).Maybe().Times(tt.mockTimes)
+			reviewServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(withActor(httptest.NewRequest(http.MethodGet, "/courses/id01/reviews?status="+tt.status, nil), tt.actor), rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = GetReviews(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, rec.Body.String())
+			}
+			reviewServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetReview(t *testing.T) {
+	r := types.Review{Student: "ana", Rating: 4, Text: "good"}
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status created", `{"student":"ana","rating":4,"text":"good"}`, "ana", nil, 1, http.StatusCreated},
+		{"Status created as the actor", `{"rating":4,"text":"good"}`, "ana", nil, 1, http.StatusCreated},
+		{"Status created but redis err", `{"student":"ana","rating":4,"text":"good"}`, "ana", &cache.RedisErr{}, 1, http.StatusCreated},
+		{"Status bad request anonymous", `{"rating":4,"text":"good"}`, "", nil, 0, http.StatusBadRequest},
+		{"Status bad request anonymous for the body", `{"student":"ana","rating":4,"text":"good"}`, "", nil, 0, http.StatusBadRequest},
+		{"Status forbidden another student", `{"student":"ana","rating":4,"text":"good"}`, "eve", nil, 0, http.StatusForbidden},
+		{"Status bad request rating", `{"student":"ana","rating":6}`, "ana", nil, 0, http.StatusBadRequest},
+		{"Status bad request body", `{"student":"ana","rating":"4"}`, "ana", nil, 0, http.StatusBadRequest},
+		{"Status not found", `{"student":"ana","rating":4,"text":"good"}`, "ana", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status forbidden", `{"student":"ana","rating":4,"text":"good"}`, "ana", reviewservice.ErrNotEnrolled, 1, http.StatusForbidden},
+		{"Status conflict", `{"student":"ana","rating":4,"text":"good"}`, "ana", reviewservice.ErrAlreadyReviewed, 1, http.StatusConflict},
+		{"Status internal server error", `{"student":"ana","rating":4,"text":"good"}`, "ana", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reviewServiceMngr = &reviewservice.Mock{}
+			reviewServiceMngr.On("Create", mock.Anything, "id01", r).Return(r, tt.mockErr).Maybe().Times(tt.mockTimes)
+			reviewServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPost, "/courses/id01/reviews", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = withActor(req, tt.actor)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("id01")
+
+			_ = SetReview(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			reviewServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPutReview(t *testing.T) {
+	r := types.Review{Student: "ana", Rating: 2}
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", `{"rating":2}`, "ana", nil, 1, http.StatusOK},
+		{"Status ok the student of the path", `{"student":"eve","rating":2}`, "ana", nil, 1, http.StatusOK},
+		{"Status bad request", `{"rating":0}`, "ana", nil, 0, http.StatusBadRequest},
+		{"Status forbidden another student", `{"rating":2}`, "eve", nil, 0, http.StatusForbidden},
+		{"Status forbidden an admin", `{"rating":2}`, "root", nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", `{"rating":2}`, "", nil, 0, http.StatusForbidden},
+		{"Status not found", `{"rating":2}`, "ana", storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status internal server error", `{"rating":2}`, "ana", mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reviewServiceMngr = &reviewservice.Mock{}
+			reviewServiceMngr.On("Update", mock.Anything, "id01", r).Return(r, tt.mockErr).Maybe().Times(tt.mockTimes)
+			reviewServiceMngr.InitMock()
+
+			e := echo.New()
+			e.Validator = NewValidator()
+			req := httptest.NewRequest(http.MethodPut, "/courses/id01/reviews/ana", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = withActor(req, tt.actor)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "student")
+			c.SetParamValues("id01", "ana")
+
+			_ = PutReview(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			reviewServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDelReview(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		roles      []string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", "ana", nil, nil, 1, http.StatusOK},
+		{"Status ok for a moderator", "mod", []string{actor.Moderator}, nil, 1, http.StatusOK},
+		{"Status ok but redis err", "ana", nil, &cache.RedisErr{}, 1, http.StatusOK},
+		{"Status forbidden another student", "eve", nil, nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", "", nil, nil, 0, http.StatusForbidden},
+		{"Status not found", "ana", nil, storage.ErrNotFound, 1, http.StatusNotFound},
+		{"Status internal server error", "ana", nil, mgo.ErrCursor, 1, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reviewServiceMngr = &reviewservice.Mock{}
+			reviewServiceMngr.On("Delete", mock.Anything, "id01", "ana").Return(tt.mockErr).Maybe().Times(tt.mockTimes)
+			reviewServiceMngr.InitMock()
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			req := withActor(httptest.NewRequest(http.MethodDelete, "/courses/id01/reviews/ana", nil), tt.actor)
+			req = req.WithContext(actor.WithRoles(req.Context(), tt.roles...))
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "student")
+			c.SetParamValues("id01", "ana")
+
+			_ = DelReview(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			reviewServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFlagReview(t *testing.T) {
+	tests := []struct {
+		name       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", "bob", nil, 1, http.StatusOK},
+		{"Status bad request anonymous", "", nil, 0, http.StatusBadRequest},
+		{"Status not found", "bob", storage.ErrNotFound, 1, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reviewServiceMngr = &reviewservice.Mock{}
+			reviewServiceMngr.On("Flag", mock.Anything, "id01", "ana").Return(types.Review{}, tt.mockErr).Maybe().Times(tt.mockTimes)
+			reviewServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/courses/id01/reviews/ana/flag", nil)
+			if tt.actor != "" {
+				req = req.WithContext(actor.NewContext(req.Context(), tt.actor))
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "student")
+			c.SetParamValues("id01", "ana")
+
+			_ = FlagReview(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			reviewServiceMngr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestModerateReview(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		actor      string
+		mockErr    error
+		mockTimes  int
+		statusCode int
+	}{
+		{"Status ok", `{"status":"hidden"}`, "mod", nil, 1, http.StatusOK},
+		{"Status bad request body", `{"status":1}`, "mod", nil, 0, http.StatusBadRequest},
+		{"Status bad request status", `{"status":"hidden"}`, "mod", reviewservice.ErrInvalidStatus, 1, http.StatusBadRequest},
+		{"Status forbidden", `{"status":"hidden"}`, "ana", nil, 0, http.StatusForbidden},
+		{"Status forbidden admin", `{"status":"hidden"}`, "root", nil, 0, http.StatusForbidden},
+		{"Status forbidden anonymous", `{"status":"hidden"}`, "", nil, 0, http.StatusForbidden},
+		{"Status not found", `{"status":"hidden"}`, "mod", storage.ErrNotFound, 1, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reviewServiceMngr = &reviewservice.Mock{}
+			reviewServiceMngr.On("Moderate", mock.Anything, "id01", "ana", types.ReviewHidden).Return(types.Review{}, tt.mockErr).Maybe().Times(tt.mockTimes)
+			reviewServiceMngr.InitMock()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/courses/id01/reviews/ana/status", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req = withActor(req, tt.actor)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id", "student")
+			c.SetParamValues("id01", "ana")
+
+			_ = ModerateReview(c)
+			assert.Equal(t, tt.statusCode, rec.Code)
+			reviewServiceMngr.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ednesic/coursemanagement/money"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+//Validator validates the bodies bound by the handlers with the validate tags of their types
+type Validator struct {
+	validate *validator.Validate
+}
+
+//NewValidator returns a Validator naming the fields of its errors after their json names. The currency tag
+//validates ISO 4217 codes, whatever their case.
+func NewValidator() *Validator {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	_ = v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return money.IsCurrency(strings.ToUpper(fl.Field().String()))
+	})
+	return &Validator{validate: v}
+}
+
+//Validate is the echo.Validator used by c.Validate, answering validator.ValidationErrors for invalid bodies
+func (v *Validator) Validate(i interface{}) error {
+	return v.validate.Struct(i)
+}
@@ -2,13 +2,28 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"os"
 	"os/signal"
+	"strconv"
 	"time"
 
+	"github.com/ednesic/coursemanagement/actor"
 	"github.com/ednesic/coursemanagement/cache"
 	"github.com/ednesic/coursemanagement/handlers"
 	"github.com/ednesic/coursemanagement/metrics"
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/payment"
+	"github.com/ednesic/coursemanagement/scheduler"
+	"github.com/ednesic/coursemanagement/services/bundleservice"
+	"github.com/ednesic/coursemanagement/services/categoryservice"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/services/instructorservice"
+	"github.com/ednesic/coursemanagement/services/orderservice"
+	"github.com/ednesic/coursemanagement/services/progressservice"
+	"github.com/ednesic/coursemanagement/services/promotionservice"
+	"github.com/ednesic/coursemanagement/services/reviewservice"
 	"github.com/ednesic/coursemanagement/storage"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -20,6 +35,8 @@ func main() {
 	var err error
 	e := echo.New()
 	e.Logger.SetLevel(log.DEBUG)
+	e.Validator = handlers.NewValidator()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
@@ -27,8 +44,11 @@ func main() {
 		e.Logger.SetLevel(log.INFO)
 	}
 
-	cache.GetInstance().Initialize(map[string]string{"server1": os.Getenv("REDIS_HOST")})
-	err = storage.GetInstance().Initialize(
+	cacheLayer, cacheOpts := cacheDriver(os.Getenv("CACHE_DRIVER"))
+	cacheLayer.Initialize(cacheOpts)
+	cache.SetInstance(cacheLayer)
+	dal := dataAccessLayer(os.Getenv("DB_DRIVER"))
+	err = dal.Initialize(
 		ctx,
 		os.Getenv("DB_HOST"),
 		os.Getenv("DB"),
@@ -37,9 +57,46 @@ func main() {
 	if err != nil {
 		e.Logger.Fatal("Could not resolve Data access layer: ", err)
 	}
+	storage.SetInstance(dal)
+	if err = courseservice.EnsureIndexes(ctx); err != nil {
+		e.Logger.Fatal("Could not create course indexes: ", err)
+	}
+	if n, err := courseservice.MigratePrices(context.Background()); err != nil {
+		e.Logger.Fatal("Could not migrate the course prices: ", err)
+	} else if n > 0 {
+		e.Logger.Infof("Migrated the prices of %d courses", n)
+	}
+	if err = categoryservice.EnsureIndexes(ctx); err != nil {
+		e.Logger.Fatal("Could not create category indexes: ", err)
+	}
+	if err = instructorservice.EnsureIndexes(ctx); err != nil {
+		e.Logger.Fatal("Could not create instructor indexes: ", err)
+	}
+	if err = enrollmentservice.EnsureIndexes(ctx); err != nil {
+		e.Logger.Fatal("Could not create enrollment indexes: ", err)
+	}
+	if err = progressservice.EnsureIndexes(ctx); err != nil {
+		e.Logger.Fatal("Could not create progress indexes: ", err)
+	}
+	if err = reviewservice.EnsureIndexes(ctx); err != nil {
+		e.Logger.Fatal("Could not create review indexes: ", err)
+	}
+	if err = promotionservice.EnsureIndexes(ctx); err != nil {
+		e.Logger.Fatal("Could not create promotion indexes: ", err)
+	}
+	if err = bundleservice.EnsureIndexes(ctx); err != nil {
+		e.Logger.Fatal("Could not create bundle indexes: ", err)
+	}
+	if err = orderservice.EnsureIndexes(ctx); err != nil {
+		e.Logger.Fatal("Could not create order indexes: ", err)
+	}
+	progressservice.SetSecret(certificateSecret(e.Logger))
+	exchangeRates(e.Logger)
+	paymentGateway(e.Logger)
 
 	e.Use(middleware.Recover())
 	e.Use(middleware.RequestID())
+	e.Use(actor.Middleware())
 	e.Use(middleware.BodyLimit("2M"))
 	e.Use(metrics.NewMetric())
 	e.Use(middleware.Logger())
@@ -47,11 +104,97 @@ func main() {
 	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
 
 	gCourse := e.Group("/courses")
-	gCourse.DELETE("/:name", handlers.DelCourse)
-	gCourse.GET("/:name", handlers.GetCourse)
+	gCourse.DELETE("/:id", handlers.DelCourse)
+	gCourse.GET("/:id", handlers.GetCourse)
 	gCourse.GET("", handlers.GetCourses)
+	gCourse.GET("/search", handlers.SearchCourses)
+	gCourse.GET("/trash", handlers.GetTrash)
 	gCourse.POST("", handlers.SetCourse)
-	gCourse.PUT("", handlers.PutCourse)
+	gCourse.PUT("/:id", handlers.PutCourse)
+	gCourse.PATCH("/:id", handlers.PatchCourse)
+	gCourse.POST("/:id/restore", handlers.RestoreCourse)
+	gCourse.GET("/:id/history", handlers.GetCourseHistory)
+	gCourse.GET("/:id/history/diff", handlers.GetCourseDiff)
+	gCourse.GET("/:id/revisions/:rev", handlers.GetCourseRevision)
+	gCourse.POST("/:id/revisions/:rev/restore", handlers.RevertCourse)
+	gCourse.POST("/:id/submit", handlers.SubmitCourse)
+	gCourse.POST("/:id/publish", handlers.PublishCourse)
+	gCourse.POST("/:id/unpublish", handlers.UnpublishCourse)
+	gCourse.POST("/:id/archive", handlers.ArchiveCourse)
+	gCourse.GET("/:id/modules", handlers.GetModules)
+	gCourse.POST("/:id/modules", handlers.SetModule)
+	gCourse.PUT("/:id/modules/order", handlers.ReorderModules)
+	gCourse.GET("/:id/modules/:module", handlers.GetModule)
+	gCourse.PUT("/:id/modules/:module", handlers.PutModule)
+	gCourse.DELETE("/:id/modules/:module", handlers.DelModule)
+	gCourse.POST("/:id/modules/:module/lessons", handlers.SetLesson)
+	gCourse.PUT("/:id/modules/:module/lessons/order", handlers.ReorderLessons)
+	gCourse.GET("/:id/modules/:module/lessons/:lesson", handlers.GetLesson)
+	gCourse.PUT("/:id/modules/:module/lessons/:lesson", handlers.PutLesson)
+	gCourse.DELETE("/:id/modules/:module/lessons/:lesson", handlers.DelLesson)
+	gCourse.POST("/:id/enrollments", handlers.EnrollCourse)
+	gCourse.GET("/:id/enrollments", handlers.GetCourseEnrollments)
+	gCourse.DELETE("/:id/enrollments/:student", handlers.CancelEnrollment)
+	gCourse.GET("/:id/reviews", handlers.GetReviews)
+	gCourse.POST("/:id/reviews", handlers.SetReview)
+	gCourse.PUT("/:id/reviews/:student", handlers.PutReview)
+	gCourse.DELETE("/:id/reviews/:student", handlers.DelReview)
+	gCourse.POST("/:id/reviews/:student/flag", handlers.FlagReview)
+	gCourse.PUT("/:id/reviews/:student/status", handlers.ModerateReview)
+	gCourse.GET("/:id/seats", handlers.GetCourseSeats)
+	gCourse.POST("/:id/quote", handlers.QuoteCourse)
+
+	gInstructor := e.Group("/instructors")
+	gInstructor.GET("", handlers.GetInstructors)
+	gInstructor.POST("", handlers.SetInstructor)
+	gInstructor.GET("/:id", handlers.GetInstructor)
+	gInstructor.PUT("/:id", handlers.PutInstructor)
+	gInstructor.DELETE("/:id", handlers.DelInstructor)
+	gInstructor.GET("/:id/courses", handlers.GetInstructorCourses)
+
+	gCategory := e.Group("/categories")
+	gCategory.GET("", handlers.GetCategories)
+	gCategory.POST("", handlers.SetCategory)
+	gCategory.GET("/:id", handlers.GetCategory)
+	gCategory.PUT("/:id", handlers.PutCategory)
+	gCategory.DELETE("/:id", handlers.DelCategory)
+	gCategory.GET("/:id/courses", handlers.GetCategoryCourses)
+
+	gCoupon := e.Group("/coupons")
+	gCoupon.GET("", handlers.GetCoupons)
+	gCoupon.POST("", handlers.SetCoupon)
+	gCoupon.GET("/:code", handlers.GetCoupon)
+	gCoupon.PUT("/:code", handlers.PutCoupon)
+	gCoupon.DELETE("/:code", handlers.DelCoupon)
+
+	gBundle := e.Group("/bundles")
+	gBundle.GET("", handlers.GetBundles)
+	gBundle.POST("", handlers.SetBundle)
+	gBundle.GET("/:id", handlers.GetBundle)
+	gBundle.PUT("/:id", handlers.PutBundle)
+	gBundle.DELETE("/:id", handlers.DelBundle)
+	gBundle.POST("/:id/enrollments", handlers.EnrollBundle)
+
+	gOrder := e.Group("/orders")
+	gOrder.POST("", handlers.CreateOrder)
+	gOrder.GET("/:id", handlers.GetOrder)
+	gOrder.POST("/:id/refunds", handlers.RefundOrder)
+	gOrder.GET("/:id/refunds", handlers.GetOrderRefunds)
+
+	gStudent := e.Group("/students")
+	gStudent.GET("/:id/enrollments", handlers.GetStudentEnrollments)
+	gStudent.GET("/:id/orders", handlers.GetStudentOrders)
+	gStudent.GET("/:id/courses/:course/progress", handlers.GetProgress)
+	gStudent.POST("/:id/courses/:course/lessons/:lesson/progress", handlers.RecordProgress)
+
+	gCertificate := e.Group("/certificates")
+	gCertificate.GET("/:id", handlers.GetCertificate)
+	gCertificate.POST("/verify", handlers.VerifyCertificate)
+
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go scheduler.Every(jobs, time.Hour, purgeTrash(e.Logger, trashRetention()))
+	go scheduler.Every(jobs, time.Minute, runScheduledTransitions(e.Logger))
 
 	go func() {
 		if err := e.Start(":" + os.Getenv("PORT")); err != nil {
@@ -59,7 +202,7 @@ func main() {
 		}
 	}()
 
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
@@ -70,3 +213,102 @@ func main() {
 	cache.GetInstance().Disconnect()
 	storage.GetInstance().Disconnect()
 }
+
+//dataAccessLayer returns the data access layer of the driver, mongo unless it is "memory"
+func dataAccessLayer(driver string) storage.DataAccessLayer {
+	if driver == "memory" {
+		return storage.NewMemory()
+	}
+	return storage.GetInstance()
+}
+
+//cacheDriver returns the cache of the driver and its options. The driver is "memory", "tiered" (memory
+//in front of redis, keeping entries locally for CACHE_LOCAL_TTL) or redis by default.
+func cacheDriver(driver string) (cache.Cache, map[string]string) {
+	size, _ := strconv.Atoi(os.Getenv("CACHE_SIZE"))
+	hosts := map[string]string{"server1": os.Getenv("REDIS_HOST")}
+	switch driver {
+	case "memory":
+		return cache.NewLRU(), map[string]string{"size": os.Getenv("CACHE_SIZE")}
+	case "tiered":
+		ttl, err := time.ParseDuration(os.Getenv("CACHE_LOCAL_TTL"))
+		if err != nil || ttl <= 0 {
+			ttl = 5 * time.Second
+		}
+		return cache.NewTiered(size, ttl), hosts
+	}
+	return cache.GetInstance(), hosts
+}
+
+//trashRetention is how long deleted courses stay in the trash, TRASH_RETENTION_DAYS or 30 days
+func trashRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days < 1 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+//certificateSecret is the key signing the completion certificates, CERTIFICATE_SECRET or a random one which does
+//not verify the certificates issued before a restart
+func certificateSecret(logger echo.Logger) []byte {
+	if secret := os.Getenv("CERTIFICATE_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	logger.Warn("CERTIFICATE_SECRET is not set, certificates will not verify after a restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		logger.Fatal("Could not generate the certificate secret: ", err)
+	}
+	return secret
+}
+
+//exchangeRates initializes the static exchange rates of the json file of RATES_FILE. Without it courses are only
+//priced in the currencies of their price points.
+func exchangeRates(logger echo.Logger) {
+	file := os.Getenv("RATES_FILE")
+	if file == "" {
+		logger.Warn("RATES_FILE is not set, prices will not be converted to other currencies")
+		return
+	}
+	if err := money.NewStatic().Initialize(map[string]string{"file": file}); err != nil {
+		logger.Fatal("Could not read the exchange rates: ", err)
+	}
+}
+
+//paymentGateway initializes the fake payment gateway, declining the comma separated sources of
+//FAKE_DECLINED_SOURCES too. No money is charged until a real gateway is initialized in its place.
+func paymentGateway(logger echo.Logger) {
+	logger.Warn("Payments are taken by the fake gateway, no money is charged")
+	if err := payment.NewFake().Initialize(map[string]string{"declined": os.Getenv("FAKE_DECLINED_SOURCES")}); err != nil {
+		logger.Fatal("Could not initialize the payment gateway: ", err)
+	}
+}
+
+//purgeTrash is the job removing for good the courses in the trash for longer than the retention
+func purgeTrash(logger echo.Logger, retention time.Duration) scheduler.Job {
+	return func(ctx context.Context) {
+		n, err := courseservice.GetInstance().Purge(actor.NewContext(ctx, actor.System), time.Now().Add(-retention))
+		if err != nil {
+			logger.Error("Could not purge the trash: ", err)
+			return
+		}
+		if n > 0 {
+			logger.Infof("Purged %d courses from the trash", n)
+		}
+	}
+}
+
+//runScheduledTransitions is the job publishing and unpublishing the courses scheduled to
+func runScheduledTransitions(logger echo.Logger) scheduler.Job {
+	return func(ctx context.Context) {
+		n, err := courseservice.GetInstance().RunScheduled(actor.NewContext(ctx, actor.System), time.Now())
+		if err != nil {
+			logger.Error("Could not run the scheduled course transitions: ", err)
+			return
+		}
+		if n > 0 {
+			logger.Infof("Moved %d courses to their scheduled status", n)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	//CacheLoadLoaded is a value loaded from the source on a cache miss
+	CacheLoadLoaded = "loaded"
+	//CacheLoadCollapsed is a cache miss served by the load another request was already doing
+	CacheLoadCollapsed = "collapsed"
+	//CacheLoadRefreshed is a value loaded again shortly before it expires
+	CacheLoadRefreshed = "refreshed"
+)
+
+var cacheLoads = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "cache",
+		Name:      "loads_total",
+		Help:      "Cache loads by outcome: loaded, collapsed in a concurrent load or refreshed before expiring.",
+	},
+	[]string{"outcome"},
+)
+
+func init() {
+	prometheus.MustRegister(cacheLoads)
+}
+
+//CacheLoad counts a cache load by its outcome
+func CacheLoad(outcome string) {
+	cacheLoads.WithLabelValues(outcome).Inc()
+}
@@ -0,0 +1,79 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ednesic/coursemanagement/types"
+)
+
+var (
+	//ErrUnknownCurrency is returned for codes which are not ISO 4217 currencies
+	ErrUnknownCurrency = errors.New("unknown currency")
+	//ErrInvalidAmount is returned for amounts which are not decimals or have more decimal places than their currency
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+//Parse reads the decimal amount, like "19.90", in the minor units of the currency. It is exact: amounts with more
+//decimal places than the currency has are refused instead of rounded.
+func Parse(amount string, currency string) (types.Money, error) {
+	if !IsCurrency(currency) {
+		return types.Money{}, ErrUnknownCurrency
+	}
+	if strings.ContainsAny(amount, "eE/") {
+		return types.Money{}, ErrInvalidAmount
+	}
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return types.Money{}, ErrInvalidAmount
+	}
+	r.Mul(r, scale(Exponent(currency)))
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return types.Money{}, ErrInvalidAmount
+	}
+	return types.Money{Amount: r.Num().Int64(), Currency: currency}, nil
+}
+
+//Convert changes the money to the currency at the rate of the provider, rounding half away from zero to
+//the minor units of the currency
+func Convert(m types.Money, currency string) (types.Money, error) {
+	if !IsCurrency(currency) {
+		return types.Money{}, ErrUnknownCurrency
+	}
+	if m.Currency == currency {
+		return m, nil
+	}
+	rate, err := GetInstance().Rate(m.Currency, currency)
+	if err != nil {
+		return types.Money{}, err
+	}
+	r := new(big.Rat).SetInt64(m.Amount)
+	r.Mul(r, rate)
+	r.Mul(r, new(big.Rat).Quo(scale(Exponent(currency)), scale(Exponent(m.Currency))))
+	return types.Money{Amount: round(r), Currency: currency}, nil
+}
+
+//Resolve is the price in the currency: the price point in it when there is one, otherwise the base price converted
+func Resolve(base types.Money, points []types.Money, currency string) (types.Money, error) {
+	for _, p := range points {
+		if p.Currency == currency {
+			return p, nil
+		}
+	}
+	return Convert(base, currency)
+}
+
+//scale is 10 to the exponent
+func scale(exp int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+}
+
+//round rounds half away from zero
+func round(r *big.Rat) int64 {
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if m.Mul(m.Abs(m), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(r.Sign())))
+	}
+	return q.Int64()
+}
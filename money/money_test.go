@@ -0,0 +1,111 @@
+package money
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     types.Money
+		err      error
+	}{
+		{"19.90", "USD", types.Money{Amount: 1990, Currency: "USD"}, nil},
+		{"0.1", "EUR", types.Money{Amount: 10, Currency: "EUR"}, nil},
+		{"1500", "JPY", types.Money{Amount: 1500, Currency: "JPY"}, nil},
+		{"1.234", "KWD", types.Money{Amount: 1234, Currency: "KWD"}, nil},
+		{"1.5", "JPY", types.Money{}, ErrInvalidAmount},
+		{"19.999", "USD", types.Money{}, ErrInvalidAmount},
+		{"1e3", "USD", types.Money{}, ErrInvalidAmount},
+		{"ten", "USD", types.Money{}, ErrInvalidAmount},
+		{"10", "usd", types.Money{}, ErrUnknownCurrency},
+		{"10", "XYZ", types.Money{}, ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			m, err := Parse(tt.amount, tt.currency)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.want, m)
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	withRates(t, `{"base": "USD", "rates": {"EUR": 0.92, "JPY": 149.5, "KWD": "0.307", "ISK": 137.5}}`)
+	tests := []struct {
+		name     string
+		from     types.Money
+		currency string
+		want     types.Money
+		err      error
+	}{
+		{"Same currency", types.Money{Amount: 1990, Currency: "USD"}, "USD", types.Money{Amount: 1990, Currency: "USD"}, nil},
+		{"From the base", types.Money{Amount: 1990, Currency: "USD"}, "EUR", types.Money{Amount: 1831, Currency: "EUR"}, nil},
+		{"To the base", types.Money{Amount: 1831, Currency: "EUR"}, "USD", types.Money{Amount: 1990, Currency: "USD"}, nil},
+		{"Fewer decimal places", types.Money{Amount: 1990, Currency: "USD"}, "JPY", types.Money{Amount: 2975, Currency: "JPY"}, nil},
+		{"More decimal places", types.Money{Amount: 1000, Currency: "USD"}, "KWD", types.Money{Amount: 3070, Currency: "KWD"}, nil},
+		{"Cross rate", types.Money{Amount: 920, Currency: "EUR"}, "JPY", types.Money{Amount: 1495, Currency: "JPY"}, nil},
+		{"Rounds half away from zero", types.Money{Amount: 4, Currency: "USD"}, "ISK", types.Money{Amount: 6, Currency: "ISK"}, nil},
+		{"No rate", types.Money{Amount: 1990, Currency: "USD"}, "BRL", types.Money{}, ErrNoRate},
+		{"Unknown currency", types.Money{Amount: 1990, Currency: "USD"}, "XYZ", types.Money{}, ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Convert(tt.from, tt.currency)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.want, m)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	withRates(t, `{"base": "USD", "rates": {"EUR": 0.92}}`)
+	base := types.Money{Amount: 1990, Currency: "USD"}
+	points := []types.Money{{Amount: 1790, Currency: "EUR"}}
+
+	m, err := Resolve(base, points, "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, types.Money{Amount: 1790, Currency: "EUR"}, m)
+	m, err = Resolve(base, nil, "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, types.Money{Amount: 1831, Currency: "EUR"}, m)
+	m, err = Resolve(base, points, "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, base, m)
+}
+
+func TestStaticInitialize_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"Not json", `rates`},
+		{"Unknown base", `{"base": "XYZ", "rates": {}}`},
+		{"Unknown currency", `{"base": "USD", "rates": {"XYZ": 1}}`},
+		{"Zero rate", `{"base": "USD", "rates": {"EUR": 0}}`},
+		{"Invalid rate", `{"base": "USD", "rates": {"EUR": "a lot"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, NewStatic().Initialize(map[string]string{"file": ratesFileOf(t, tt.file)}))
+		})
+	}
+	assert.Error(t, NewStatic().Initialize(map[string]string{"file": "missing.json"}))
+}
+
+//withRates makes a static provider of the rates the instance
+func withRates(t *testing.T, rates string) {
+	assert.NoError(t, NewStatic().Initialize(map[string]string{"file": ratesFileOf(t, rates)}))
+}
+
+func ratesFileOf(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
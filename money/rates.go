@@ -0,0 +1,112 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"sync"
+)
+
+//ErrNoRate is returned when the provider has no rate between the currencies
+var ErrNoRate = errors.New("no exchange rate")
+
+var (
+	instance Rates
+	once     sync.Once
+)
+
+//Rates is an interface for the providers of exchange rates
+type Rates interface {
+	//Rate is how many units of the currency to one unit of the currency from
+	Rate(from, to string) (*big.Rat, error)
+	Initialize(opts map[string]string) error
+}
+
+//GetInstance returns the exchange rates provider, a static one without rates until another is initialized
+func GetInstance() Rates {
+	once.Do(func() {
+		if instance == nil {
+			instance = &staticImpl{}
+		}
+	})
+	return instance
+}
+
+//staticImpl has the rates of a base currency to the others, working out the rates between the others through it
+type staticImpl struct {
+	mu    sync.RWMutex
+	base  string
+	rates map[string]*big.Rat
+}
+
+//ratesFile is the format of the files of the static provider, like {"base": "USD", "rates": {"EUR": 0.92}}
+type ratesFile struct {
+	Base  string                 `json:"base"`
+	Rates map[string]json.Number `json:"rates"`
+}
+
+//NewStatic returns a provider of fixed rates, for offline use. It becomes the instance returned by GetInstance
+//once initialized.
+func NewStatic() Rates {
+	return &staticImpl{}
+}
+
+//Initialize reads the rates from the json file of the "file" option. Rates are read as written, so they are exact.
+func (s *staticImpl) Initialize(opts map[string]string) error {
+	f, err := os.Open(opts["file"])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var file ratesFile
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	if err = dec.Decode(&file); err != nil {
+		return err
+	}
+	if !IsCurrency(file.Base) {
+		return ErrUnknownCurrency
+	}
+	rates := map[string]*big.Rat{}
+	for currency, n := range file.Rates {
+		if !IsCurrency(currency) {
+			return ErrUnknownCurrency
+		}
+		r, ok := new(big.Rat).SetString(n.String())
+		if !ok || r.Sign() <= 0 {
+			return errors.New("invalid exchange rate of " + currency)
+		}
+		rates[currency] = r
+	}
+
+	s.mu.Lock()
+	s.base, s.rates = file.Base, rates
+	s.mu.Unlock()
+	instance = s
+	return nil
+}
+
+//Rate divides the rate of the base to the currency to by the one of the base to the currency from
+func (s *staticImpl) Rate(from, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, okFrom := s.rate(from)
+	b, okTo := s.rate(to)
+	if !okFrom || !okTo {
+		return nil, ErrNoRate
+	}
+	return new(big.Rat).Quo(b, a), nil
+}
+
+func (s *staticImpl) rate(currency string) (*big.Rat, bool) {
+	if currency == s.base && s.base != "" {
+		return big.NewRat(1, 1), true
+	}
+	r, ok := s.rates[currency]
+	return r, ok
+}
@@ -0,0 +1,93 @@
+package payment
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+//DeclinedSource is the payment source the fake gateway always declines
+const DeclinedSource = "tok_declined"
+
+//fakeImpl takes the payments in memory without moving any money
+type fakeImpl struct {
+	mu       sync.Mutex
+	declined map[string]bool
+	charges  map[string]*fakeCharge
+	results  map[string]fakeResult
+}
+
+type fakeCharge struct {
+	amount   types.Money
+	refunded int64
+}
+
+//fakeResult is the answer to an idempotency key
+type fakeResult struct {
+	id  string
+	err error
+}
+
+//NewFake returns a gateway for local use and tests, accepting every source but DeclinedSource. It becomes the
+//instance returned by GetInstance once initialized.
+func NewFake() PaymentGateway {
+	return &fakeImpl{
+		declined: map[string]bool{DeclinedSource: true},
+		charges:  map[string]*fakeCharge{},
+		results:  map[string]fakeResult{},
+	}
+}
+
+//Initialize forgets the payments taken and declines the sources of the comma separated "declined" option too
+func (f *fakeImpl) Initialize(opts map[string]string) error {
+	f.mu.Lock()
+	f.declined = map[string]bool{DeclinedSource: true}
+	for _, source := range strings.Split(opts["declined"], ",") {
+		if source = strings.TrimSpace(source); source != "" {
+			f.declined[source] = true
+		}
+	}
+	f.charges, f.results = map[string]*fakeCharge{}, map[string]fakeResult{}
+	f.mu.Unlock()
+	instance = f
+	return nil
+}
+
+//Charge declines the sources of the declined option and the empty one
+func (f *fakeImpl) Charge(ctx context.Context, key, source string, amount types.Money) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r, ok := f.results["charge:"+key]; ok {
+		return r.id, r.err
+	}
+	r := fakeResult{err: ErrDeclined}
+	if source != "" && !f.declined[source] {
+		r = fakeResult{id: "ch_" + storage.NewID()}
+		f.charges[r.id] = &fakeCharge{amount: amount}
+	}
+	f.results["charge:"+key] = r
+	return r.id, r.err
+}
+
+//Refund gives back at most what is left of the payment, in its currency
+func (f *fakeImpl) Refund(ctx context.Context, key, payment string, amount types.Money) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r, ok := f.results["refund:"+key]; ok {
+		return r.id, r.err
+	}
+	ch, ok := f.charges[payment]
+	if !ok {
+		return "", ErrUnknownPayment
+	}
+	if amount.Currency != ch.amount.Currency || amount.Amount > ch.amount.Amount-ch.refunded {
+		return "", ErrRefundExceeded
+	}
+	ch.refunded += amount.Amount
+	r := fakeResult{id: "re_" + storage.NewID()}
+	f.results["refund:"+key] = r
+	return r.id, nil
+}
@@ -0,0 +1,53 @@
+package payment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake_Charge(t *testing.T) {
+	g := NewFake()
+	assert.NoError(t, g.Initialize(map[string]string{"declined": "tok_expired, tok_stolen"}))
+	assert.Equal(t, g, GetInstance())
+	ctx := context.Background()
+	usd := types.Money{Amount: 1000, Currency: "USD"}
+
+	id, err := g.Charge(ctx, "order1", "tok_visa", usd)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+	again, err := g.Charge(ctx, "order1", "tok_stolen", usd)
+	assert.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	for _, source := range []string{"", DeclinedSource, "tok_expired", "tok_stolen"} {
+		_, err = g.Charge(ctx, "order-"+source, source, usd)
+		assert.Equal(t, ErrDeclined, err, source)
+	}
+	_, err = g.Charge(ctx, "order-tok_stolen", "tok_visa", usd)
+	assert.Equal(t, ErrDeclined, err)
+}
+
+func TestFake_Refund(t *testing.T) {
+	g := NewFake()
+	ctx := context.Background()
+	payment, err := g.Charge(ctx, "order1", "tok_visa", types.Money{Amount: 1000, Currency: "USD"})
+	assert.NoError(t, err)
+
+	id, err := g.Refund(ctx, "refund1", payment, types.Money{Amount: 600, Currency: "USD"})
+	assert.NoError(t, err)
+	again, err := g.Refund(ctx, "refund1", payment, types.Money{Amount: 600, Currency: "USD"})
+	assert.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	_, err = g.Refund(ctx, "refund2", payment, types.Money{Amount: 600, Currency: "USD"})
+	assert.Equal(t, ErrRefundExceeded, err)
+	_, err = g.Refund(ctx, "refund2", payment, types.Money{Amount: 400, Currency: "EUR"})
+	assert.Equal(t, ErrRefundExceeded, err)
+	_, err = g.Refund(ctx, "refund2", payment, types.Money{Amount: 400, Currency: "USD"})
+	assert.NoError(t, err)
+	_, err = g.Refund(ctx, "refund3", "ch_missing", types.Money{Amount: 1, Currency: "USD"})
+	assert.Equal(t, ErrUnknownPayment, err)
+}
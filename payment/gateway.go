@@ -0,0 +1,41 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ednesic/coursemanagement/types"
+)
+
+var (
+	instance PaymentGateway
+	once     sync.Once
+
+	//ErrDeclined is returned when the payment source is refused
+	ErrDeclined = errors.New("payment declined")
+	//ErrUnknownPayment is returned for refunds of payments the gateway did not take
+	ErrUnknownPayment = errors.New("unknown payment")
+	//ErrRefundExceeded is returned for refunds above what is left of the payment
+	ErrRefundExceeded = errors.New("refund exceeds the payment")
+)
+
+//PaymentGateway is an interface for the providers taking the payments. Charges and refunds are made once for each
+//idempotency key, retrying one answers the first result.
+type PaymentGateway interface {
+	//Charge takes the amount from the payment source, answering the id of the payment
+	Charge(ctx context.Context, key, source string, amount types.Money) (string, error)
+	//Refund gives back the amount of the payment of the id, answering the id of the refund
+	Refund(ctx context.Context, key, payment string, amount types.Money) (string, error)
+	Initialize(opts map[string]string) error
+}
+
+//GetInstance returns the payment gateway, the fake one until another is initialized
+func GetInstance() PaymentGateway {
+	once.Do(func() {
+		if instance == nil {
+			instance = NewFake()
+		}
+	})
+	return instance
+}
@@ -0,0 +1,24 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+//Job is a task run periodically by Every
+type Job func(ctx context.Context)
+
+//Every runs the job every interval, the first time after one interval, until the context is done.
+//Runs do not overlap: a run taking longer than the interval delays the next one.
+func Every(ctx context.Context, interval time.Duration, job Job) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			job(ctx)
+		}
+	}
+}
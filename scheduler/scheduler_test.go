@@ -0,0 +1,31 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvery_RunsUntilDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs int32
+	done := make(chan struct{})
+	go func() {
+		Every(ctx, 5*time.Millisecond, func(context.Context) {
+			if atomic.AddInt32(&runs, 1) == 3 {
+				cancel()
+			}
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the job was not run until the context was done")
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+}
@@ -0,0 +1,215 @@
+package bundleservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const coll = "bundle"
+
+var (
+	instance BundleService
+	once     sync.Once
+
+	//ErrUnknownCourse for bundles of courses that do not exist
+	ErrUnknownCourse = errors.New("unknown course")
+	//ErrTooFewCourses for bundles of less than two distinct courses
+	ErrTooFewCourses = errors.New("a bundle has at least two courses")
+	//ErrNotActive for enrollments in bundles which are not sold
+	ErrNotActive = errors.New("bundle is not active")
+	//ErrInActiveBundle for deletions of courses sold in an active bundle
+	ErrInActiveBundle = errors.New("course is in an active bundle")
+)
+
+//BundleService is an interface for bundle service
+type BundleService interface {
+	Create(context.Context, types.Bundle) (types.Bundle, error)
+	Update(context.Context, types.Bundle) (types.Bundle, error)
+	Delete(context.Context, string) error
+	FindAll() ([]types.Bundle, error)
+	FindOne(string) (types.Bundle, error)
+	FindByCourse(string) ([]types.Bundle, error)
+	Enroll(context.Context, string, string) ([]types.Enrollment, error)
+}
+
+type bundleImpl struct{}
+
+func init() {
+	courseservice.AddHook(keepBundled)
+}
+
+//GetInstance to get service instance
+func GetInstance() BundleService {
+	once.Do(func() {
+		if instance == nil {
+			instance = &bundleImpl{}
+		}
+	})
+	return instance
+}
+
+//EnsureIndexes creates the indexes the bundle service relies on, the one finding the bundles of a course
+func EnsureIndexes(ctx context.Context) error {
+	return storage.GetInstance().EnsureIndex(ctx, coll, storage.Index{Keys: []string{"courses", "active"}})
+}
+
+//FindOne finds the bundle by its id
+func (s bundleImpl) FindOne(id string) (b types.Bundle, err error) {
+	err = cache.GetOrLoadTagged(coll+id, []string{coll}, &b, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": id}, v)
+	})
+	return b, err
+}
+
+//FindAll lists the bundles by name
+func (s bundleImpl) FindAll() (bs []types.Bundle, err error) {
+	err = cache.GetOrLoadTagged(coll+"all", []string{coll}, &bs, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().Find(ctx, coll, map[string]interface{}{}, storage.FindOptions{Sort: []string{"name"}}, v)
+	})
+	return bs, err
+}
+
+//FindByCourse lists by name the active bundles including the course of the id
+func (s bundleImpl) FindByCourse(courseID string) (bs []types.Bundle, err error) {
+	err = cache.GetOrLoadTagged(coll+"course"+courseID, []string{coll}, &bs, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		query := map[string]interface{}{"courses": courseID, "active": true}
+		return storage.GetInstance().Find(ctx, coll, query, storage.FindOptions{Sort: []string{"name"}}, v)
+	})
+	return bs, err
+}
+
+//Create generates the id of the bundle before storing it. Its courses are given by id or slug and stored by id.
+func (s bundleImpl) Create(ctx context.Context, b types.Bundle) (types.Bundle, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if err := normalize(&b); err != nil {
+		return b, err
+	}
+	b.ID = storage.NewID()
+	err := storage.GetInstance().Insert(ctx, coll, b)
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return b, err
+}
+
+//Update replaces the bundle with the same id
+func (s bundleImpl) Update(ctx context.Context, b types.Bundle) (types.Bundle, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if err := normalize(&b); err != nil {
+		return b, err
+	}
+	err := storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": b.ID}, map[string]interface{}{"$set": &b})
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return b, err
+}
+
+//Delete removes the bundle of the id, the enrollments made through it are kept
+func (s bundleImpl) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	err := storage.GetInstance().Remove(ctx, coll, map[string]interface{}{"_id": id})
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return err
+}
+
+//Enroll enrolls the student in every course of the free active bundle of the id, whatever the price of the courses.
+//The courses the student is already enrolled in are skipped. Each course is enrolled in on its own, so the
+//enrollments made before a failure are kept and enrolling again completes the bundle. Priced bundles are not enrolled
+//in directly.
+func (s bundleImpl) Enroll(ctx context.Context, id, student string) ([]types.Enrollment, error) {
+	b, err := s.FindOne(id)
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return nil, err
+	}
+	if !b.Active {
+		return nil, ErrNotActive
+	}
+	if b.Price.Amount > 0 {
+		return nil, enrollmentservice.ErrPaymentRequired
+	}
+	es := []types.Enrollment{}
+	for _, courseID := range b.Courses {
+		e, err := enrollmentservice.GetInstance().Admit(ctx, courseID, student)
+		if err == enrollmentservice.ErrAlreadyEnrolled {
+			continue
+		}
+		if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+			return es, err
+		}
+		es = append(es, e)
+	}
+	return es, nil
+}
+
+//normalize resolves the courses of the bundle to their ids, leaving out the repeated ones, and puts its price in the
+//default currency when it has none
+func normalize(b *types.Bundle) error {
+	var ids []string
+	for _, ref := range b.Courses {
+		c, err := courseservice.GetInstance().FindOne(ref)
+		if err == storage.ErrNotFound {
+			return ErrUnknownCourse
+		}
+		if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+			return err
+		}
+		if !contains(ids, c.ID) {
+			ids = append(ids, c.ID)
+		}
+	}
+	if len(ids) < 2 {
+		return ErrTooFewCourses
+	}
+	b.Courses = ids
+
+	if b.Price.Currency = strings.ToUpper(b.Price.Currency); b.Price.Currency == "" {
+		b.Price.Currency = types.DefaultCurrency
+	}
+	if !money.IsCurrency(b.Price.Currency) {
+		return money.ErrUnknownCurrency
+	}
+	return nil
+}
+
+//keepBundled is the courseservice.Hook failing with ErrInActiveBundle the deletions of courses sold in an active bundle
+func keepBundled(ctx context.Context, action string, before, after *types.Course) ([]string, error) {
+	if action != courseservice.ActionDelete {
+		return nil, nil
+	}
+	n, err := storage.GetInstance().Count(ctx, coll, map[string]interface{}{"courses": before.ID, "active": true})
+	if err == nil && n > 0 {
+		err = ErrInActiveBundle
+	}
+	return nil, err
+}
+
+func contains(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
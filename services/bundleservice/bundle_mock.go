@@ -0,0 +1,60 @@
+package bundleservice
+
+import (
+	"context"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/mock"
+)
+
+//Mock is a mocked structure for bundle service
+type Mock struct {
+	mock.Mock
+}
+
+//InitMock to initialize mock befores tests
+func (s *Mock) InitMock() {
+	instance = s
+}
+
+//Create is a mock for bundle service create
+func (s *Mock) Create(ctx context.Context, b types.Bundle) (types.Bundle, error) {
+	args := s.Called(ctx, b)
+	return args.Get(0).(types.Bundle), args.Error(1)
+}
+
+//Update is a mock for bundle service update
+func (s *Mock) Update(ctx context.Context, b types.Bundle) (types.Bundle, error) {
+	args := s.Called(ctx, b)
+	return args.Get(0).(types.Bundle), args.Error(1)
+}
+
+//Delete is a mock for bundle service delete
+func (s *Mock) Delete(ctx context.Context, id string) error {
+	args := s.Called(ctx, id)
+	return args.Error(0)
+}
+
+//FindAll is a mock for bundle service findAll
+func (s *Mock) FindAll() ([]types.Bundle, error) {
+	args := s.Called()
+	return args.Get(0).([]types.Bundle), args.Error(1)
+}
+
+//FindOne is a mock for bundle service findOne
+func (s *Mock) FindOne(id string) (types.Bundle, error) {
+	args := s.Called(id)
+	return args.Get(0).(types.Bundle), args.Error(1)
+}
+
+//FindByCourse is a mock for bundle service findByCourse
+func (s *Mock) FindByCourse(courseID string) ([]types.Bundle, error) {
+	args := s.Called(courseID)
+	return args.Get(0).([]types.Bundle), args.Error(1)
+}
+
+//Enroll is a mock for bundle service enroll
+func (s *Mock) Enroll(ctx context.Context, id, student string) ([]types.Enrollment, error) {
+	args := s.Called(ctx, id, student)
+	return args.Get(0).([]types.Enrollment), args.Error(1)
+}
@@ -0,0 +1,127 @@
+package bundleservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemoryBundles(t *testing.T) BundleService {
+	testutil.Memory(t, courseservice.EnsureIndexes, enrollmentservice.EnsureIndexes, EnsureIndexes)
+	return bundleImpl{}
+}
+
+func TestBundle_CRUD(t *testing.T) {
+	s := newMemoryBundles(t)
+	ctx := context.Background()
+	goCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go"})
+	sqlCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "SQL"})
+
+	b, err := s.Create(ctx, types.Bundle{Name: "Backend", Courses: []string{"go", sqlCourse.ID, goCourse.ID}, Price: types.Money{Amount: 2990, Currency: "eur"}})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, b.ID)
+	assert.Equal(t, []string{goCourse.ID, sqlCourse.ID}, b.Courses)
+	assert.Equal(t, types.Money{Amount: 2990, Currency: "EUR"}, b.Price)
+
+	_, err = s.Create(ctx, types.Bundle{Name: "Unknown", Courses: []string{"go", "missing"}})
+	assert.Equal(t, ErrUnknownCourse, err)
+	_, err = s.Create(ctx, types.Bundle{Name: "Repeated", Courses: []string{"go", goCourse.ID}})
+	assert.Equal(t, ErrTooFewCourses, err)
+	_, err = s.Create(ctx, types.Bundle{Name: "Currency", Courses: []string{"go", "sql"}, Price: types.Money{Currency: "XYZ"}})
+	assert.Equal(t, money.ErrUnknownCurrency, err)
+
+	bs, err := s.FindByCourse(goCourse.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, bs)
+
+	b.Active = true
+	b.Price = types.Money{Amount: 2500}
+	_, err = s.Update(ctx, b)
+	assert.NoError(t, err)
+	found, err := s.FindOne(b.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Money{Amount: 2500, Currency: "USD"}, found.Price)
+	bs, err = s.FindByCourse(sqlCourse.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Bundle{found}, bs)
+	_, err = s.Update(ctx, types.Bundle{ID: "missing", Name: "Missing", Courses: []string{"go", "sql"}})
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	bs, err = s.FindAll()
+	assert.NoError(t, err)
+	assert.Len(t, bs, 1)
+	assert.NoError(t, s.Delete(ctx, b.ID))
+	_, err = s.FindOne(b.ID)
+	assert.Equal(t, storage.ErrNotFound, err)
+	bs, err = s.FindByCourse(sqlCourse.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, bs)
+	assert.Equal(t, storage.ErrNotFound, s.Delete(ctx, b.ID))
+}
+
+func TestBundle_Enroll(t *testing.T) {
+	s := newMemoryBundles(t)
+	ctx := context.Background()
+	goCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go"})
+	sqlCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "SQL"})
+	b, err := s.Create(ctx, types.Bundle{Name: "Backend", Courses: []string{goCourse.ID, sqlCourse.ID}})
+	assert.NoError(t, err)
+
+	_, err = s.Enroll(ctx, b.ID, "ana")
+	assert.Equal(t, ErrNotActive, err)
+	_, err = s.Enroll(ctx, "missing", "ana")
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	b.Active = true
+	_, err = s.Update(ctx, b)
+	assert.NoError(t, err)
+	_, err = enrollmentservice.GetInstance().Enroll(ctx, sqlCourse.ID, "ana")
+	assert.NoError(t, err)
+
+	es, err := s.Enroll(ctx, b.ID, "ana")
+	assert.NoError(t, err)
+	assert.Len(t, es, 1)
+	assert.Equal(t, goCourse.ID, es[0].CourseID)
+	es, err = enrollmentservice.GetInstance().FindByStudent("ana")
+	assert.NoError(t, err)
+	assert.Len(t, es, 2)
+
+	es, err = s.Enroll(ctx, b.ID, "ana")
+	assert.NoError(t, err)
+	assert.Empty(t, es)
+
+	b.Price = types.Money{Amount: 1500, Currency: "USD"}
+	_, err = s.Update(ctx, b)
+	assert.NoError(t, err)
+	_, err = s.Enroll(ctx, b.ID, "bob")
+	assert.Equal(t, enrollmentservice.ErrPaymentRequired, err)
+}
+
+func TestBundle_KeepsCourses(t *testing.T) {
+	s := newMemoryBundles(t)
+	ctx := context.Background()
+	goCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go"})
+	sqlCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "SQL"})
+	b, err := s.Create(ctx, types.Bundle{Name: "Backend", Courses: []string{goCourse.ID, sqlCourse.ID}, Active: true})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrInActiveBundle, courseservice.GetInstance().Delete(ctx, goCourse.ID, 0))
+	c, err := courseservice.GetInstance().FindOne(goCourse.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, goCourse.Version, c.Version)
+	h, err := courseservice.GetInstance().History(goCourse.ID)
+	assert.NoError(t, err)
+	assert.NotEqual(t, courseservice.ActionDelete, h[len(h)-1].Action)
+
+	b.Active = false
+	_, err = s.Update(ctx, b)
+	assert.NoError(t, err)
+	assert.NoError(t, courseservice.GetInstance().Delete(ctx, goCourse.ID, 0))
+}
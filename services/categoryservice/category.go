@@ -0,0 +1,214 @@
+package categoryservice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const coll = "category"
+
+var (
+	instance CategoryService
+	once     sync.Once
+
+	//ErrUnknownParent for categories under a category that does not exist
+	ErrUnknownParent = errors.New("unknown parent category")
+	//ErrInvalidParent for categories moved under themselves or one of their descendants
+	ErrInvalidParent = errors.New("category moved under itself")
+	//ErrHasChildren for deletions of categories other categories are under
+	ErrHasChildren = errors.New("category has subcategories")
+)
+
+//CategoryService is an interface for category service
+type CategoryService interface {
+	Create(context.Context, types.Category) (types.Category, error)
+	Update(context.Context, types.Category) (types.Category, error)
+	Delete(context.Context, string) error
+	FindAll() ([]types.Category, error)
+	FindOne(string) (types.Category, error)
+}
+
+type categoryImpl struct{}
+
+//GetInstance to get service instance
+func GetInstance() CategoryService {
+	once.Do(func() {
+		if instance == nil {
+			instance = &categoryImpl{}
+		}
+	})
+	return instance
+}
+
+//EnsureIndexes creates the indexes the category service relies on, the ones finding the descendants of a category
+func EnsureIndexes(ctx context.Context) error {
+	for _, idx := range []storage.Index{{Keys: []string{"parent"}}, {Keys: []string{"path"}}} {
+		if err := storage.GetInstance().EnsureIndex(ctx, coll, idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//FindOne finds the category by its id. Every category is cached with the coll tag, as the paths of the descendants
+//of a category change with it.
+func (s categoryImpl) FindOne(id string) (cat types.Category, err error) {
+	err = cache.GetOrLoadTagged(coll+id, []string{coll}, &cat, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": id}, v)
+	})
+	return cat, err
+}
+
+//FindAll lists the categories of every level by name, the tree is the one of their parents
+func (s categoryImpl) FindAll() (cats []types.Category, err error) {
+	err = cache.GetOrLoadTagged(coll+"all", []string{coll}, &cats, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().Find(ctx, coll, map[string]interface{}{}, storage.FindOptions{Sort: []string{"name"}}, v)
+	})
+	return cats, err
+}
+
+//Create generates the id of the category before storing it under its parent, at the root when it has none
+func (s categoryImpl) Create(ctx context.Context, cat types.Category) (types.Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	cat.ID = storage.NewID()
+	path, err := pathUnder(ctx, cat)
+	if err != nil {
+		return cat, err
+	}
+	cat.Path = path
+	err = storage.GetInstance().Insert(ctx, coll, cat)
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return cat, err
+}
+
+//Update renames the category with the same id and moves it, with its descendants, under its parent
+func (s categoryImpl) Update(ctx context.Context, cat types.Category) (types.Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	var old types.Category
+	err := storage.GetInstance().WithTransaction(ctx, func(ctx context.Context) error {
+		if err := storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": cat.ID}, &old); err != nil {
+			return err
+		}
+		path, err := pathUnder(ctx, cat)
+		if err != nil {
+			return err
+		}
+		cat.Path = path
+
+		set, unset := map[string]interface{}{"name": cat.Name}, map[string]interface{}{}
+		if cat.Parent == "" {
+			unset["parent"], unset["path"] = "", ""
+		} else {
+			set["parent"], set["path"] = cat.Parent, cat.Path
+		}
+		update := map[string]interface{}{"$set": set}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		if err := storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": cat.ID}, update); err != nil {
+			return err
+		}
+		if old.Parent == cat.Parent {
+			return nil
+		}
+		return moveDescendants(ctx, cat)
+	})
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return cat, err
+}
+
+//Delete removes the category of the id when no category is under it, the courses keep listing it
+func (s categoryImpl) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	n, err := storage.GetInstance().Count(ctx, coll, map[string]interface{}{"parent": id})
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrHasChildren
+	}
+	err = storage.GetInstance().Remove(ctx, coll, map[string]interface{}{"_id": id})
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return err
+}
+
+//Subtree is the ids of the category of the id and of its descendants among the categories
+func Subtree(cats []types.Category, id string) []string {
+	ids := []string{id}
+	for _, c := range cats {
+		if contains(c.Path, id) {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+//pathUnder is the path of the category under its parent, failing when the parent does not exist or is the category
+//itself or one of its descendants
+func pathUnder(ctx context.Context, cat types.Category) ([]string, error) {
+	if cat.Parent == "" {
+		return nil, nil
+	}
+	var parent types.Category
+	err := storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": cat.Parent}, &parent)
+	if err == storage.ErrNotFound {
+		return nil, ErrUnknownParent
+	}
+	if err != nil {
+		return nil, err
+	}
+	if parent.ID == cat.ID || contains(parent.Path, cat.ID) {
+		return nil, ErrInvalidParent
+	}
+	return append(parent.Path, parent.ID), nil
+}
+
+//moveDescendants rewrites the paths of the descendants of the category to follow its own
+func moveDescendants(ctx context.Context, cat types.Category) error {
+	var descendants []types.Category
+	if err := storage.GetInstance().Find(ctx, coll, map[string]interface{}{"path": cat.ID}, storage.FindOptions{}, &descendants); err != nil {
+		return err
+	}
+	for _, d := range descendants {
+		path := append(append([]string{}, cat.Path...), cat.ID)
+		for i, id := range d.Path {
+			if id == cat.ID {
+				path = append(path, d.Path[i+1:]...)
+				break
+			}
+		}
+		update := map[string]interface{}{"$set": map[string]interface{}{"path": path}}
+		if err := storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": d.ID}, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package categoryservice
+
+import (
+	"context"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/mock"
+)
+
+//Mock is a mocked structure for category service
+type Mock struct {
+	mock.Mock
+}
+
+//InitMock to initialize mock befores tests
+func (s *Mock) InitMock() {
+	instance = s
+}
+
+//Create is a mock for category service create
+func (s *Mock) Create(ctx context.Context, cat types.Category) (types.Category, error) {
+	args := s.Called(ctx, cat)
+	return args.Get(0).(types.Category), args.Error(1)
+}
+
+//Update is a mock for category service update
+func (s *Mock) Update(ctx context.Context, cat types.Category) (types.Category, error) {
+	args := s.Called(ctx, cat)
+	return args.Get(0).(types.Category), args.Error(1)
+}
+
+//Delete is a mock for category service delete
+func (s *Mock) Delete(ctx context.Context, id string) error {
+	args := s.Called(ctx, id)
+	return args.Error(0)
+}
+
+//FindAll is a mock for category service findAll
+func (s *Mock) FindAll() ([]types.Category, error) {
+	args := s.Called()
+	return args.Get(0).([]types.Category), args.Error(1)
+}
+
+//FindOne is a mock for category service findOne
+func (s *Mock) FindOne(id string) (types.Category, error) {
+	args := s.Called(id)
+	return args.Get(0).(types.Category), args.Error(1)
+}
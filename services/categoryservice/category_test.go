@@ -0,0 +1,86 @@
+package categoryservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemoryCategories(t *testing.T) CategoryService {
+	testutil.Memory(t, EnsureIndexes)
+	return categoryImpl{}
+}
+
+func TestCategory_Tree(t *testing.T) {
+	s := newMemoryCategories(t)
+	ctx := context.Background()
+
+	dev, err := s.Create(ctx, types.Category{Name: "Development"})
+	assert.NoError(t, err)
+	assert.Empty(t, dev.Path)
+	web, err := s.Create(ctx, types.Category{Name: "Web", Parent: dev.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{dev.ID}, web.Path)
+	front, err := s.Create(ctx, types.Category{Name: "Frontend", Parent: web.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{dev.ID, web.ID}, front.Path)
+	design, err := s.Create(ctx, types.Category{Name: "Design"})
+	assert.NoError(t, err)
+
+	cats, err := s.FindAll()
+	assert.NoError(t, err)
+	assert.Len(t, cats, 4)
+	assert.ElementsMatch(t, []string{dev.ID, web.ID, front.ID}, Subtree(cats, dev.ID))
+	assert.Equal(t, []string{design.ID}, Subtree(cats, design.ID))
+
+	web.Parent = design.ID
+	web, err = s.Update(ctx, web)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{design.ID}, web.Path)
+	front, err = s.FindOne(front.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{design.ID, web.ID}, front.Path)
+
+	web.Parent, web.Name = "", "Web development"
+	web, err = s.Update(ctx, web)
+	assert.NoError(t, err)
+	got, err := s.FindOne(web.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Category{ID: web.ID, Name: "Web development"}, got)
+	front, err = s.FindOne(front.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{web.ID}, front.Path)
+
+	assert.Equal(t, ErrHasChildren, s.Delete(ctx, web.ID))
+	assert.NoError(t, s.Delete(ctx, front.ID))
+	assert.NoError(t, s.Delete(ctx, web.ID))
+	assert.Equal(t, storage.ErrNotFound, s.Delete(ctx, web.ID))
+}
+
+func TestCategory_Errors(t *testing.T) {
+	s := newMemoryCategories(t)
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, types.Category{Name: "Web", Parent: "unknown"})
+	assert.Equal(t, ErrUnknownParent, err)
+
+	dev, err := s.Create(ctx, types.Category{Name: "Development"})
+	assert.NoError(t, err)
+	web, err := s.Create(ctx, types.Category{Name: "Web", Parent: dev.ID})
+	assert.NoError(t, err)
+
+	dev.Parent = web.ID
+	_, err = s.Update(ctx, dev)
+	assert.Equal(t, ErrInvalidParent, err)
+	dev.Parent = dev.ID
+	_, err = s.Update(ctx, dev)
+	assert.Equal(t, ErrInvalidParent, err)
+	_, err = s.Update(ctx, types.Category{ID: "unknown", Name: "Unknown"})
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.FindOne("unknown")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
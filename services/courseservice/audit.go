@@ -0,0 +1,175 @@
+package courseservice
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const auditColl = "course_audit"
+
+//Actions of the audit entries
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
+	ActionRevert  = "revert"
+	//ActionTransition changes the status of a course and ActionSchedule schedules the change for later
+	ActionTransition = "transition"
+	ActionSchedule   = "schedule"
+	//ActionCurriculum changes the modules or lessons of a course
+	ActionCurriculum = "curriculum"
+)
+
+//audited runs the write of the course, its hooks, and stores its audit entry and the revision of after in one
+//transaction, so all or none are stored. Before is nil for created courses and after for purged ones.
+func audited(ctx context.Context, action string, before, after *types.Course, write func(context.Context) error) error {
+	e := types.AuditEntry{
+		ID:     storage.NewID(),
+		Action: action,
+		Actor:  actor.FromContext(ctx),
+		At:     time.Now().UTC(),
+		Before: before,
+		After:  after,
+	}
+	if after != nil {
+		e.CourseID, e.Version = after.ID, after.Version
+	} else {
+		e.CourseID, e.Version = before.ID, before.Version
+	}
+
+	var tags []string
+	err := storage.GetInstance().WithTransaction(ctx, func(ctx context.Context) (err error) {
+		if err = write(ctx); err != nil {
+			return err
+		}
+		if tags, err = runHooks(ctx, action, before, after); err != nil {
+			return err
+		}
+		if err := storage.GetInstance().Insert(ctx, auditColl, e); err != nil {
+			return err
+		}
+		if after == nil {
+			return nil
+		}
+		return storage.GetInstance().Insert(ctx, revisionColl, types.Revision{
+			ID:       storage.NewID(),
+			CourseID: after.ID,
+			Version:  after.Version,
+			Actor:    e.Actor,
+			At:       e.At,
+			Course:   *after,
+		})
+	})
+	if err == nil && len(tags) > 0 {
+		err = cache.InvalidateTags(tags...)
+	}
+	return err
+}
+
+//History lists, oldest first, the audit entries of the course of the id or slug. Courses purged from the trash
+//are only found by their id.
+func (s courseImpl) History(ref string) ([]types.AuditEntry, error) {
+	entries := []types.AuditEntry{}
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	id, found, err := courseID(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	query := map[string]interface{}{"courseId": id}
+	if err := storage.GetInstance().Find(ctx, auditColl, query, storage.FindOptions{Sort: []string{"version", "at"}}, &entries); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 && !found {
+		return nil, storage.ErrNotFound
+	}
+	return entries, nil
+}
+
+//courseID resolves the id or slug to the id of a course, trash included. The live course is the one of a slug
+//shared with courses in the trash. When no course is found the ref is taken as the id of a purged course.
+func courseID(ctx context.Context, ref string) (id string, found bool, err error) {
+	var c types.Course
+	err = storage.GetInstance().FindOne(ctx, coll, live(byRef(ref)), &c)
+	if err == storage.ErrNotFound {
+		err = storage.GetInstance().FindOne(ctx, coll, byRef(ref), &c)
+	}
+	if err == storage.ErrNotFound {
+		return ref, false, nil
+	}
+	return c.ID, err == nil, err
+}
+
+//Diff lists the fields changed from the revision from to the revision to of the course of the id or slug.
+//A revision is the course as stored with that version, ErrNotFound is returned when the history misses one.
+func (s courseImpl) Diff(ref string, from, to int64) ([]types.FieldChange, error) {
+	entries, err := s.History(ref)
+	if err != nil {
+		return nil, err
+	}
+	a, b := revision(entries, from), revision(entries, to)
+	if a == nil || b == nil {
+		return nil, storage.ErrNotFound
+	}
+	return diff(*a, *b)
+}
+
+func revision(entries []types.AuditEntry, version int64) *types.Course {
+	for _, e := range entries {
+		if e.After != nil && e.After.Version == version {
+			return e.After
+		}
+	}
+	return nil
+}
+
+//diff compares the json fields of the courses, the version is left out as it always changes
+func diff(from, to types.Course) ([]types.FieldChange, error) {
+	a, err := jsonFields(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := jsonFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []types.FieldChange{}
+	for _, name := range names {
+		if name != "version" && !reflect.DeepEqual(a[name], b[name]) {
+			changes = append(changes, types.FieldChange{Field: name, From: a[name], To: b[name]})
+		}
+	}
+	return changes, nil
+}
+
+func jsonFields(c types.Course) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	data, err := json.Marshal(c)
+	if err == nil {
+		err = json.Unmarshal(data, &fields)
+	}
+	return fields, err
+}
@@ -0,0 +1,122 @@
+package courseservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/services/instructorservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+//mockAudit lets the writes of the mocked storage run in a transaction storing their audit entries and revisions
+func mockAudit(mongoMock *storage.DataAccessLayerMock) {
+	mongoMock.On("WithTransaction", mock.Anything, mock.Anything).Return(nil).Maybe()
+	mongoMock.On("Insert", mock.Anything, auditColl, mock.AnythingOfType("types.AuditEntry")).Return(nil).Maybe()
+	mongoMock.On("Insert", mock.Anything, revisionColl, mock.AnythingOfType("types.Revision")).Return(nil).Maybe()
+}
+
+func TestAudited_RecordsActorAndSnapshots(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	before := types.Course{ID: "id01", Version: 1, Price: testutil.USD(10)}
+	after := types.Course{ID: "id01", Version: 2, Price: testutil.USD(0)}
+
+	var entry types.AuditEntry
+	var rev types.Revision
+	mongoMock.On("WithTransaction", mock.Anything, mock.Anything).Return(nil).Once()
+	mongoMock.On("Insert", mock.Anything, auditColl, mock.AnythingOfType("types.AuditEntry")).
+		Run(func(args mock.Arguments) {
+			entry = args.Get(2).(types.AuditEntry)
+		}).Return(nil).Once()
+	mongoMock.On("Insert", mock.Anything, revisionColl, mock.AnythingOfType("types.Revision")).
+		Run(func(args mock.Arguments) {
+			rev = args.Get(2).(types.Revision)
+		}).Return(nil).Once()
+
+	ctx := actor.NewContext(context.Background(), "finance")
+	assert.NoError(t, audited(ctx, ActionUpdate, &before, &after, func(context.Context) error { return nil }))
+	assert.Equal(t, "finance", entry.Actor)
+	assert.Equal(t, ActionUpdate, entry.Action)
+	assert.Equal(t, "id01", entry.CourseID)
+	assert.Equal(t, int64(2), entry.Version)
+	assert.Equal(t, &before, entry.Before)
+	assert.Equal(t, &after, entry.After)
+	assert.False(t, entry.At.IsZero())
+	assert.Equal(t, types.Revision{ID: rev.ID, CourseID: "id01", Version: 2, Actor: "finance", At: entry.At, Course: after}, rev)
+	mongoMock.AssertExpectations(t)
+}
+
+func TestAudited_WriteErr(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	errWrite := errors.New("write err")
+	mongoMock.On("WithTransaction", mock.Anything, mock.Anything).Return(nil).Once()
+
+	c := types.Course{ID: "id01", Version: 1}
+	assert.Equal(t, errWrite, audited(context.Background(), ActionCreate, nil, &c, func(context.Context) error { return errWrite }))
+	mongoMock.AssertExpectations(t)
+	mongoMock.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCourseHistory(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := actor.NewContext(context.Background(), "ana")
+	finance, err := instructorservice.GetInstance().Create(actor.NewContext(ctx, "finance"), types.Instructor{User: "finance", Name: "Finance"})
+	assert.NoError(t, err)
+	c, err := s.Create(ctx, types.Course{Name: "Go", Price: testutil.USD(10), Instructors: []string{finance.ID}})
+	assert.NoError(t, err)
+	c.Price = testutil.USD(0)
+	_, err = s.Update(actor.NewContext(context.Background(), "finance"), c)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Delete(ctx, c.Slug, 0))
+
+	entries, err := s.History(c.Slug)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, []string{ActionCreate, ActionUpdate, ActionDelete}, []string{entries[0].Action, entries[1].Action, entries[2].Action})
+		assert.Equal(t, []string{"ana", "finance", "ana"}, []string{entries[0].Actor, entries[1].Actor, entries[2].Actor})
+		assert.Nil(t, entries[0].Before)
+		assert.Equal(t, testutil.USD(10), entries[1].Before.Price)
+		assert.Equal(t, testutil.USD(0), entries[1].After.Price)
+	}
+
+	changes, err := s.Diff(c.ID, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.FieldChange{{
+		Field: "price",
+		From:  map[string]interface{}{"amount": 10.0, "currency": "USD"},
+		To:    map[string]interface{}{"amount": 0.0, "currency": "USD"},
+	}}, changes)
+
+	_, err = s.Diff(c.ID, 1, 9)
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.History("missing")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestCourseHistory_PurgedCourse(t *testing.T) {
+	s := newMemoryCourses(t)
+	c, err := s.Create(context.Background(), types.Course{Name: "Go"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Delete(context.Background(), c.ID, 0))
+	n, err := s.Purge(actor.NewContext(context.Background(), actor.System), time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	entries, err := s.History(c.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, ActionPurge, entries[2].Action)
+		assert.Equal(t, actor.System, entries[2].Actor)
+		assert.Nil(t, entries[2].After)
+	}
+	_, err = s.History(c.Slug)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
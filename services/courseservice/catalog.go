@@ -0,0 +1,103 @@
+package courseservice
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/categoryservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+//ErrUnknownCategory for courses in a category that does not exist
+var ErrUnknownCategory = errors.New("unknown category")
+
+//checkCategory fails when the course is in a category that does not exist, the one of old is not checked again
+func checkCategory(old, course types.Course) error {
+	if course.Category == "" || course.Category == old.Category {
+		return nil
+	}
+	_, err := categoryservice.GetInstance().FindOne(course.Category)
+	if err == storage.ErrNotFound {
+		return ErrUnknownCategory
+	}
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return err
+	}
+	return nil
+}
+
+//normalizeTags lowercases and trims the tags, leaving out the empty and repeated ones
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !contains(normalized, t) {
+			normalized = append(normalized, t)
+		}
+	}
+	return normalized
+}
+
+//withCategories resolves the category of the options to its subtree, the categories of the tree are returned
+//for the facets
+func withCategories(opts ListOptions) (ListOptions, []types.Category, error) {
+	if opts.Category == "" && !opts.Facets {
+		return opts, nil, nil
+	}
+	cats, err := categoryservice.GetInstance().FindAll()
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return opts, nil, err
+	}
+	if opts.Category != "" {
+		opts.categories = categoryservice.Subtree(cats, opts.Category)
+	}
+	return opts, cats, nil
+}
+
+//facets counts the courses of the options by category and by tag. Categories count the courses of their
+//descendants too.
+func facets(ctx context.Context, opts ListOptions, cats []types.Category) (map[string][]storage.FacetCount, error) {
+	byTag := opts
+	byTag.Tags = nil
+	tags, err := storage.GetInstance().Facet(ctx, coll, byTag.query(), "tags")
+	if err != nil {
+		return nil, err
+	}
+	byCategory := opts
+	byCategory.Category, byCategory.categories = "", nil
+	counts, err := storage.GetInstance().Facet(ctx, coll, byCategory.query(), "category")
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]storage.FacetCount{"category": rollup(counts, cats), "tag": tags}, nil
+}
+
+//rollup adds the counts of the categories to the ones of their ancestors, the most frequent first
+func rollup(counts []storage.FacetCount, cats []types.Category) []storage.FacetCount {
+	paths := map[string][]string{}
+	for _, c := range cats {
+		paths[c.ID] = c.Path
+	}
+	totals := map[string]int64{}
+	for _, c := range counts {
+		totals[c.Value] += c.Count
+		for _, id := range paths[c.Value] {
+			totals[id] += c.Count
+		}
+	}
+	rolled := make([]storage.FacetCount, 0, len(totals))
+	for id, n := range totals {
+		rolled = append(rolled, storage.FacetCount{Value: id, Count: n})
+	}
+	sort.Slice(rolled, func(i, j int) bool {
+		if rolled[i].Count != rolled[j].Count {
+			return rolled[i].Count > rolled[j].Count
+		}
+		return rolled[i].Value < rolled[j].Value
+	})
+	return rolled
+}
@@ -0,0 +1,85 @@
+package courseservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/services/categoryservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCourseCatalog_Facets(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+	dev, err := categoryservice.GetInstance().Create(ctx, types.Category{Name: "Development"})
+	assert.NoError(t, err)
+	web, err := categoryservice.GetInstance().Create(ctx, types.Category{Name: "Web", Parent: dev.ID})
+	assert.NoError(t, err)
+	design, err := categoryservice.GetInstance().Create(ctx, types.Category{Name: "Design"})
+	assert.NoError(t, err)
+
+	for _, c := range []types.Course{
+		{Name: "Go", Category: dev.ID, Tags: []string{" Go ", "backend", "go"}},
+		{Name: "React", Category: web.ID, Tags: []string{"javascript", "frontend"}},
+		{Name: "Node", Category: web.ID, Tags: []string{"javascript", "backend"}},
+		{Name: "Figma", Category: design.ID},
+	} {
+		_, err := s.Create(ctx, c)
+		assert.NoError(t, err)
+	}
+	got, err := s.FindOne("go")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "backend"}, got.Tags)
+
+	p, err := s.FindAll(ListOptions{Status: types.StatusDraft, Category: dev.ID, Facets: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Go", "Node", "React"}, names(p.Courses))
+	assert.Equal(t, []storage.FacetCount{{Value: dev.ID, Count: 3}, {Value: web.ID, Count: 2}, {Value: design.ID, Count: 1}}, sortedByValue(p.Facets["category"], dev.ID, web.ID, design.ID))
+	assert.Equal(t, []storage.FacetCount{{Value: "backend", Count: 2}, {Value: "javascript", Count: 2}, {Value: "frontend", Count: 1}, {Value: "go", Count: 1}}, p.Facets["tag"])
+
+	p, err = s.FindAll(ListOptions{Status: types.StatusDraft, Category: dev.ID, Tags: []string{"javascript", "backend"}, Facets: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Node"}, names(p.Courses))
+	assert.Equal(t, []storage.FacetCount{{Value: dev.ID, Count: 1}, {Value: web.ID, Count: 1}}, sortedByValue(p.Facets["category"], dev.ID, web.ID))
+	assert.Len(t, p.Facets["tag"], 4)
+
+	p, err = s.FindAll(ListOptions{Status: types.StatusDraft, Category: design.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Figma"}, names(p.Courses))
+	assert.Nil(t, p.Facets)
+}
+
+func TestCourseCatalog_UnknownCategory(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+	_, err := s.Create(ctx, types.Course{Name: "Go", Category: "unknown"})
+	assert.Equal(t, ErrUnknownCategory, err)
+
+	c, err := s.Create(ctx, types.Course{Name: "Go"})
+	assert.NoError(t, err)
+	c.Category = "unknown"
+	_, err = s.Update(ctx, c)
+	assert.Equal(t, ErrUnknownCategory, err)
+
+	p, err := s.FindAll(ListOptions{Status: types.StatusDraft, Category: "unknown"})
+	assert.NoError(t, err)
+	assert.Empty(t, p.Courses)
+}
+
+//sortedByValue orders the counts as the values, as equal counts are in the order of the random ids of the categories
+func sortedByValue(counts []storage.FacetCount, values ...string) []storage.FacetCount {
+	sorted := make([]storage.FacetCount, 0, len(counts))
+	for _, v := range values {
+		for _, c := range counts {
+			if c.Value == v {
+				sorted = append(sorted, c)
+			}
+		}
+	}
+	if len(sorted) != len(counts) {
+		return counts
+	}
+	return sorted
+}
@@ -2,10 +2,14 @@ package courseservice
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/ednesic/coursemanagement/actor"
 	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/slug"
 	"github.com/ednesic/coursemanagement/storage"
 	"github.com/ednesic/coursemanagement/types"
 )
@@ -15,15 +19,41 @@ const coll = "course"
 var (
 	instance CourseService
 	once     sync.Once
+
+	//ErrVersionMismatch for changes expecting a version of the course other than the current one
+	ErrVersionMismatch = errors.New("course version mismatch")
+
+	//reserved are the words of the course routes which are not slugs
+	reserved = map[string]bool{"search": true, "trash": true}
 )
 
-//CourseService is an interface for course service
+//CourseService is an interface for course service. The changes are audited with the actor of their context.
 type CourseService interface {
-	Create(types.Course) error
-	Update(types.Course) error
-	FindAll() ([]types.Course, error)
-	Delete(string) error
+	Create(context.Context, types.Course) (types.Course, error)
+	Update(context.Context, types.Course) (types.Course, error)
+	FindAll(ListOptions) (Page, error)
+	Delete(context.Context, string, int64) error
 	FindOne(string) (types.Course, error)
+	Search(string, ListOptions) ([]types.CourseHit, error)
+	Patch(context.Context, string, int64, string, []byte, func(interface{}) error) (types.Course, error)
+	Restore(context.Context, string) (types.Course, error)
+	Purge(context.Context, time.Time) (int64, error)
+	History(string) ([]types.AuditEntry, error)
+	Diff(string, int64, int64) ([]types.FieldChange, error)
+	Revision(string, int64) (types.Revision, error)
+	Revert(context.Context, string, int64, int64) (types.Course, error)
+	Transition(context.Context, string, string, time.Time, int64) (types.Course, error)
+	RunScheduled(context.Context, time.Time) (int64, error)
+	Curriculum(string) ([]types.Module, error)
+	AddModule(context.Context, string, types.Module) (types.Module, error)
+	UpdateModule(context.Context, string, types.Module) (types.Module, error)
+	DeleteModule(context.Context, string, string) error
+	ReorderModules(context.Context, string, []string) ([]types.Module, error)
+	AddLesson(context.Context, string, string, types.Lesson) (types.Lesson, error)
+	UpdateLesson(context.Context, string, string, types.Lesson) (types.Lesson, error)
+	DeleteLesson(context.Context, string, string, string) error
+	ReorderLessons(context.Context, string, string, []string) (types.Module, error)
+	Rate(context.Context, string, func(context.Context, types.Course) (types.Rating, error)) (types.Course, error)
 }
 
 type courseImpl struct{}
@@ -38,61 +68,233 @@ func GetInstance() CourseService {
 	return instance
 }
 
-func (s courseImpl) FindOne(name string) (c types.Course, err error) {
-	var mgoErr error
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+//FindOne finds the course by its id or its slug, courses in the trash are not found
+func (s courseImpl) FindOne(ref string) (c types.Course, err error) {
+	err = cache.GetOrLoad(coll+ref, &c, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().FindOne(ctx, coll, live(byRef(ref)), v)
+	})
+	return c, err
+}
+
+//Create generates the id and the slug of the course before storing it as the first version of a draft
+//written by the actor of the context. Courses without instructors are linked to the one the actor is.
+func (s courseImpl) Create(ctx context.Context, course types.Course) (types.Course, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
-	if err := cache.GetInstance().Get(coll+name, &c); err != nil {
-		if mgoErr = storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"name": name}, &c); mgoErr == nil {
-			return c, cache.GetInstance().Set(coll+name, c, time.Minute)
+	if err := checkInstructors(types.Course{}, course); err != nil {
+		return course, err
+	}
+	if err := checkCategory(types.Course{}, course); err != nil {
+		return course, err
+	}
+	if err := normalizePrices(&course); err != nil {
+		return course, err
+	}
+	if err := ownInstructor(ctx, &course); err != nil {
+		return course, err
+	}
+	course.ID = storage.NewID()
+	var err error
+	if course.Slug, err = slugOf(ctx, course); err != nil {
+		return course, err
+	}
+	course.Version = 1
+	course.Status, course.Author, course.Scheduled = types.StatusDraft, "", nil
+	course.Duration, course.Rating = 0, nil
+	course.Tags = normalizeTags(course.Tags)
+	if a := actor.FromContext(ctx); a != actor.Anonymous {
+		course.Author = a
+	}
+	err = audited(ctx, ActionCreate, nil, &course, func(ctx context.Context) error {
+		return storage.GetInstance().Insert(ctx, coll, course)
+	})
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+		if cerr := cache.Put(coll+course.ID, course, time.Minute); err == nil {
+			err = cerr
 		}
 	}
-	return c, mgoErr
+	return course, err
 }
 
-func (s courseImpl) Create(course types.Course) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+//Update replaces the course which id or slug is the id of the course, its slug follows the new name. The version
+//of the course is the one it is expected to replace, any when it is 0.
+func (s courseImpl) Update(ctx context.Context, course types.Course) (types.Course, error) {
+	var old types.Course
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
-	err := storage.GetInstance().Insert(ctx, coll, course)
+	if err := storage.GetInstance().FindOne(ctx, coll, live(byRef(course.ID)), &old); err != nil {
+		return course, err
+	}
+	if err := checkOwner(ctx, old); err != nil {
+		return old, err
+	}
+	if err := checkVersion(old, course.Version); err != nil {
+		return old, err
+	}
+	return replace(ctx, ActionUpdate, old, course)
+}
+
+//replace stores every field of the course in place of old as its next version, keeping its id, author,
+//lifecycle, which only Transition changes, duration, which follows the curriculum, and rating, which follows the
+//reviews. Its slug follows the new name and is kept while the name is. The course is not replaced when old is not its
+//current version anymore.
+func replace(ctx context.Context, action string, old, course types.Course) (types.Course, error) {
+	course.ID, course.Slug = old.ID, old.Slug
+	if course.Name != old.Name || old.Slug == "" {
+		var err error
+		if course.Slug, err = slugOf(ctx, course); err != nil {
+			return old, err
+		}
+	}
+	course.Version = old.Version + 1
+	course.DeletedAt = old.DeletedAt
+	course.Status, course.Author, course.Scheduled = old.Status, old.Author, old.Scheduled
+	course.Duration, course.Rating = old.Duration, old.Rating
+	course.Tags = normalizeTags(course.Tags)
+	if err := checkInstructors(old, course); err != nil {
+		return old, err
+	}
+	if err := checkCategory(old, course); err != nil {
+		return old, err
+	}
+	if err := normalizePrices(&course); err != nil {
+		return old, err
+	}
+	err := audited(ctx, action, &old, &course, func(ctx context.Context) error {
+		//the rating is left out of the update as reviews change it without a new version
+		set := course
+		set.Rating = nil
+		return storage.
+			GetInstance().
+			Update(ctx, coll, versioned(old), map[string]interface{}{"$set": &set})
+	})
 	if err == nil {
-		return cache.GetInstance().Set(coll+course.Name, course, time.Minute)
+		return course, invalidate(old)
 	}
-	return err
+	if err == storage.ErrNotFound {
+		return old, ErrVersionMismatch
+	}
+	return course, err
 }
 
-func (s courseImpl) Update(course types.Course) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+func (s courseImpl) FindAll(opts ListOptions) (p Page, err error) {
+	opts, cats, err := withCategories(opts)
+	if err != nil {
+		return p, err
+	}
+	err = cache.GetOrLoadTagged(coll+"all"+opts.cacheKey(), []string{coll}, &p, time.Minute, func(v interface{}) (err error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		page, query := v.(*Page), opts.query()
+		if page.Total, err = storage.GetInstance().Count(ctx, coll, query); err != nil {
+			return err
+		}
+		if err = storage.GetInstance().Find(ctx, coll, query, opts.findOptions(), &page.Courses); err != nil || !opts.Facets {
+			return err
+		}
+		page.Facets, err = facets(ctx, opts, cats)
+		return err
+	})
+	return p, err
+}
+
+//Delete moves the course of the id or slug to the trash when it is at the version, any when it is 0.
+//The course is removed for good by Purge.
+func (s courseImpl) Delete(ctx context.Context, ref string, version int64) error {
+	var old types.Course
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
-	err := storage.
-		GetInstance().
-		Update(ctx, coll, map[string]interface{}{"name": course.Name}, map[string]interface{}{"$set": &course})
+	if err := storage.GetInstance().FindOne(ctx, coll, live(byRef(ref)), &old); err != nil {
+		return err
+	}
+	if err := checkOwner(ctx, old); err != nil {
+		return err
+	}
+	if err := checkVersion(old, version); err != nil {
+		return err
+	}
+	deleted, now := old, time.Now().UTC()
+	deleted.DeletedAt, deleted.Version = &now, old.Version+1
+	err := audited(ctx, ActionDelete, &old, &deleted, func(ctx context.Context) error {
+		return storage.GetInstance().Update(ctx, coll, versioned(old), map[string]interface{}{
+			"$set": map[string]interface{}{"deletedAt": now, "version": deleted.Version},
+		})
+	})
 	if err == nil {
-		return cache.GetInstance().Delete(coll + course.Name)
+		return invalidate(old)
+	}
+	if err == storage.ErrNotFound {
+		return ErrVersionMismatch
 	}
 	return err
 }
 
-func (s courseImpl) FindAll() ([]types.Course, error) {
-	var mgoErr error
-	var cs []types.Course
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-	suffixKey := "all"
+//byRef selects a course by its id or its slug
+func byRef(ref string) map[string]interface{} {
+	return map[string]interface{}{"$or": []interface{}{
+		map[string]interface{}{"_id": ref},
+		map[string]interface{}{"slug": ref},
+	}}
+}
+
+//live restricts the query to the courses which are not in the trash
+func live(query map[string]interface{}) map[string]interface{} {
+	query["deletedAt"] = nil
+	return query
+}
+
+//versioned selects the course while it is at its version. Courses stored before versions were kept are at 0.
+func versioned(c types.Course) map[string]interface{} {
+	if c.Version == 0 {
+		return map[string]interface{}{"_id": c.ID, "version": map[string]interface{}{"$in": []interface{}{0, nil}}}
+	}
+	return map[string]interface{}{"_id": c.ID, "version": c.Version}
+}
+
+//checkVersion fails when the course is not at the version, any version is accepted when it is 0
+func checkVersion(c types.Course, version int64) error {
+	if version != 0 && version != c.Version {
+		return ErrVersionMismatch
+	}
+	return nil
+}
 
-	if cacheErr := cache.GetInstance().Get(coll+suffixKey, &cs); cacheErr != nil {
-		if mgoErr = storage.GetInstance().Find(ctx, coll, map[string]interface{}{}, &cs); mgoErr == nil {
-			return cs, cache.GetInstance().Set(coll+suffixKey, cs, time.Minute)
+//slugOf derives the slug of the course from its name, falling back to its id when the name has no letters nor digits.
+//Slugs of other live courses and the words of the course routes are followed by the first free number, from 2.
+func slugOf(ctx context.Context, course types.Course) (string, error) {
+	base := slug.Make(course.Name)
+	if base == "" {
+		return course.ID, nil
+	}
+	for n := 1; ; n++ {
+		s := base
+		if n > 1 {
+			s = fmt.Sprintf("%s-%d", base, n)
+		}
+		if reserved[s] {
+			continue
+		}
+		taken, err := storage.GetInstance().Count(ctx, coll, live(map[string]interface{}{
+			"slug": s,
+			"_id":  map[string]interface{}{"$ne": course.ID},
+		}))
+		if err != nil || taken == 0 {
+			return s, err
 		}
 	}
-	return cs, mgoErr
 }
 
-func (s courseImpl) Delete(name string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-	err := storage.GetInstance().Remove(ctx, coll, map[string]interface{}{"name": name})
-	if err == nil {
-		return cache.GetInstance().Delete(coll + name)
+//invalidate drops the course cached by id and by slug and every cached list and search result, which are tagged with coll.
+//Keys that were not cached are not an error.
+func invalidate(c types.Course) error {
+	err := cache.InvalidateTags(coll)
+	for _, key := range []string{coll + c.ID, coll + c.Slug} {
+		if cerr := cache.GetInstance().Delete(key); err == nil && !cache.IsMiss(cerr) {
+			err = cerr
+		}
 	}
 	return err
 }
@@ -1,6 +1,9 @@
 package courseservice
 
 import (
+	"context"
+	"time"
+
 	"github.com/ednesic/coursemanagement/types"
 	"github.com/stretchr/testify/mock"
 )
@@ -16,31 +19,151 @@ func (s *Mock) InitMock() {
 }
 
 //FindOne is a mock for course service findOne
-func (s *Mock) FindOne(name string) (c types.Course, err error) {
-	args := s.Called(name)
+func (s *Mock) FindOne(ref string) (c types.Course, err error) {
+	args := s.Called(ref)
 	return args.Get(0).(types.Course), args.Error(1)
 }
 
 //Create is a mock for course service create
-func (s *Mock) Create(course types.Course) error {
-	args := s.Called(course)
-	return args.Error(0)
+func (s *Mock) Create(ctx context.Context, course types.Course) (types.Course, error) {
+	args := s.Called(ctx, course)
+	return args.Get(0).(types.Course), args.Error(1)
 }
 
 //Update is a mock for course service update
-func (s *Mock) Update(course types.Course) error {
-	args := s.Called(course)
-	return args.Error(0)
+func (s *Mock) Update(ctx context.Context, course types.Course) (types.Course, error) {
+	args := s.Called(ctx, course)
+	return args.Get(0).(types.Course), args.Error(1)
 }
 
 //FindAll is a mock for course service finaAll
-func (s *Mock) FindAll() (cs []types.Course, err error) {
-	args := s.Called()
-	return args.Get(0).([]types.Course), args.Error(1)
+func (s *Mock) FindAll(opts ListOptions) (p Page, err error) {
+	args := s.Called(opts)
+	return args.Get(0).(Page), args.Error(1)
 }
 
 //Delete is a mock for course service delete
-func (s *Mock) Delete(name string) error {
-	args := s.Called(name)
+func (s *Mock) Delete(ctx context.Context, ref string, version int64) error {
+	args := s.Called(ctx, ref, version)
+	return args.Error(0)
+}
+
+//Search is a mock for course service search
+func (s *Mock) Search(text string, opts ListOptions) ([]types.CourseHit, error) {
+	args := s.Called(text, opts)
+	return args.Get(0).([]types.CourseHit), args.Error(1)
+}
+
+//Patch is a mock for course service patch
+func (s *Mock) Patch(ctx context.Context, ref string, version int64, patchType string, patch []byte, validate func(interface{}) error) (types.Course, error) {
+	args := s.Called(ctx, ref, version, patchType, patch, validate)
+	return args.Get(0).(types.Course), args.Error(1)
+}
+
+//Restore is a mock for course service restore
+func (s *Mock) Restore(ctx context.Context, ref string) (types.Course, error) {
+	args := s.Called(ctx, ref)
+	return args.Get(0).(types.Course), args.Error(1)
+}
+
+//Purge is a mock for course service purge
+func (s *Mock) Purge(ctx context.Context, before time.Time) (int64, error) {
+	args := s.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+//History is a mock for course service history
+func (s *Mock) History(ref string) ([]types.AuditEntry, error) {
+	args := s.Called(ref)
+	return args.Get(0).([]types.AuditEntry), args.Error(1)
+}
+
+//Diff is a mock for course service diff
+func (s *Mock) Diff(ref string, from, to int64) ([]types.FieldChange, error) {
+	args := s.Called(ref, from, to)
+	return args.Get(0).([]types.FieldChange), args.Error(1)
+}
+
+//Revision is a mock for course service revision
+func (s *Mock) Revision(ref string, rev int64) (types.Revision, error) {
+	args := s.Called(ref, rev)
+	return args.Get(0).(types.Revision), args.Error(1)
+}
+
+//Revert is a mock for course service revert
+func (s *Mock) Revert(ctx context.Context, ref string, rev, version int64) (types.Course, error) {
+	args := s.Called(ctx, ref, rev, version)
+	return args.Get(0).(types.Course), args.Error(1)
+}
+
+//Transition is a mock for course service transition
+func (s *Mock) Transition(ctx context.Context, ref, status string, at time.Time, version int64) (types.Course, error) {
+	args := s.Called(ctx, ref, status, at, version)
+	return args.Get(0).(types.Course), args.Error(1)
+}
+
+//RunScheduled is a mock for course service runScheduled
+func (s *Mock) RunScheduled(ctx context.Context, now time.Time) (int64, error) {
+	args := s.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+//Curriculum is a mock for course service curriculum
+func (s *Mock) Curriculum(ref string) ([]types.Module, error) {
+	args := s.Called(ref)
+	return args.Get(0).([]types.Module), args.Error(1)
+}
+
+//AddModule is a mock for course service addModule
+func (s *Mock) AddModule(ctx context.Context, ref string, module types.Module) (types.Module, error) {
+	args := s.Called(ctx, ref, module)
+	return args.Get(0).(types.Module), args.Error(1)
+}
+
+//UpdateModule is a mock for course service updateModule
+func (s *Mock) UpdateModule(ctx context.Context, ref string, module types.Module) (types.Module, error) {
+	args := s.Called(ctx, ref, module)
+	return args.Get(0).(types.Module), args.Error(1)
+}
+
+//DeleteModule is a mock for course service deleteModule
+func (s *Mock) DeleteModule(ctx context.Context, ref, moduleID string) error {
+	args := s.Called(ctx, ref, moduleID)
+	return args.Error(0)
+}
+
+//ReorderModules is a mock for course service reorderModules
+func (s *Mock) ReorderModules(ctx context.Context, ref string, ids []string) ([]types.Module, error) {
+	args := s.Called(ctx, ref, ids)
+	return args.Get(0).([]types.Module), args.Error(1)
+}
+
+//AddLesson is a mock for course service addLesson
+func (s *Mock) AddLesson(ctx context.Context, ref, moduleID string, lesson types.Lesson) (types.Lesson, error) {
+	args := s.Called(ctx, ref, moduleID, lesson)
+	return args.Get(0).(types.Lesson), args.Error(1)
+}
+
+//UpdateLesson is a mock for course service updateLesson
+func (s *Mock) UpdateLesson(ctx context.Context, ref, moduleID string, lesson types.Lesson) (types.Lesson, error) {
+	args := s.Called(ctx, ref, moduleID, lesson)
+	return args.Get(0).(types.Lesson), args.Error(1)
+}
+
+//DeleteLesson is a mock for course service deleteLesson
+func (s *Mock) DeleteLesson(ctx context.Context, ref, moduleID, lessonID string) error {
+	args := s.Called(ctx, ref, moduleID, lessonID)
 	return args.Error(0)
 }
+
+//ReorderLessons is a mock for course service reorderLessons
+func (s *Mock) ReorderLessons(ctx context.Context, ref, moduleID string, ids []string) (types.Module, error) {
+	args := s.Called(ctx, ref, moduleID, ids)
+	return args.Get(0).(types.Module), args.Error(1)
+}
+
+//Rate is a mock for course service rate
+func (s *Mock) Rate(ctx context.Context, ref string, fn func(context.Context, types.Course) (types.Rating, error)) (types.Course, error) {
+	args := s.Called(ctx, ref, fn)
+	return args.Get(0).(types.Course), args.Error(1)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	redis "github.com/ednesic/coursemanagement/cache"
 	"github.com/ednesic/coursemanagement/storage"
@@ -13,20 +14,21 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
+//usd is an amount of cents
 func TestCourseFindOne_FindsCourseCached(t *testing.T) {
 	redisMock := &redis.Mock{}
-	testName := "test01"
-	redisCourseMock := types.Course{Name: testName}
+	testID := "id01"
+	redisCourseMock := types.Course{ID: testID, Name: "test01"}
 	redisMock.Initialize(map[string]string{})
 
-	redisMock.On("Get", coll+testName, mock.Anything).Return(nil).
+	redisMock.On("Get", coll+testID, mock.Anything).Return(nil).
 		Run(func(args mock.Arguments) {
-			arg := args.Get(1).(*types.Course)
-			*arg = redisCourseMock
+			arg := args.Get(1).(*redis.Entry)
+			*arg, _ = redis.NewEntry(redisCourseMock, 0, time.Minute)
 		}).Once()
 	courseService := courseImpl{}
 
-	c, err := courseService.FindOne(testName)
+	c, err := courseService.FindOne(testID)
 	assert.Nil(t, err)
 	assert.Equal(t, c, redisCourseMock)
 
@@ -36,22 +38,23 @@ func TestCourseFindOne_FindsCourseCached(t *testing.T) {
 func TestCourseFindOne_DoNotFindCourseCached(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	redisMock := &redis.Mock{}
-	testName := "test01"
-	mongoCourseMock := types.Course{Name: testName}
+	testSlug := "test-01"
+	mongoCourseMock := types.Course{ID: "id01", Slug: testSlug, Name: "Test 01"}
 	redisMock.Initialize(map[string]string{})
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
 
-	redisMock.On("Get", coll+testName, mock.Anything).Return(cache.ErrCacheMiss).Once()
-	mongoMock.On("FindOne", mock.Anything, coll, mock.Anything, mock.AnythingOfType("*types.Course")).
+	redisMock.On("Get", coll+testSlug, mock.Anything).Return(cache.ErrCacheMiss).Once()
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(testSlug)), mock.AnythingOfType("*types.Course")).
 		Run(func(args mock.Arguments) {
 			arg := args.Get(3).(*types.Course)
 			*arg = mongoCourseMock
 		}).Return(nil).Once()
-	redisMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	redisMock.On("Set", coll+testSlug, mock.Anything, mock.Anything).Return(nil).Once()
 
 	courseService := courseImpl{}
 
-	c, err := courseService.FindOne(testName)
+	c, err := courseService.FindOne(testSlug)
 	assert.Nil(t, err)
 	assert.Equal(t, c, mongoCourseMock)
 
@@ -62,16 +65,17 @@ func TestCourseFindOne_DoNotFindCourseCached(t *testing.T) {
 func TestCourseCreate_ErrOnInsert(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	testCourse := types.Course{Name: "test02"}
-	errMock := errors.New("insert err")
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+	mongoMock.On("Count", mock.Anything, coll, mock.Anything).Return(0, nil).Once()
 
 	mongoMock.On("Insert", mock.Anything, coll, mock.AnythingOfType("types.Course")).
-		Return(errMock).Once()
+		Return(storage.ErrDuplicate).Once()
 
 	courseService := courseImpl{}
 
-	err := courseService.Create(testCourse)
-	assert.Equal(t, err, errMock)
+	_, err := courseService.Create(context.Background(), testCourse)
+	assert.Equal(t, err, storage.ErrDuplicate)
 	mongoMock.AssertExpectations(t)
 }
 
@@ -82,14 +86,17 @@ func TestCourseCreate_ErrOnCache(t *testing.T) {
 	errMock := errors.New("insert err")
 	redisMock.Initialize(map[string]string{})
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+	mongoMock.On("Count", mock.Anything, coll, mock.Anything).Return(0, nil).Once()
 
 	mongoMock.On("Insert", mock.Anything, coll, mock.AnythingOfType("types.Course")).
 		Return(nil).Once()
-	redisMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(errMock).Once()
+	redisMock.On("Set", "tag:"+coll, mock.Anything, time.Duration(0)).Return(nil).Once()
+	redisMock.On("Set", mock.Anything, mock.Anything, time.Minute).Return(errMock).Once()
 
 	courseService := courseImpl{}
 
-	err := courseService.Create(testCourse)
+	_, err := courseService.Create(context.Background(), testCourse)
 	assert.Equal(t, errMock, err)
 	mongoMock.AssertExpectations(t)
 	redisMock.AssertExpectations(t)
@@ -98,34 +105,66 @@ func TestCourseCreate_ErrOnCache(t *testing.T) {
 func TestCourseCreate_Success(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	redisMock := &redis.Mock{}
-	testCourse := types.Course{Name: "test02"}
+	testCourse := types.Course{Name: "Test 02 é"}
 	redisMock.Initialize(map[string]string{})
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+	mongoMock.On("Count", mock.Anything, coll, mock.MatchedBy(func(q map[string]interface{}) bool {
+		return q["slug"] == "test-02-e"
+	})).Return(0, nil).Once()
 
+	var inserted types.Course
 	mongoMock.On("Insert", mock.Anything, coll, mock.AnythingOfType("types.Course")).
-		Return(nil).Once()
-	redisMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		Run(func(args mock.Arguments) {
+			inserted = args.Get(2).(types.Course)
+		}).Return(nil).Once()
+	redisMock.On("Set", "tag:"+coll, mock.Anything, time.Duration(0)).Return(nil).Once()
+	redisMock.On("Set", mock.Anything, mock.Anything, time.Minute).Return(nil).Once()
 
 	courseService := courseImpl{}
 
-	err := courseService.Create(testCourse)
+	c, err := courseService.Create(context.Background(), testCourse)
 	assert.Nil(t, err)
+	assert.NotEmpty(t, c.ID)
+	assert.Equal(t, "test-02-e", c.Slug)
+	assert.Equal(t, int64(1), c.Version)
+	assert.Equal(t, c, inserted)
+	redisMock.AssertCalled(t, "Set", coll+c.ID, mock.Anything, time.Minute)
 	mongoMock.AssertExpectations(t)
 	redisMock.AssertExpectations(t)
 }
 
+func TestCourseUpdate_ErrNotFound(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	testCourse := types.Course{ID: "id02", Name: "test02"}
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(testCourse.ID)), mock.Anything).
+		Return(storage.ErrNotFound).Once()
+
+	courseService := courseImpl{}
+
+	_, err := courseService.Update(context.Background(), testCourse)
+	assert.Equal(t, storage.ErrNotFound, err)
+	mongoMock.AssertExpectations(t)
+}
+
 func TestCourseUpdate_ErrUpdate(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
-	testCourse := types.Course{Name: "test02"}
+	testCourse := types.Course{ID: "id02", Name: "test02"}
 	errMock := errors.New("err update")
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
 
+	mongoMock.On("FindOne", mock.Anything, coll, mock.Anything, mock.Anything).Return(nil).Once()
+	mongoMock.On("Count", mock.Anything, coll, mock.Anything).Return(0, nil).Once()
 	mongoMock.On("Update", mock.Anything, coll, mock.Anything, mock.Anything).
 		Return(errMock).Once()
 
 	courseService := courseImpl{}
 
-	err := courseService.Update(testCourse)
+	_, err := courseService.Update(context.Background(), testCourse)
 	assert.Equal(t, err, errMock)
 	mongoMock.AssertExpectations(t)
 }
@@ -133,18 +172,22 @@ func TestCourseUpdate_ErrUpdate(t *testing.T) {
 func TestCourseUpdate_ErrCache(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	redisMock := &redis.Mock{}
-	testCourse := types.Course{Name: "test02"}
+	testCourse := types.Course{ID: "id02", Name: "test02"}
 	errMock := errors.New("err update")
 	redisMock.Initialize(map[string]string{})
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
 
+	mongoMock.On("FindOne", mock.Anything, coll, mock.Anything, mock.Anything).Return(nil).Once()
+	mongoMock.On("Count", mock.Anything, coll, mock.Anything).Return(0, nil).Once()
 	mongoMock.On("Update", mock.Anything, coll, mock.Anything, mock.Anything).
 		Return(nil).Once()
-	redisMock.On("Delete", mock.Anything).Return(errMock).Once()
+	redisMock.On("Set", "tag:"+coll, mock.Anything, time.Duration(0)).Return(nil).Once()
+	redisMock.On("Delete", mock.Anything).Return(errMock).Twice()
 
 	courseService := courseImpl{}
 
-	err := courseService.Update(testCourse)
+	_, err := courseService.Update(context.Background(), testCourse)
 	assert.Equal(t, err, errMock)
 	mongoMock.AssertExpectations(t)
 	redisMock.AssertExpectations(t)
@@ -153,59 +196,100 @@ func TestCourseUpdate_ErrCache(t *testing.T) {
 func TestCourseUpdate_Success(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	redisMock := &redis.Mock{}
-	testCourse := types.Course{Name: "test02"}
+	oldCourse := types.Course{ID: "id02", Slug: "old-name", Version: 2, Name: "Old name"}
+	testCourse := types.Course{ID: "id02", Name: "New name"}
 	redisMock.Initialize(map[string]string{})
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
 
-	mongoMock.On("Update", mock.Anything, coll, mock.Anything, mock.Anything).
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(testCourse.ID)), mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(3).(*types.Course) = oldCourse
+		}).Return(nil).Once()
+	mongoMock.On("Count", mock.Anything, coll, live(map[string]interface{}{
+		"slug": "new-name",
+		"_id":  map[string]interface{}{"$ne": testCourse.ID},
+	})).Return(0, nil).Once()
+	mongoMock.On("Update", mock.Anything, coll, map[string]interface{}{"_id": testCourse.ID, "version": int64(2)}, mock.Anything).
 		Return(nil).Once()
-	redisMock.On("Delete", mock.Anything).Return(nil).Once()
+	redisMock.On("Set", "tag:"+coll, mock.Anything, time.Duration(0)).Return(nil).Once()
+	redisMock.On("Delete", coll+oldCourse.ID).Return(nil).Once()
+	redisMock.On("Delete", coll+oldCourse.Slug).Return(nil).Once()
 
 	courseService := courseImpl{}
 
-	err := courseService.Update(testCourse)
+	c, err := courseService.Update(context.Background(), testCourse)
 	assert.Nil(t, err)
+	assert.Equal(t, "new-name", c.Slug)
+	assert.Equal(t, int64(3), c.Version)
 	mongoMock.AssertExpectations(t)
 	redisMock.AssertExpectations(t)
 }
 
 func TestCourseFindAll_SuccessGetCache(t *testing.T) {
 	redisMock := &redis.Mock{}
-	suffix := "all"
-	redisCourseMock := []types.Course{{Name: "test03"}, {Name: "test04"}}
+	suffix := "all#course=1"
+	redisPageMock := Page{Courses: []types.Course{{Name: "test03"}, {Name: "test04"}}, Total: 2}
 	redisMock.Initialize(map[string]string{})
 
+	mockTagVersion(redisMock, 1)
 	redisMock.On("Get", coll+suffix, mock.Anything).Return(nil).
 		Run(func(args mock.Arguments) {
-			arg := args.Get(1).(*[]types.Course)
-			*arg = redisCourseMock
+			arg := args.Get(1).(*redis.Entry)
+			*arg, _ = redis.NewEntry(redisPageMock, 0, time.Minute)
 		}).Once()
 
 	courseService := courseImpl{}
 
-	c, err := courseService.FindAll()
+	p, err := courseService.FindAll(ListOptions{})
 	assert.Nil(t, err)
-	assert.Equal(t, c, redisCourseMock)
+	assert.Equal(t, p, redisPageMock)
 
 	redisMock.AssertExpectations(t)
 }
 
+func TestCourseFindAll_ErrCount(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	redisMock := &redis.Mock{}
+	suffix := "all#course=1"
+	errMock := errors.New("err count")
+	redisMock.Initialize(map[string]string{})
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+
+	mockTagVersion(redisMock, 1)
+	redisMock.On("Get", coll+suffix, mock.Anything).Return(cache.ErrCacheMiss).Once()
+	mongoMock.On("Count", mock.Anything, coll, mock.Anything).Return(0, errMock).Once()
+
+	courseService := courseImpl{}
+
+	p, err := courseService.FindAll(ListOptions{})
+	assert.Equal(t, err, errMock)
+	assert.Len(t, p.Courses, 0)
+
+	redisMock.AssertExpectations(t)
+	mongoMock.AssertExpectations(t)
+}
+
 func TestCourseFindAll_ErrGet(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	redisMock := &redis.Mock{}
-	suffix := "all"
+	suffix := "all#course=1"
 	errMock := errors.New("err find")
 	redisMock.Initialize(map[string]string{})
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
 
+	mockTagVersion(redisMock, 1)
 	redisMock.On("Get", coll+suffix, mock.Anything).Return(cache.ErrCacheMiss).Once()
-	mongoMock.On("Find", mock.Anything, coll, mock.Anything, mock.AnythingOfType("*[]types.Course")).Return(errMock).Once()
+	mongoMock.On("Count", mock.Anything, coll, mock.Anything).Return(0, nil).Once()
+	mongoMock.On("Find", mock.Anything, coll, mock.Anything, mock.Anything, mock.AnythingOfType("*[]types.Course")).Return(errMock).Once()
 
 	courseService := courseImpl{}
 
-	c, err := courseService.FindAll()
+	p, err := courseService.FindAll(ListOptions{})
 	assert.Equal(t, err, errMock)
-	assert.Len(t, c, 0)
+	assert.Len(t, p.Courses, 0)
 
 	redisMock.AssertExpectations(t)
 	mongoMock.AssertExpectations(t)
@@ -214,20 +298,23 @@ func TestCourseFindAll_ErrGet(t *testing.T) {
 func TestCourseFindAll_ErrSetCache(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	redisMock := &redis.Mock{}
-	suffix := "all"
+	suffix := "all#course=1"
 	errMock := errors.New("err set cache")
 	redisMock.Initialize(map[string]string{})
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
 
+	mockTagVersion(redisMock, 1)
 	redisMock.On("Get", coll+suffix, mock.Anything).Return(cache.ErrCacheMiss).Once()
-	mongoMock.On("Find", mock.Anything, coll, mock.Anything, mock.AnythingOfType("*[]types.Course")).Return(nil).Once()
+	mongoMock.On("Count", mock.Anything, coll, mock.Anything).Return(0, nil).Once()
+	mongoMock.On("Find", mock.Anything, coll, mock.Anything, mock.Anything, mock.AnythingOfType("*[]types.Course")).Return(nil).Once()
 	redisMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(errMock).Once()
 
 	courseService := courseImpl{}
 
-	c, err := courseService.FindAll()
+	p, err := courseService.FindAll(ListOptions{})
 	assert.Equal(t, errMock, err)
-	assert.Len(t, c, 0)
+	assert.Len(t, p.Courses, 0)
 
 	redisMock.AssertExpectations(t)
 	mongoMock.AssertExpectations(t)
@@ -236,38 +323,165 @@ func TestCourseFindAll_ErrSetCache(t *testing.T) {
 func TestCourseFindAll_Success(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	redisMock := &redis.Mock{}
-	suffix := "all"
+	suffix := "all#course=1"
 	mongoCourseMock := []types.Course{{Name: "test03"}, {Name: "test04"}}
 	redisMock.Initialize(map[string]string{})
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
 
+	mockTagVersion(redisMock, 1)
 	redisMock.On("Get", coll+suffix, mock.Anything).Return(cache.ErrCacheMiss).Once()
-	mongoMock.On("Find", mock.Anything, coll, mock.Anything, mock.AnythingOfType("*[]types.Course")).Run(func(args mock.Arguments) {
-		arg := args.Get(3).(*[]types.Course)
+	mongoMock.On("Count", mock.Anything, coll, mock.Anything).Return(2, nil).Once()
+	mongoMock.On("Find", mock.Anything, coll, mock.Anything, mock.Anything, mock.AnythingOfType("*[]types.Course")).Run(func(args mock.Arguments) {
+		arg := args.Get(4).(*[]types.Course)
 		*arg = mongoCourseMock
 	}).Return(nil).Once()
 	redisMock.On("Set", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 
 	courseService := courseImpl{}
 
-	c, err := courseService.FindAll()
+	p, err := courseService.FindAll(ListOptions{})
 	assert.Nil(t, err)
-	assert.Equal(t, c, mongoCourseMock)
+	assert.Equal(t, p, Page{Courses: mongoCourseMock, Total: 2})
+
+	redisMock.AssertExpectations(t)
+	mongoMock.AssertExpectations(t)
+}
+
+func TestCourseFindAll_Options(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	redisMock := &redis.Mock{}
+	minPrice, maxPrice := int64(1000), int64(2050)
+	opts := ListOptions{Offset: 20, Limit: 10, Sort: "-price", MinPrice: &minPrice, MaxPrice: &maxPrice, Currency: "EUR"}
+	key := coll + "all?currency=EUR&limit=10&maxPrice=2050&minPrice=1000&offset=20&sort=-price#course=1"
+	query := map[string]interface{}{
+		"deletedAt":      nil,
+		"status":         map[string]interface{}{"$in": []interface{}{types.StatusPublished, "", nil}},
+		"price.currency": "EUR",
+		"price.amount":   map[string]interface{}{"$gte": minPrice, "$lte": maxPrice},
+	}
+	findOpts := storage.FindOptions{Skip: 20, Limit: 10, Sort: []string{"-price.amount", "name"}}
+	redisMock.Initialize(map[string]string{})
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+
+	mockTagVersion(redisMock, 1)
+	redisMock.On("Get", key, mock.Anything).Return(cache.ErrCacheMiss).Once()
+	mongoMock.On("Count", mock.Anything, coll, query).Return(30, nil).Once()
+	mongoMock.On("Find", mock.Anything, coll, query, findOpts, mock.AnythingOfType("*[]types.Course")).Return(nil).Once()
+	redisMock.On("Set", key, mock.Anything, mock.Anything).Return(nil).Once()
+
+	courseService := courseImpl{}
+
+	p, err := courseService.FindAll(opts)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(30), p.Total)
 
 	redisMock.AssertExpectations(t)
 	mongoMock.AssertExpectations(t)
 }
 
+func TestCourseUpdate_ErrVersionMismatch(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	oldCourse := types.Course{ID: "id02", Version: 2, Name: "Old name"}
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(oldCourse.ID)), mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(3).(*types.Course) = oldCourse
+		}).Return(nil).Once()
+
+	courseService := courseImpl{}
+
+	c, err := courseService.Update(context.Background(), types.Course{ID: "id02", Version: 1, Name: "New name"})
+	assert.Equal(t, ErrVersionMismatch, err)
+	assert.Equal(t, oldCourse, c)
+	mongoMock.AssertExpectations(t)
+}
+
+func TestCourseUpdate_ErrConcurrentUpdate(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	oldCourse := types.Course{ID: "id02", Name: "Old name"}
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(oldCourse.ID)), mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(3).(*types.Course) = oldCourse
+		}).Return(nil).Once()
+	mongoMock.On("Count", mock.Anything, coll, mock.Anything).Return(0, nil).Once()
+	mongoMock.On("Update", mock.Anything, coll, versioned(oldCourse), mock.Anything).
+		Return(storage.ErrNotFound).Once()
+
+	courseService := courseImpl{}
+
+	_, err := courseService.Update(context.Background(), types.Course{ID: "id02", Name: "New name"})
+	assert.Equal(t, ErrVersionMismatch, err)
+	mongoMock.AssertExpectations(t)
+}
+
+func TestVersioned_UnversionedCourse(t *testing.T) {
+	m := storage.NewMemory()
+	ctx := context.Background()
+	assert.NoError(t, m.Initialize(ctx, "", ""))
+	assert.NoError(t, m.Insert(ctx, coll, map[string]interface{}{"_id": "id01", "name": "legacy"}))
+
+	assert.NoError(t, m.Update(ctx, coll, versioned(types.Course{ID: "id01"}), map[string]interface{}{
+		"$set": map[string]interface{}{"version": 1},
+	}))
+	assert.Equal(t, storage.ErrNotFound, m.Remove(ctx, coll, versioned(types.Course{ID: "id01"})))
+	assert.NoError(t, m.Remove(ctx, coll, versioned(types.Course{ID: "id01", Version: 1})))
+}
+
+func TestCourseDelete_ErrNotFound(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	testCourse := "test02"
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(testCourse)), mock.Anything).Return(storage.ErrNotFound).Once()
+
+	courseService := courseImpl{}
+
+	err := courseService.Delete(context.Background(), testCourse, 0)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	mongoMock.AssertExpectations(t)
+}
+
+func TestCourseDelete_ErrVersionMismatch(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	testCourse := types.Course{ID: "id02", Version: 2}
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(testCourse.ID)), mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(3).(*types.Course) = testCourse
+		}).Return(nil).Once()
+
+	courseService := courseImpl{}
+
+	err := courseService.Delete(context.Background(), testCourse.ID, 1)
+	assert.Equal(t, ErrVersionMismatch, err)
+
+	mongoMock.AssertExpectations(t)
+}
+
 func TestCourseDelete_ErrDelete(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	errMock := errors.New("err delete")
-	mongoMock.On("Remove", mock.Anything, coll, mock.Anything).Return(errMock).Once()
 	testCourse := "test02"
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(testCourse)), mock.Anything).Return(nil).Once()
+	mongoMock.On("Update", mock.Anything, coll, mock.Anything, mock.Anything).Return(errMock).Once()
 
 	courseService := courseImpl{}
 
-	err := courseService.Delete(testCourse)
+	err := courseService.Delete(context.Background(), testCourse, 0)
 	assert.Equal(t, err, errMock)
 
 	mongoMock.AssertExpectations(t)
@@ -277,16 +491,23 @@ func TestCourseDelete_ErrCache(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	redisMock := &redis.Mock{}
 	errMock := errors.New("err delete")
-	testCourse := "test02"
+	testCourse := types.Course{ID: "id02", Slug: "test02", Version: 3}
 	redisMock.Initialize(map[string]string{})
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
 
-	redisMock.On("Delete", coll+testCourse).Return(errMock).Once()
-	mongoMock.On("Remove", mock.Anything, coll, mock.Anything).Return(nil).Once()
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(testCourse.Slug)), mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(3).(*types.Course) = testCourse
+		}).Return(nil).Once()
+	redisMock.On("Set", "tag:"+coll, mock.Anything, time.Duration(0)).Return(nil).Once()
+	redisMock.On("Delete", coll+testCourse.ID).Return(errMock).Once()
+	redisMock.On("Delete", coll+testCourse.Slug).Return(nil).Once()
+	mongoMock.On("Update", mock.Anything, coll, map[string]interface{}{"_id": testCourse.ID, "version": int64(3)}, mock.Anything).Return(nil).Once()
 
 	courseService := courseImpl{}
 
-	err := courseService.Delete(testCourse)
+	err := courseService.Delete(context.Background(), testCourse.Slug, 0)
 	assert.Equal(t, err, errMock)
 
 	redisMock.AssertExpectations(t)
@@ -296,18 +517,81 @@ func TestCourseDelete_ErrCache(t *testing.T) {
 func TestCourseDelete_Success(t *testing.T) {
 	mongoMock := &storage.DataAccessLayerMock{}
 	redisMock := &redis.Mock{}
-	testCourse := "test02"
+	testCourse := types.Course{ID: "id02", Slug: "test02", Version: 3}
 	redisMock.Initialize(map[string]string{})
 	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
 
-	redisMock.On("Delete", coll+testCourse).Return(nil).Once()
-	mongoMock.On("Remove", mock.Anything, coll, mock.Anything).Return(nil).Once()
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(testCourse.ID)), mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(3).(*types.Course) = testCourse
+		}).Return(nil).Once()
+	redisMock.On("Set", "tag:"+coll, mock.Anything, time.Duration(0)).Return(nil).Once()
+	redisMock.On("Delete", coll+testCourse.ID).Return(nil).Once()
+	redisMock.On("Delete", coll+testCourse.Slug).Return(nil).Once()
+	mongoMock.On("Update", mock.Anything, coll, map[string]interface{}{"_id": testCourse.ID, "version": int64(3)}, mock.Anything).Return(nil).Once()
 
 	courseService := courseImpl{}
 
-	err := courseService.Delete(testCourse)
+	err := courseService.Delete(context.Background(), testCourse.ID, 3)
 	assert.Nil(t, err)
 
 	redisMock.AssertExpectations(t)
 	mongoMock.AssertExpectations(t)
 }
+
+//mockTagVersion mocks reading the version of the tag of cached course lists
+func mockTagVersion(redisMock *redis.Mock, v int64) {
+	redisMock.On("Get", "tag:"+coll, mock.Anything).Return(nil).
+		Run(func(args mock.Arguments) {
+			*args.Get(1).(*int64) = v
+		}).Once()
+}
+
+func TestCourseFindAll_NoTagVersion(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	redisMock := &redis.Mock{}
+	errMock := &redis.RedisErr{Msg: "down"}
+	redisMock.Initialize(map[string]string{})
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+
+	redisMock.On("Get", "tag:"+coll, mock.Anything).Return(errMock).Once()
+	mongoMock.On("Count", mock.Anything, coll, mock.Anything).Return(1, nil).Once()
+	mongoMock.On("Find", mock.Anything, coll, mock.Anything, mock.Anything, mock.AnythingOfType("*[]types.Course")).Return(nil).Once()
+
+	courseService := courseImpl{}
+
+	p, err := courseService.FindAll(ListOptions{})
+	assert.Equal(t, errMock, err)
+	assert.Equal(t, int64(1), p.Total)
+
+	redisMock.AssertExpectations(t)
+	mongoMock.AssertExpectations(t)
+}
+
+func TestCourseSlug_Collisions(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+	slugs := map[string]string{}
+	for _, name := range []string{"C++", "C#", "C", "Search", "trash"} {
+		c, err := s.Create(ctx, types.Course{Name: name})
+		assert.NoError(t, err)
+		slugs[name] = c.Slug
+	}
+	assert.Equal(t, map[string]string{"C++": "c", "C#": "c-2", "C": "c-3", "Search": "search-2", "trash": "trash-2"}, slugs)
+
+	c, err := s.Update(ctx, types.Course{ID: "c-2", Name: "C#", Description: "sharp"})
+	assert.NoError(t, err)
+	assert.Equal(t, "c-2", c.Slug)
+	assert.Equal(t, "sharp", c.Description)
+
+	c, err = s.Update(ctx, types.Course{ID: "c-2", Name: "C sharp"})
+	assert.NoError(t, err)
+	assert.Equal(t, "c-sharp", c.Slug)
+	c, err = s.Update(ctx, types.Course{ID: "c-3", Name: "C++ again"})
+	assert.NoError(t, err)
+	assert.Equal(t, "c-again", c.Slug)
+	_, err = s.Update(ctx, types.Course{ID: "c-2", Name: "other"})
+	assert.Equal(t, storage.ErrNotFound, err)
+}
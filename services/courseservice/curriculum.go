@@ -0,0 +1,300 @@
+package courseservice
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const moduleColl = "course_modules"
+
+//ErrInvalidOrder for reorderings not listing every module or lesson exactly once
+var ErrInvalidOrder = errors.New("order must list every module or lesson once")
+
+//Curriculum lists in order the modules of the course of the id or slug, courses in the trash are not found
+func (s courseImpl) Curriculum(ref string) (modules []types.Module, err error) {
+	err = cache.GetOrLoadTagged(coll+"modules"+ref, []string{coll}, &modules, time.Minute, func(v interface{}) error {
+		var c types.Course
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		if err := storage.GetInstance().FindOne(ctx, coll, live(byRef(ref)), &c); err != nil {
+			return err
+		}
+		ms, err := findModules(ctx, c.ID)
+		*v.(*[]types.Module) = ms
+		return err
+	})
+	return modules, err
+}
+
+//AddModule adds the module to the curriculum of the course of the id or slug at its order, last when it is 0
+func (s courseImpl) AddModule(ctx context.Context, ref string, module types.Module) (types.Module, error) {
+	module.ID = storage.NewID()
+	for i := range module.Lessons {
+		module.Lessons[i].ID = storage.NewID()
+	}
+	ms, err := editCurriculum(ctx, ref, func(ms []types.Module) ([]types.Module, error) {
+		return insertModule(ms, module), nil
+	})
+	return moduleOf(ms, module.ID), err
+}
+
+//UpdateModule changes the title of the module of the course of the id or slug and moves it to its order, when it
+//is not 0. Its lessons are changed by the lesson methods.
+func (s courseImpl) UpdateModule(ctx context.Context, ref string, module types.Module) (types.Module, error) {
+	ms, err := editCurriculum(ctx, ref, func(ms []types.Module) ([]types.Module, error) {
+		i := indexOfModule(ms, module.ID)
+		if i < 0 {
+			return nil, storage.ErrNotFound
+		}
+		m := ms[i]
+		m.Title = module.Title
+		if module.Order == 0 {
+			ms[i] = m
+			return ms, nil
+		}
+		m.Order = module.Order
+		return insertModule(append(ms[:i:i], ms[i+1:]...), m), nil
+	})
+	return moduleOf(ms, module.ID), err
+}
+
+//DeleteModule removes the module, and its lessons, from the curriculum of the course of the id or slug
+func (s courseImpl) DeleteModule(ctx context.Context, ref, moduleID string) error {
+	_, err := editCurriculum(ctx, ref, func(ms []types.Module) ([]types.Module, error) {
+		i := indexOfModule(ms, moduleID)
+		if i < 0 {
+			return nil, storage.ErrNotFound
+		}
+		return append(ms[:i:i], ms[i+1:]...), nil
+	})
+	return err
+}
+
+//ReorderModules sorts the modules of the course of the id or slug as the ids, which list every module once
+func (s courseImpl) ReorderModules(ctx context.Context, ref string, ids []string) ([]types.Module, error) {
+	return editCurriculum(ctx, ref, func(ms []types.Module) ([]types.Module, error) {
+		if len(ids) != len(ms) {
+			return nil, ErrInvalidOrder
+		}
+		sorted := make([]types.Module, 0, len(ms))
+		for _, id := range ids {
+			i := indexOfModule(ms, id)
+			if i < 0 || indexOfModule(sorted, id) >= 0 {
+				return nil, ErrInvalidOrder
+			}
+			sorted = append(sorted, ms[i])
+		}
+		return sorted, nil
+	})
+}
+
+//AddLesson adds the lesson to the module of the course of the id or slug at its order, last when it is 0
+func (s courseImpl) AddLesson(ctx context.Context, ref, moduleID string, lesson types.Lesson) (types.Lesson, error) {
+	lesson.ID = storage.NewID()
+	ms, err := editLessons(ctx, ref, moduleID, func(ls []types.Lesson) ([]types.Lesson, error) {
+		return insertLesson(ls, lesson), nil
+	})
+	return lessonOf(moduleOf(ms, moduleID), lesson.ID), err
+}
+
+//UpdateLesson replaces the lesson of the module of the course of the id or slug, keeping its id, and moves it to
+//its order when it is not 0
+func (s courseImpl) UpdateLesson(ctx context.Context, ref, moduleID string, lesson types.Lesson) (types.Lesson, error) {
+	ms, err := editLessons(ctx, ref, moduleID, func(ls []types.Lesson) ([]types.Lesson, error) {
+		i := indexOfLesson(ls, lesson.ID)
+		if i < 0 {
+			return nil, storage.ErrNotFound
+		}
+		if lesson.Order == 0 {
+			lesson.Order = i + 1
+		}
+		return insertLesson(append(ls[:i:i], ls[i+1:]...), lesson), nil
+	})
+	return lessonOf(moduleOf(ms, moduleID), lesson.ID), err
+}
+
+//DeleteLesson removes the lesson from the module of the course of the id or slug
+func (s courseImpl) DeleteLesson(ctx context.Context, ref, moduleID, lessonID string) error {
+	_, err := editLessons(ctx, ref, moduleID, func(ls []types.Lesson) ([]types.Lesson, error) {
+		i := indexOfLesson(ls, lessonID)
+		if i < 0 {
+			return nil, storage.ErrNotFound
+		}
+		return append(ls[:i:i], ls[i+1:]...), nil
+	})
+	return err
+}
+
+//ReorderLessons sorts the lessons of the module of the course of the id or slug as the ids, which list every lesson once
+func (s courseImpl) ReorderLessons(ctx context.Context, ref, moduleID string, ids []string) (types.Module, error) {
+	ms, err := editLessons(ctx, ref, moduleID, func(ls []types.Lesson) ([]types.Lesson, error) {
+		if len(ids) != len(ls) {
+			return nil, ErrInvalidOrder
+		}
+		sorted := make([]types.Lesson, 0, len(ls))
+		for _, id := range ids {
+			i := indexOfLesson(ls, id)
+			if i < 0 || indexOfLesson(sorted, id) >= 0 {
+				return nil, ErrInvalidOrder
+			}
+			sorted = append(sorted, ls[i])
+		}
+		return sorted, nil
+	})
+	return moduleOf(ms, moduleID), err
+}
+
+//editCurriculum replaces the modules of the course of the id or slug with the ones edit returns, in that order.
+//The modules changed and the duration of the course, as its next version, are stored in one transaction.
+func editCurriculum(ctx context.Context, ref string, edit func([]types.Module) ([]types.Module, error)) ([]types.Module, error) {
+	var old types.Course
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if err := storage.GetInstance().FindOne(ctx, coll, live(byRef(ref)), &old); err != nil {
+		return nil, err
+	}
+	if err := checkOwner(ctx, old); err != nil {
+		return nil, err
+	}
+	before, err := findModules(ctx, old.ID)
+	if err != nil {
+		return nil, err
+	}
+	after, err := edit(append([]types.Module{}, before...))
+	if err != nil {
+		return nil, err
+	}
+
+	course := old
+	course.Version, course.Duration = old.Version+1, 0
+	for i := range after {
+		after[i].CourseID, after[i].Order, after[i].Duration = old.ID, i+1, 0
+		after[i].Lessons = append([]types.Lesson{}, after[i].Lessons...)
+		for j := range after[i].Lessons {
+			after[i].Lessons[j].Order = j + 1
+			after[i].Duration += after[i].Lessons[j].Duration
+		}
+		course.Duration += after[i].Duration
+	}
+
+	err = audited(ctx, ActionCurriculum, &old, &course, func(ctx context.Context) error {
+		if err := writeModules(ctx, before, after); err != nil {
+			return err
+		}
+		return storage.GetInstance().Update(ctx, coll, versioned(old), map[string]interface{}{
+			"$set": map[string]interface{}{"duration": course.Duration, "version": course.Version},
+		})
+	})
+	if err == nil {
+		return after, invalidate(old)
+	}
+	if err == storage.ErrNotFound {
+		return nil, ErrVersionMismatch
+	}
+	return nil, err
+}
+
+//editLessons replaces the lessons of the module with the ones edit returns, in that order
+func editLessons(ctx context.Context, ref, moduleID string, edit func([]types.Lesson) ([]types.Lesson, error)) ([]types.Module, error) {
+	return editCurriculum(ctx, ref, func(ms []types.Module) ([]types.Module, error) {
+		i := indexOfModule(ms, moduleID)
+		if i < 0 {
+			return nil, storage.ErrNotFound
+		}
+		ls, err := edit(append([]types.Lesson{}, ms[i].Lessons...))
+		ms[i].Lessons = ls
+		return ms, err
+	})
+}
+
+//writeModules inserts, updates and removes the modules which are new, changed or gone from before to after
+func writeModules(ctx context.Context, before, after []types.Module) error {
+	for _, m := range after {
+		i := indexOfModule(before, m.ID)
+		if i < 0 {
+			if err := storage.GetInstance().Insert(ctx, moduleColl, m); err != nil {
+				return err
+			}
+		} else if !reflect.DeepEqual(before[i], m) {
+			if err := storage.GetInstance().Update(ctx, moduleColl, map[string]interface{}{"_id": m.ID}, map[string]interface{}{"$set": m}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, m := range before {
+		if indexOfModule(after, m.ID) < 0 {
+			if err := storage.GetInstance().Remove(ctx, moduleColl, map[string]interface{}{"_id": m.ID}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func findModules(ctx context.Context, courseID string) ([]types.Module, error) {
+	ms := []types.Module{}
+	query := map[string]interface{}{"courseId": courseID}
+	err := storage.GetInstance().Find(ctx, moduleColl, query, storage.FindOptions{Sort: []string{"order"}}, &ms)
+	for i := range ms {
+		if ms[i].Lessons == nil {
+			ms[i].Lessons = []types.Lesson{}
+		}
+	}
+	return ms, err
+}
+
+//insertModule inserts the module at its order, last when it is out of the modules
+func insertModule(ms []types.Module, m types.Module) []types.Module {
+	i := m.Order - 1
+	if i < 0 || i > len(ms) {
+		i = len(ms)
+	}
+	return append(ms[:i:i], append([]types.Module{m}, ms[i:]...)...)
+}
+
+//insertLesson inserts the lesson at its order, last when it is out of the lessons
+func insertLesson(ls []types.Lesson, l types.Lesson) []types.Lesson {
+	i := l.Order - 1
+	if i < 0 || i > len(ls) {
+		i = len(ls)
+	}
+	return append(ls[:i:i], append([]types.Lesson{l}, ls[i:]...)...)
+}
+
+func indexOfModule(ms []types.Module, id string) int {
+	for i, m := range ms {
+		if m.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexOfLesson(ls []types.Lesson, id string) int {
+	for i, l := range ls {
+		if l.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func moduleOf(ms []types.Module, id string) types.Module {
+	if i := indexOfModule(ms, id); i >= 0 {
+		return ms[i]
+	}
+	return types.Module{}
+}
+
+func lessonOf(m types.Module, id string) types.Lesson {
+	if i := indexOfLesson(m.Lessons, id); i >= 0 {
+		return m.Lessons[i]
+	}
+	return types.Lesson{}
+}
@@ -0,0 +1,132 @@
+package courseservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCourseCurriculum(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+	c, err := s.Create(ctx, types.Course{Name: "Go"})
+	assert.NoError(t, err)
+
+	intro, err := s.AddModule(ctx, c.Slug, types.Module{Title: "Intro", Lessons: []types.Lesson{
+		{Title: "Welcome", Duration: 60, ContentType: types.ContentVideo, FreePreview: true},
+	}})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, intro.ID)
+	assert.Equal(t, c.ID, intro.CourseID)
+	assert.Equal(t, 1, intro.Order)
+	assert.Equal(t, int64(60), intro.Duration)
+	assert.NotEmpty(t, intro.Lessons[0].ID)
+
+	basics, err := s.AddModule(ctx, c.ID, types.Module{Title: "Basics"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, basics.Order)
+	setup, err := s.AddModule(ctx, c.ID, types.Module{Title: "Setup", Order: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, setup.Order)
+
+	vars, err := s.AddLesson(ctx, c.ID, basics.ID, types.Lesson{Title: "Variables", Duration: 300, ContentType: types.ContentVideo})
+	assert.NoError(t, err)
+	quiz, err := s.AddLesson(ctx, c.ID, basics.ID, types.Lesson{Title: "Quiz", Duration: 120, ContentType: types.ContentQuiz, Order: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, quiz.Order)
+
+	ms, err := s.Curriculum(c.Slug)
+	assert.NoError(t, err)
+	if assert.Len(t, ms, 3) {
+		assert.Equal(t, []string{"Intro", "Setup", "Basics"}, []string{ms[0].Title, ms[1].Title, ms[2].Title})
+		assert.Equal(t, []types.Lesson{
+			{ID: quiz.ID, Title: "Quiz", Order: 1, Duration: 120, ContentType: types.ContentQuiz},
+			{ID: vars.ID, Title: "Variables", Order: 2, Duration: 300, ContentType: types.ContentVideo},
+		}, ms[2].Lessons)
+		assert.Equal(t, int64(420), ms[2].Duration)
+		assert.Equal(t, []types.Lesson{}, ms[1].Lessons)
+	}
+	c, err = s.FindOne(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(480), c.Duration)
+	assert.Equal(t, int64(6), c.Version)
+
+	vars.Duration, vars.Order = 30, 1
+	vars, err = s.UpdateLesson(ctx, c.ID, basics.ID, vars)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, vars.Order)
+	basics, err = s.ReorderLessons(ctx, c.ID, basics.ID, []string{quiz.ID, vars.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{quiz.ID, vars.ID}, []string{basics.Lessons[0].ID, basics.Lessons[1].ID})
+	assert.NoError(t, s.DeleteLesson(ctx, c.ID, basics.ID, quiz.ID))
+
+	basics, err = s.UpdateModule(ctx, c.ID, types.Module{ID: basics.ID, Title: "Go basics", Order: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, basics.Order)
+	assert.Len(t, basics.Lessons, 1)
+	ms, err = s.ReorderModules(ctx, c.ID, []string{intro.ID, basics.ID, setup.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, []int{ms[0].Order, ms[1].Order, ms[2].Order})
+	assert.NoError(t, s.DeleteModule(ctx, c.ID, intro.ID))
+
+	ms, err = s.Curriculum(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Go basics", "Setup"}, []string{ms[0].Title, ms[1].Title})
+	c, err = s.FindOne(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(30), c.Duration)
+
+	c.Name = "Go 2"
+	c, err = s.Update(ctx, c)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(30), c.Duration)
+}
+
+func TestCourseCurriculum_Errors(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+	c, err := s.Create(ctx, types.Course{Name: "Go"})
+	assert.NoError(t, err)
+	m, err := s.AddModule(ctx, c.ID, types.Module{Title: "Intro", Lessons: []types.Lesson{{Title: "Welcome", ContentType: types.ContentVideo}}})
+	assert.NoError(t, err)
+
+	_, err = s.Curriculum("missing")
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.AddModule(ctx, "missing", types.Module{Title: "Intro"})
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.UpdateModule(ctx, c.ID, types.Module{ID: "missing", Title: "Intro"})
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, s.DeleteModule(ctx, c.ID, "missing"))
+	_, err = s.AddLesson(ctx, c.ID, "missing", types.Lesson{Title: "Welcome"})
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, s.DeleteLesson(ctx, c.ID, m.ID, "missing"))
+
+	_, err = s.ReorderModules(ctx, c.ID, []string{m.ID, m.ID})
+	assert.Equal(t, ErrInvalidOrder, err)
+	_, err = s.ReorderLessons(ctx, c.ID, m.ID, []string{"missing"})
+	assert.Equal(t, ErrInvalidOrder, err)
+
+	c, err = s.FindOne(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), c.Version)
+}
+
+func TestCoursePurge_RemovesCurriculum(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+	c, err := s.Create(ctx, types.Course{Name: "Go"})
+	assert.NoError(t, err)
+	_, err = s.AddModule(ctx, c.ID, types.Module{Title: "Intro"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Delete(ctx, c.ID, 0))
+	_, err = s.Purge(ctx, time.Now().Add(time.Second))
+	assert.NoError(t, err)
+
+	ms, err := findModules(ctx, c.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, ms)
+}
@@ -0,0 +1,40 @@
+package courseservice
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ednesic/coursemanagement/types"
+)
+
+//Hook checks or follows a change of a course in the transaction storing it, the change is not stored when it fails.
+//Before is nil for created courses and after for purged ones. The cache tags it returns are invalidated once the
+//change is stored.
+type Hook func(ctx context.Context, action string, before, after *types.Course) ([]string, error)
+
+var (
+	hooks   []Hook
+	hooksMu sync.RWMutex
+)
+
+//AddHook runs the hook in every change of a course from now on, after the hooks added before it
+func AddHook(h Hook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, h)
+}
+
+//runHooks runs the hooks in order until one fails, returning the tags of all of them
+func runHooks(ctx context.Context, action string, before, after *types.Course) ([]string, error) {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	var tags []string
+	for _, h := range hooks {
+		ts, err := h(ctx, action, before, after)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, ts...)
+	}
+	return tags, nil
+}
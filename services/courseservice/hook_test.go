@@ -0,0 +1,36 @@
+package courseservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+	defer func(saved []Hook) { hooks = saved }(hooks)
+	errHook := errors.New("hook")
+	var actions []string
+	AddHook(func(ctx context.Context, action string, before, after *types.Course) ([]string, error) {
+		actions = append(actions, action)
+		if after != nil && after.Capacity > 10 {
+			return nil, errHook
+		}
+		return nil, nil
+	})
+
+	c, err := s.Create(ctx, types.Course{Name: "Go", Capacity: 5})
+	assert.NoError(t, err)
+	c.Capacity = 20
+	_, err = s.Update(ctx, c)
+	assert.Equal(t, errHook, err)
+	found, err := s.FindOne(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), found.Capacity)
+	assert.NoError(t, s.Delete(ctx, c.ID, 0))
+	assert.Equal(t, []string{ActionCreate, ActionUpdate, ActionDelete}, actions)
+}
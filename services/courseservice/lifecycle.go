@@ -0,0 +1,124 @@
+package courseservice
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+//ErrInvalidTransition for status changes the lifecycle of courses does not allow
+var ErrInvalidTransition = errors.New("invalid course status transition")
+
+//transitions are the statuses each status is allowed to move to
+var transitions = map[string][]string{
+	types.StatusDraft:     {types.StatusInReview, types.StatusArchived},
+	types.StatusInReview:  {types.StatusDraft, types.StatusPublished, types.StatusArchived},
+	types.StatusPublished: {types.StatusDraft, types.StatusArchived},
+	types.StatusArchived:  {types.StatusDraft},
+}
+
+//Transition moves the course of the id or slug to the status when it is at the version, any when it is 0.
+//When at is in the future the move is only scheduled, RunScheduled makes it then. A transition replaces the
+//one scheduled before.
+func (s courseImpl) Transition(ctx context.Context, ref, status string, at time.Time, version int64) (types.Course, error) {
+	var old types.Course
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if err := storage.GetInstance().FindOne(ctx, coll, live(byRef(ref)), &old); err != nil {
+		return old, err
+	}
+	if err := checkOwner(ctx, old); err != nil {
+		return old, err
+	}
+	if err := checkVersion(old, version); err != nil {
+		return old, err
+	}
+	if !allowed(statusOf(old), status) {
+		return old, ErrInvalidTransition
+	}
+
+	course, action := old, ActionTransition
+	course.Scheduled = nil
+	if at.After(time.Now()) {
+		course.Scheduled, action = &types.ScheduledTransition{Status: status, At: at.UTC()}, ActionSchedule
+	} else {
+		course.Status = status
+	}
+	return transition(ctx, action, old, course)
+}
+
+//RunScheduled makes the transitions scheduled up to the time and returns how many courses changed. Scheduled
+//transitions the lifecycle does not allow anymore are dropped.
+func (s courseImpl) RunScheduled(ctx context.Context, now time.Time) (int64, error) {
+	var cs []types.Course
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	query := live(map[string]interface{}{"scheduled.at": map[string]interface{}{"$lte": now}})
+	if err := storage.GetInstance().Find(ctx, coll, query, storage.FindOptions{}, &cs); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, old := range cs {
+		course := old
+		course.Scheduled = nil
+		if allowed(statusOf(old), old.Scheduled.Status) {
+			course.Status = old.Scheduled.Status
+		}
+		_, err := transition(ctx, ActionTransition, old, course)
+		if err == ErrVersionMismatch {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		if course.Status != statusOf(old) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+//transition stores the status and the scheduled transition of the course in place of the ones of old as its next version
+func transition(ctx context.Context, action string, old, course types.Course) (types.Course, error) {
+	course.Version = old.Version + 1
+	update := map[string]interface{}{
+		"$set": map[string]interface{}{"status": course.Status, "version": course.Version, "scheduled": course.Scheduled},
+	}
+	if course.Scheduled == nil {
+		update = map[string]interface{}{
+			"$set":   map[string]interface{}{"status": course.Status, "version": course.Version},
+			"$unset": map[string]interface{}{"scheduled": ""},
+		}
+	}
+	err := audited(ctx, action, &old, &course, func(ctx context.Context) error {
+		return storage.GetInstance().Update(ctx, coll, versioned(old), update)
+	})
+	if err == nil {
+		return course, invalidate(old)
+	}
+	if err == storage.ErrNotFound {
+		return old, ErrVersionMismatch
+	}
+	return old, err
+}
+
+func allowed(from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+//statusOf is the status of the course, published for the ones stored before there was a lifecycle
+func statusOf(c types.Course) string {
+	if c.Status == "" {
+		return types.StatusPublished
+	}
+	return c.Status
+}
@@ -0,0 +1,143 @@
+package courseservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCourseTransition(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := actor.NewContext(context.Background(), "ana")
+	c, err := s.Create(ctx, types.Course{Name: "Go", Status: types.StatusPublished})
+	assert.NoError(t, err)
+	assert.Equal(t, types.StatusDraft, c.Status)
+	assert.Equal(t, "ana", c.Author)
+
+	_, err = s.Transition(ctx, c.Slug, types.StatusPublished, time.Time{}, 0)
+	assert.Equal(t, ErrInvalidTransition, err)
+
+	c, err = s.Transition(ctx, c.Slug, types.StatusInReview, time.Time{}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, types.StatusInReview, c.Status)
+	assert.Equal(t, int64(2), c.Version)
+
+	_, err = s.Transition(ctx, c.Slug, types.StatusPublished, time.Time{}, 1)
+	assert.Equal(t, ErrVersionMismatch, err)
+	c, err = s.Transition(ctx, c.Slug, types.StatusPublished, time.Now().Add(-time.Minute), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, types.StatusPublished, c.Status)
+
+	c.Name, c.Status = "Go 2", types.StatusArchived
+	c, err = s.Update(ctx, c)
+	assert.NoError(t, err)
+	assert.Equal(t, types.StatusPublished, c.Status)
+	assert.Equal(t, "ana", c.Author)
+
+	entries, err := s.History(c.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 4) {
+		assert.Equal(t, ActionTransition, entries[1].Action)
+	}
+	_, err = s.Transition(ctx, "missing", types.StatusDraft, time.Time{}, 0)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestCourseTransition_Scheduled(t *testing.T) {
+	s := newMemoryCourses(t)
+	c, err := s.Create(context.Background(), types.Course{Name: "Go"})
+	assert.NoError(t, err)
+	_, err = s.Transition(context.Background(), c.ID, types.StatusInReview, time.Time{}, 0)
+	assert.NoError(t, err)
+
+	at := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+	c, err = s.Transition(context.Background(), c.ID, types.StatusPublished, at, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, types.StatusInReview, c.Status)
+	assert.Equal(t, &types.ScheduledTransition{Status: types.StatusPublished, At: at}, c.Scheduled)
+
+	n, err := s.RunScheduled(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	n, err = s.RunScheduled(actor.NewContext(context.Background(), actor.System), at)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	c, err = s.FindOne(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.StatusPublished, c.Status)
+	assert.Nil(t, c.Scheduled)
+
+	entries, err := s.History(c.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 4) {
+		assert.Equal(t, ActionSchedule, entries[2].Action)
+		assert.Equal(t, ActionTransition, entries[3].Action)
+		assert.Equal(t, actor.System, entries[3].Actor)
+	}
+}
+
+func TestCourseRunScheduled_DropsInvalidTransitions(t *testing.T) {
+	s := newMemoryCourses(t)
+	c, err := s.Create(context.Background(), types.Course{Name: "Go"})
+	assert.NoError(t, err)
+	_, err = s.Transition(context.Background(), c.ID, types.StatusInReview, time.Time{}, 0)
+	assert.NoError(t, err)
+	_, err = s.Transition(context.Background(), c.ID, types.StatusPublished, time.Now().Add(time.Hour), 0)
+	assert.NoError(t, err)
+	_, err = s.Transition(context.Background(), c.ID, types.StatusArchived, time.Time{}, 0)
+	assert.NoError(t, err)
+
+	c, err = s.FindOne(c.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, c.Scheduled)
+
+	assert.NoError(t, storage.GetInstance().Update(context.Background(), coll, map[string]interface{}{"_id": c.ID}, map[string]interface{}{
+		"$set": map[string]interface{}{"scheduled": types.ScheduledTransition{Status: types.StatusPublished, At: time.Now()}},
+	}))
+	n, err := s.RunScheduled(context.Background(), time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+	c, err = s.FindOne(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.StatusArchived, c.Status)
+	assert.Nil(t, c.Scheduled)
+}
+
+func TestCourseFindAll_Lifecycle(t *testing.T) {
+	s := newMemoryCourses(t)
+	ana := actor.NewContext(context.Background(), "ana")
+	draft, err := s.Create(ana, types.Course{Name: "Draft"})
+	assert.NoError(t, err)
+	_, err = s.Create(actor.NewContext(context.Background(), "bob"), types.Course{Name: "Other draft"})
+	assert.NoError(t, err)
+	published, err := s.Create(ana, types.Course{Name: "Published"})
+	assert.NoError(t, err)
+	_, err = s.Transition(ana, published.ID, types.StatusInReview, time.Time{}, 0)
+	assert.NoError(t, err)
+	_, err = s.Transition(ana, published.ID, types.StatusPublished, time.Time{}, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, storage.GetInstance().Insert(context.Background(), coll, types.Course{ID: "legacy", Name: "Legacy"}))
+
+	p, err := s.FindAll(ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), p.Total)
+	assert.Equal(t, []string{"Legacy", "Published"}, names(p.Courses))
+
+	p, err = s.FindAll(ListOptions{Limit: 10, Status: types.StatusDraft, Author: "ana"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{draft.Name}, names(p.Courses))
+}
+
+func names(cs []types.Course) []string {
+	ns := []string{}
+	for _, c := range cs {
+		ns = append(ns, c.Name)
+	}
+	return ns
+}
@@ -0,0 +1,165 @@
+package courseservice
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+//SortFields are the course fields FindAll is able to sort on
+var SortFields = map[string]bool{"name": true, "price": true}
+
+//sortPaths are the stored fields of the sort fields which are not stored as they are named
+var sortPaths = map[string]string{"price": "price.amount"}
+
+//ListOptions is the pagination, sorting and filtering applied by FindAll.
+//Sort is one of SortFields, prefixed with "-" for descending order. Deleted lists the courses in the trash instead.
+//MinPrice and MaxPrice are minor units of Currency, the default currency when it is empty, and restrict the list to
+//the courses priced in it.
+//Only published courses are listed unless Status is another one, courses in the trash are listed whatever their
+//status. Author restricts the list to the courses of an author and Instructor to the ones of an instructor.
+//Category restricts it to a category and its descendants, Tags to the courses having all of them. Facets counts
+//the courses of each category and tag.
+type ListOptions struct {
+	Offset     int64
+	Limit      int64
+	Sort       string
+	MinPrice   *int64
+	MaxPrice   *int64
+	Currency   string
+	Deleted    bool
+	Status     string
+	Author     string
+	Instructor string
+	Category   string
+	Tags       []string
+	Facets     bool
+
+	//categories is the subtree of Category
+	categories []string
+}
+
+//Page is a slice of courses and the total of courses matching the filters. Facets are the counts of the courses by
+//category and tag when the options ask for them, each ignoring the filter of its own field.
+type Page struct {
+	Courses []types.Course
+	Total   int64
+	Facets  map[string][]storage.FacetCount `json:",omitempty"`
+}
+
+func (o ListOptions) query() map[string]interface{} {
+	query := map[string]interface{}{"deletedAt": nil}
+	if o.Deleted {
+		query["deletedAt"] = map[string]interface{}{"$ne": nil}
+	}
+	if o.Status == types.StatusPublished || o.Status == "" && !o.Deleted {
+		query["status"] = map[string]interface{}{"$in": []interface{}{types.StatusPublished, "", nil}}
+	} else if o.Status != "" {
+		query["status"] = o.Status
+	}
+	if o.Author != "" {
+		query["author"] = o.Author
+	}
+	if o.Instructor != "" {
+		query["instructors"] = o.Instructor
+	}
+	if o.Category != "" {
+		query["category"] = map[string]interface{}{"$in": o.categories}
+	}
+	if len(o.Tags) > 0 {
+		query["tags"] = map[string]interface{}{"$all": o.Tags}
+	}
+	price := map[string]interface{}{}
+	if o.MinPrice != nil {
+		price["$gte"] = *o.MinPrice
+	}
+	if o.MaxPrice != nil {
+		price["$lte"] = *o.MaxPrice
+	}
+	if len(price) > 0 {
+		query["price.currency"] = o.currency()
+		query["price.amount"] = price
+	}
+	return query
+}
+
+//currency is the currency of the price filters
+func (o ListOptions) currency() string {
+	if o.Currency == "" {
+		return types.DefaultCurrency
+	}
+	return o.Currency
+}
+
+func (o ListOptions) findOptions() storage.FindOptions {
+	sort := []string{}
+	if o.Sort != "" {
+		field := strings.TrimPrefix(o.Sort, "-")
+		if path, ok := sortPaths[field]; ok {
+			field = path
+		}
+		if strings.HasPrefix(o.Sort, "-") {
+			field = "-" + field
+		}
+		sort = append(sort, field)
+	}
+	if o.Sort != "name" && o.Sort != "-name" {
+		sort = append(sort, "name")
+	}
+	return storage.FindOptions{Skip: o.Offset, Limit: o.Limit, Sort: sort}
+}
+
+func (o ListOptions) cacheKey() string {
+	v := o.values()
+	if len(v) == 0 {
+		return ""
+	}
+	return "?" + v.Encode()
+}
+
+func (o ListOptions) values() url.Values {
+	v := url.Values{}
+	if o.Offset > 0 {
+		v.Set("offset", strconv.FormatInt(o.Offset, 10))
+	}
+	if o.Limit > 0 {
+		v.Set("limit", strconv.FormatInt(o.Limit, 10))
+	}
+	if o.Sort != "" {
+		v.Set("sort", o.Sort)
+	}
+	if o.MinPrice != nil {
+		v.Set("minPrice", strconv.FormatInt(*o.MinPrice, 10))
+	}
+	if o.MaxPrice != nil {
+		v.Set("maxPrice", strconv.FormatInt(*o.MaxPrice, 10))
+	}
+	if o.MinPrice != nil || o.MaxPrice != nil {
+		v.Set("currency", o.currency())
+	}
+	if o.Deleted {
+		v.Set("deleted", "true")
+	}
+	if o.Status != "" {
+		v.Set("status", o.Status)
+	}
+	if o.Author != "" {
+		v.Set("author", o.Author)
+	}
+	if o.Instructor != "" {
+		v.Set("instructor", o.Instructor)
+	}
+	if o.Category != "" {
+		v.Set("category", o.Category)
+	}
+	for _, t := range o.Tags {
+		v.Add("tag", t)
+	}
+	if o.Facets {
+		v.Set("facets", "true")
+	}
+	return v
+}
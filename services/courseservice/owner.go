@@ -0,0 +1,92 @@
+package courseservice
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/instructorservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+var (
+	//ErrNotOwner for changes to a course made by someone who is neither its author nor one of its instructors
+	ErrNotOwner = errors.New("only the author and the instructors of the course change it")
+	//ErrUnknownInstructor for courses linked to an instructor that does not exist
+	ErrUnknownInstructor = errors.New("unknown instructor")
+)
+
+//checkOwner fails when the actor of the context does not own the course. Courses are owned by their author and the
+//users of their instructors, courses without any are owned by everyone. Admins and the service itself change every
+//course, requests without an actor only the ones owned by everyone.
+func checkOwner(ctx context.Context, c types.Course) error {
+	a := actor.FromContext(ctx)
+	if c.Author == "" && len(c.Instructors) == 0 || actor.HasRole(ctx, actor.Admin) {
+		return nil
+	}
+	if a == actor.Anonymous {
+		return ErrNotOwner
+	}
+	if a == c.Author {
+		return nil
+	}
+	i, err := instructorservice.GetInstance().FindByUser(a)
+	if err == storage.ErrNotFound {
+		return ErrNotOwner
+	}
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return err
+	}
+	for _, id := range c.Instructors {
+		if id == i.ID {
+			return nil
+		}
+	}
+	return ErrNotOwner
+}
+
+//checkInstructors fails when the course is linked to instructors that do not exist, the ones old is linked to are
+//not checked again
+func checkInstructors(old, course types.Course) error {
+	for _, id := range course.Instructors {
+		if contains(old.Instructors, id) {
+			continue
+		}
+		_, err := instructorservice.GetInstance().FindOne(id)
+		if err == storage.ErrNotFound {
+			return ErrUnknownInstructor
+		}
+		if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//ownInstructor links the course to the instructor the actor of the context is, when the course has no instructors
+func ownInstructor(ctx context.Context, course *types.Course) error {
+	a := actor.FromContext(ctx)
+	if len(course.Instructors) > 0 || a == actor.Anonymous || a == actor.System {
+		return nil
+	}
+	i, err := instructorservice.GetInstance().FindByUser(a)
+	if err == storage.ErrNotFound {
+		return nil
+	}
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return err
+	}
+	course.Instructors = []string{i.ID}
+	return nil
+}
+
+func contains(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package courseservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/services/instructorservice"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCourseOwnership(t *testing.T) {
+	s := newMemoryCourses(t)
+	ana := actor.NewContext(context.Background(), "ana")
+	bob := actor.NewContext(context.Background(), "bob")
+	eve := actor.NewContext(context.Background(), "eve")
+	i, err := instructorservice.GetInstance().Create(bob, types.Instructor{User: "bob", Name: "Bob"})
+	assert.NoError(t, err)
+	_, err = instructorservice.GetInstance().Create(eve, types.Instructor{User: "eve", Name: "Eve"})
+	assert.NoError(t, err)
+
+	c, err := s.Create(ana, types.Course{Name: "Go", Instructors: []string{i.ID}})
+	assert.NoError(t, err)
+
+	c.Price = testutil.USD(10)
+	c, err = s.Update(bob, c)
+	assert.NoError(t, err)
+	c.Price = testutil.USD(20)
+	_, err = s.Update(eve, c)
+	assert.Equal(t, ErrNotOwner, err)
+	_, err = s.Update(actor.NewContext(context.Background(), "mallory"), c)
+	assert.Equal(t, ErrNotOwner, err)
+	_, err = s.Patch(eve, c.ID, 0, MergePatch, []byte(`{"price":30}`), func(interface{}) error { return nil })
+	assert.Equal(t, ErrNotOwner, err)
+	_, err = s.Transition(eve, c.ID, types.StatusInReview, time.Time{}, 0)
+	assert.Equal(t, ErrNotOwner, err)
+	_, err = s.AddModule(eve, c.ID, types.Module{Title: "Intro"})
+	assert.Equal(t, ErrNotOwner, err)
+	_, err = s.Revert(eve, c.ID, 1, 0)
+	assert.Equal(t, ErrNotOwner, err)
+	assert.Equal(t, ErrNotOwner, s.Delete(eve, c.ID, 0))
+
+	assert.NoError(t, s.Delete(ana, c.ID, 0))
+	_, err = s.Restore(eve, c.ID)
+	assert.Equal(t, ErrNotOwner, err)
+	_, err = s.Restore(context.Background(), c.ID)
+	assert.Equal(t, ErrNotOwner, err)
+	_, err = s.Restore(actor.WithRoles(eve, actor.Admin), c.ID)
+	assert.NoError(t, err)
+
+	_, err = s.Update(context.Background(), c)
+	assert.Equal(t, ErrNotOwner, err)
+	assert.Equal(t, ErrNotOwner, s.Delete(context.Background(), c.ID, 0))
+	_, err = s.AddModule(context.Background(), c.ID, types.Module{Title: "Intro"})
+	assert.Equal(t, ErrNotOwner, err)
+	_, err = s.Transition(actor.NewContext(context.Background(), actor.System), c.ID, types.StatusInReview, time.Time{}, 0)
+	assert.NoError(t, err)
+}
+
+func TestCourseInstructors(t *testing.T) {
+	s := newMemoryCourses(t)
+	bob := actor.NewContext(context.Background(), "bob")
+	i, err := instructorservice.GetInstance().Create(bob, types.Instructor{User: "bob", Name: "Bob"})
+	assert.NoError(t, err)
+
+	_, err = s.Create(bob, types.Course{Name: "Rust", Instructors: []string{"missing"}})
+	assert.Equal(t, ErrUnknownInstructor, err)
+	c, err := s.Create(bob, types.Course{Name: "Go"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{i.ID}, c.Instructors)
+
+	c.Instructors = append(c.Instructors, "missing")
+	_, err = s.Update(bob, c)
+	assert.Equal(t, ErrUnknownInstructor, err)
+
+	_, err = s.Transition(bob, c.ID, types.StatusInReview, time.Time{}, 0)
+	assert.NoError(t, err)
+	_, err = s.Transition(bob, c.ID, types.StatusPublished, time.Time{}, 0)
+	assert.NoError(t, err)
+	_, err = s.Create(context.Background(), types.Course{Name: "Anonymous"})
+	assert.NoError(t, err)
+
+	p, err := s.FindAll(ListOptions{Limit: 10, Instructor: i.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Go"}, names(p.Courses))
+}
@@ -0,0 +1,79 @@
+package courseservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	jsonpatch "github.com/evanphx/json-patch"
+)
+
+const (
+	//MergePatch is the media type of a JSON merge patch (RFC 7396)
+	MergePatch = "application/merge-patch+json"
+	//JSONPatch is the media type of a JSON patch (RFC 6902)
+	JSONPatch = "application/json-patch+json"
+)
+
+var (
+	//ErrInvalidPatch for patches that cannot be read or applied to the course
+	ErrInvalidPatch = errors.New("invalid patch")
+	//ErrUnsupportedPatch for patches of a media type other than MergePatch and JSONPatch
+	ErrUnsupportedPatch = errors.New("unsupported patch media type")
+)
+
+//Patch applies the patch of the media type patchType to the json of the course of the id or slug ref, when it
+//is at the version (any when it is 0). The patched course is stored as the next version when validate accepts it,
+//its id cannot be changed and its slug follows its name.
+func (s courseImpl) Patch(ctx context.Context, ref string, version int64, patchType string, patch []byte, validate func(interface{}) error) (types.Course, error) {
+	var old types.Course
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if err := storage.GetInstance().FindOne(ctx, coll, live(byRef(ref)), &old); err != nil {
+		return old, err
+	}
+	if err := checkOwner(ctx, old); err != nil {
+		return old, err
+	}
+	if err := checkVersion(old, version); err != nil {
+		return old, err
+	}
+	course, err := applyPatch(old, patchType, patch)
+	if err != nil {
+		return old, err
+	}
+	if err = validate(&course); err != nil {
+		return old, err
+	}
+	return replace(ctx, ActionUpdate, old, course)
+}
+
+func applyPatch(course types.Course, patchType string, patch []byte) (types.Course, error) {
+	doc, err := json.Marshal(course)
+	if err != nil {
+		return course, err
+	}
+	switch patchType {
+	case MergePatch:
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	case JSONPatch:
+		var p jsonpatch.Patch
+		if p, err = jsonpatch.DecodePatch(patch); err == nil {
+			doc, err = p.Apply(doc)
+		}
+	default:
+		return course, ErrUnsupportedPatch
+	}
+	if err != nil {
+		return course, ErrInvalidPatch
+	}
+
+	var patched types.Course
+	if err = json.Unmarshal(doc, &patched); err != nil {
+		return course, ErrInvalidPatch
+	}
+	return patched, nil
+}
@@ -0,0 +1,115 @@
+package courseservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var patchedCourse = types.Course{ID: "id01", Slug: "go-basics", Version: 4, Name: "Go basics", Price: testutil.USD(10), Picture: "http://cdn/go.png"}
+
+func noValidation(interface{}) error { return nil }
+
+func mockPatchedCourse(ref string) *storage.DataAccessLayerMock {
+	mongoMock := &storage.DataAccessLayerMock{}
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+	lc := cache.NewLRU()
+	lc.Initialize(map[string]string{})
+	cache.SetInstance(lc)
+
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef(ref)), mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(3).(*types.Course) = patchedCourse
+		}).Return(nil).Once()
+	return mongoMock
+}
+
+func TestCoursePatch_Success(t *testing.T) {
+	tests := []struct {
+		name      string
+		patchType string
+		patch     string
+		want      types.Course
+	}{
+		{"merge patch clears a field and frees the course", MergePatch, `{"price": {"amount": 0}, "picture": null}`,
+			types.Course{ID: "id01", Slug: "go-basics", Version: 5, Name: "Go basics", Price: testutil.USD(0)}},
+		{"merge patch renames but keeps the id and the version", MergePatch, `{"name": "Go advanced", "id": "other", "version": 9}`,
+			types.Course{ID: "id01", Slug: "go-advanced", Version: 5, Name: "Go advanced", Price: testutil.USD(10), Picture: "http://cdn/go.png"}},
+		{"json patch", JSONPatch, `[{"op": "test", "path": "/price/amount", "value": 10}, {"op": "replace", "path": "/price/amount", "value": 0}, {"op": "replace", "path": "/description", "value": "intro"}]`,
+			types.Course{ID: "id01", Slug: "go-basics", Version: 5, Name: "Go basics", Price: testutil.USD(0), Picture: "http://cdn/go.png", Description: "intro"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoMock := mockPatchedCourse(patchedCourse.Slug)
+			assert.NoError(t, cache.Put(coll+patchedCourse.ID, patchedCourse, time.Minute))
+			assert.NoError(t, cache.Put(coll+patchedCourse.Slug, patchedCourse, time.Minute))
+			if tt.want.Slug != patchedCourse.Slug {
+				mongoMock.On("Count", mock.Anything, coll, live(map[string]interface{}{
+					"slug": tt.want.Slug,
+					"_id":  map[string]interface{}{"$ne": patchedCourse.ID},
+				})).Return(0, nil).Once()
+			}
+			mongoMock.On("Update", mock.Anything, coll, map[string]interface{}{"_id": patchedCourse.ID, "version": int64(4)}, map[string]interface{}{"$set": &tt.want}).
+				Return(nil).Once()
+
+			courseService := courseImpl{}
+
+			c, err := courseService.Patch(context.Background(), patchedCourse.Slug, 0, tt.patchType, []byte(tt.patch), noValidation)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, c)
+			mongoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCoursePatch_Err(t *testing.T) {
+	errValidation := errors.New("invalid")
+	tests := []struct {
+		name      string
+		patchType string
+		patch     string
+		validate  func(interface{}) error
+		err       error
+	}{
+		{"unsupported media type", "application/json", `{"price": 0}`, noValidation, ErrUnsupportedPatch},
+		{"malformed merge patch", MergePatch, `{"price":`, noValidation, ErrInvalidPatch},
+		{"merge patch of the wrong type", MergePatch, `{"price": "free"}`, noValidation, ErrInvalidPatch},
+		{"failed json patch test", JSONPatch, `[{"op": "test", "path": "/price", "value": 0}]`, noValidation, ErrInvalidPatch},
+		{"invalid patched course", MergePatch, `{"name": null}`, func(interface{}) error { return errValidation }, errValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mongoMock := mockPatchedCourse(patchedCourse.ID)
+
+			courseService := courseImpl{}
+
+			c, err := courseService.Patch(context.Background(), patchedCourse.ID, 0, tt.patchType, []byte(tt.patch), tt.validate)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, patchedCourse, c)
+			mongoMock.AssertExpectations(t)
+			mongoMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCoursePatch_ErrNotFound(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	_ = mongoMock.Initialize(context.Background(), "", "")
+	mockAudit(mongoMock)
+	mongoMock.On("FindOne", mock.Anything, coll, live(byRef("missing")), mock.Anything).Return(storage.ErrNotFound).Once()
+
+	courseService := courseImpl{}
+
+	_, err := courseService.Patch(context.Background(), "missing", 0, MergePatch, []byte(`{}`), noValidation)
+	assert.Equal(t, storage.ErrNotFound, err)
+	mongoMock.AssertExpectations(t)
+}
@@ -0,0 +1,71 @@
+package courseservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+//ErrInvalidPrices for courses with more than one price in a currency
+var ErrInvalidPrices = errors.New("a course has a single price per currency")
+
+//normalizePrices uppercases the currencies of the prices, the ones without a currency are in the default one
+func normalizePrices(course *types.Course) error {
+	course.Price.Currency = currencyOf(course.Price)
+	seen := map[string]bool{course.Price.Currency: true}
+	var prices []types.Money
+	for _, p := range course.Prices {
+		p.Currency = currencyOf(p)
+		if seen[p.Currency] {
+			return ErrInvalidPrices
+		}
+		seen[p.Currency] = true
+		prices = append(prices, p)
+	}
+	course.Prices = prices
+	return nil
+}
+
+func currencyOf(m types.Money) string {
+	if m.Currency == "" {
+		return types.DefaultCurrency
+	}
+	return strings.ToUpper(m.Currency)
+}
+
+//PriceIn is the price of the course in the currency: its price point in the currency when it has one, otherwise its
+//price at the current exchange rate
+func PriceIn(course types.Course, currency string) (types.Money, error) {
+	return money.Resolve(course.Price, course.Prices, strings.ToUpper(currency))
+}
+
+//MigratePrices stores in minor units of the default currency the prices stored as bare numbers before there were
+//currencies, which are read in the default currency already. It is not a change of the courses, so their versions
+//and history are left alone. It answers how many courses were migrated.
+func MigratePrices(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var courses []types.Course
+	query := map[string]interface{}{"price.currency": map[string]interface{}{"$exists": false}}
+	if err := storage.GetInstance().Find(ctx, coll, query, storage.FindOptions{}, &courses); err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, c := range courses {
+		c.Price.Currency = currencyOf(c.Price)
+		update := map[string]interface{}{"$set": map[string]interface{}{"price": c.Price}}
+		if err := storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": c.ID}, update); err != nil {
+			return n, err
+		}
+		n++
+		if err := invalidate(c); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
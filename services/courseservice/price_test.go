@@ -0,0 +1,86 @@
+package courseservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoursePrices(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+
+	c, err := s.Create(ctx, types.Course{Name: "Go", Price: types.Money{Amount: 1990}, Prices: []types.Money{{Amount: 1790, Currency: "eur"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.USD(1990), c.Price)
+	assert.Equal(t, []types.Money{{Amount: 1790, Currency: "EUR"}}, c.Prices)
+	price, err := PriceIn(c, "eur")
+	assert.NoError(t, err)
+	assert.Equal(t, types.Money{Amount: 1790, Currency: "EUR"}, price)
+
+	_, err = s.Create(ctx, types.Course{Name: "Rust", Price: testutil.USD(1990), Prices: []types.Money{{Amount: 1790, Currency: "EUR"}, {Amount: 1690, Currency: "eur"}}})
+	assert.Equal(t, ErrInvalidPrices, err)
+	c.Prices = []types.Money{{Amount: 1990, Currency: "USD"}}
+	_, err = s.Update(ctx, c)
+	assert.Equal(t, ErrInvalidPrices, err)
+}
+
+func TestCourseFindAll_Price(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+	for _, c := range []types.Course{
+		{Name: "Go", Price: testutil.USD(1990)},
+		{Name: "Rust", Price: testutil.USD(990)},
+		{Name: "Elixir", Price: types.Money{Amount: 990, Currency: "EUR"}},
+		{Name: "Zig", Price: testutil.USD(4990)},
+	} {
+		_, err := s.Create(ctx, c)
+		assert.NoError(t, err)
+	}
+
+	min, max := int64(990), int64(1990)
+	p, err := s.FindAll(ListOptions{Status: types.StatusDraft, MinPrice: &min, MaxPrice: &max, Sort: "-price"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Go", "Rust"}, names(p.Courses))
+	p, err = s.FindAll(ListOptions{Status: types.StatusDraft, MaxPrice: &max, Currency: "EUR"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Elixir"}, names(p.Courses))
+	p, err = s.FindAll(ListOptions{Status: types.StatusDraft, Sort: "price"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Elixir", "Rust", "Go", "Zig"}, names(p.Courses))
+}
+
+func TestMigratePrices(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+	for _, doc := range []map[string]interface{}{
+		{"_id": "id01", "name": "Go", "slug": "go", "version": int64(3), "price": 19.9},
+		{"_id": "id02", "name": "Rust", "slug": "rust", "version": int64(1), "price": int32(10)},
+		{"_id": "id03", "name": "Zig", "slug": "zig", "version": int64(1)},
+	} {
+		assert.NoError(t, storage.GetInstance().Insert(ctx, coll, doc))
+	}
+	c, err := s.FindOne("go")
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.USD(1990), c.Price)
+
+	n, err := MigratePrices(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	n, err = MigratePrices(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	min := int64(1000)
+	p, err := s.FindAll(ListOptions{MinPrice: &min})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Go", "Rust"}, names(p.Courses))
+	c, err = s.FindOne("zig")
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.USD(0), c.Price)
+	assert.Equal(t, int64(1), c.Version)
+}
@@ -0,0 +1,53 @@
+package courseservice
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+//Rate runs fn in a transaction with the course of the id or slug and adds to the rating of the course the stars and
+//the count of the reviews fn returns, negative for the ones it takes out. The rating is not a change of the course:
+//its version stays the same and it is not audited.
+func (s courseImpl) Rate(ctx context.Context, ref string, fn func(context.Context, types.Course) (types.Rating, error)) (types.Course, error) {
+	var c types.Course
+	var rated bool
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	err := storage.GetInstance().WithTransaction(ctx, func(ctx context.Context) error {
+		if err := storage.GetInstance().FindOne(ctx, coll, live(byRef(ref)), &c); err != nil {
+			return err
+		}
+		change, err := fn(ctx, c)
+		if err != nil || change.Stars == 0 && change.Count == 0 {
+			return err
+		}
+		c.Rating, rated = addRating(c.Rating, change), true
+		return storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": c.ID}, map[string]interface{}{
+			"$set": map[string]interface{}{"rating": c.Rating},
+		})
+	})
+	if err == nil && rated {
+		err = invalidate(c)
+	}
+	return c, err
+}
+
+//addRating adds the stars and the count of the change to the rating, its average is rounded to the hundredth.
+//Courses without reviews have no rating.
+func addRating(r *types.Rating, change types.Rating) *types.Rating {
+	var sum types.Rating
+	if r != nil {
+		sum = *r
+	}
+	sum.Stars += change.Stars
+	sum.Count += change.Count
+	if sum.Count <= 0 {
+		return nil
+	}
+	sum.Average = math.Round(float64(sum.Stars)/float64(sum.Count)*100) / 100
+	return &sum
+}
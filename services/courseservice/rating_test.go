@@ -0,0 +1,27 @@
+package courseservice
+
+import (
+	"testing"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddRating(t *testing.T) {
+	tests := []struct {
+		name   string
+		rating *types.Rating
+		change types.Rating
+		want   *types.Rating
+	}{
+		{"First review", nil, types.Rating{Stars: 4, Count: 1}, &types.Rating{Average: 4, Count: 1, Stars: 4}},
+		{"Rounded average", &types.Rating{Average: 4, Count: 1, Stars: 4}, types.Rating{Stars: 3, Count: 2}, &types.Rating{Average: 2.33, Count: 3, Stars: 7}},
+		{"Changed stars", &types.Rating{Average: 4, Count: 2, Stars: 8}, types.Rating{Stars: -3}, &types.Rating{Average: 2.5, Count: 2, Stars: 5}},
+		{"Last review removed", &types.Rating{Average: 4, Count: 1, Stars: 4}, types.Rating{Stars: -4, Count: -1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, addRating(tt.rating, tt.change))
+		})
+	}
+}
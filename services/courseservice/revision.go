@@ -0,0 +1,47 @@
+package courseservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const revisionColl = "course_revisions"
+
+//Revision finds the revision rev of the course of the id or slug. Courses purged from the trash are only
+//found by their id.
+func (s courseImpl) Revision(ref string, rev int64) (r types.Revision, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	id, _, err := courseID(ctx, ref)
+	if err != nil {
+		return r, err
+	}
+	err = storage.GetInstance().FindOne(ctx, revisionColl, map[string]interface{}{"courseId": id, "version": rev}, &r)
+	return r, err
+}
+
+//Revert stores the revision rev of the course of the id or slug as its next version when the course is at the
+//version, any when it is 0. Courses in the trash are not reverted, they are restored first.
+func (s courseImpl) Revert(ctx context.Context, ref string, rev, version int64) (types.Course, error) {
+	var old types.Course
+	var r types.Revision
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if err := storage.GetInstance().FindOne(ctx, coll, live(byRef(ref)), &old); err != nil {
+		return old, err
+	}
+	if err := checkOwner(ctx, old); err != nil {
+		return old, err
+	}
+	if err := checkVersion(old, version); err != nil {
+		return old, err
+	}
+	query := map[string]interface{}{"courseId": old.ID, "version": rev}
+	if err := storage.GetInstance().FindOne(ctx, revisionColl, query, &r); err != nil {
+		return old, err
+	}
+	return replace(ctx, ActionRevert, old, r.Course)
+}
@@ -0,0 +1,97 @@
+package courseservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCourseRevision(t *testing.T) {
+	s := newMemoryCourses(t)
+	ana := actor.NewContext(context.Background(), "ana")
+	c, err := s.Create(ana, types.Course{Name: "Go", Price: testutil.USD(10)})
+	assert.NoError(t, err)
+	c.Price = testutil.USD(0)
+	_, err = s.Update(ana, c)
+	assert.NoError(t, err)
+
+	r, err := s.Revision(c.Slug, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, c.ID, r.CourseID)
+	assert.Equal(t, int64(1), r.Version)
+	assert.Equal(t, "ana", r.Actor)
+	assert.Equal(t, testutil.USD(10), r.Course.Price)
+
+	r, err = s.Revision(c.ID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.USD(0), r.Course.Price)
+
+	_, err = s.Revision(c.ID, 3)
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.Revision("missing", 1)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestCourseRevert(t *testing.T) {
+	s := newMemoryCourses(t)
+	c, err := s.Create(context.Background(), types.Course{Name: "Go", Price: testutil.USD(10)})
+	assert.NoError(t, err)
+	_, err = s.Update(context.Background(), types.Course{ID: c.ID, Name: "Rust", Price: testutil.USD(20)})
+	assert.NoError(t, err)
+
+	reverted, err := s.Revert(actor.NewContext(context.Background(), "ana"), "rust", 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Course{ID: c.ID, Slug: "go", Version: 3, Status: types.StatusDraft, Name: "Go", Price: testutil.USD(10)}, reverted)
+
+	found, err := s.FindOne("go")
+	assert.NoError(t, err)
+	assert.Equal(t, reverted, found)
+
+	entries, err := s.History(c.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, ActionRevert, entries[2].Action)
+		assert.Equal(t, "ana", entries[2].Actor)
+	}
+	r, err := s.Revision(c.ID, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, reverted, r.Course)
+}
+
+func TestCourseRevert_Errors(t *testing.T) {
+	s := newMemoryCourses(t)
+	c, err := s.Create(context.Background(), types.Course{Name: "Go", Price: testutil.USD(10)})
+	assert.NoError(t, err)
+
+	_, err = s.Revert(context.Background(), c.ID, 1, 9)
+	assert.Equal(t, ErrVersionMismatch, err)
+	_, err = s.Revert(context.Background(), c.ID, 9, 0)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	_, err = s.Update(context.Background(), types.Course{ID: c.ID, Name: "Rust"})
+	assert.NoError(t, err)
+	_, err = s.Create(context.Background(), types.Course{Name: "Go"})
+	assert.NoError(t, err)
+	_, err = s.Revert(context.Background(), c.ID, 1, 0)
+	assert.Equal(t, storage.ErrDuplicate, err)
+
+	assert.NoError(t, s.Delete(context.Background(), c.ID, 0))
+	_, err = s.Revert(context.Background(), c.ID, 1, 0)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestCourseRevision_Immutable(t *testing.T) {
+	s := newMemoryCourses(t)
+	c, err := s.Create(context.Background(), types.Course{Name: "Go"})
+	assert.NoError(t, err)
+	err = storage.GetInstance().Insert(context.Background(), revisionColl, types.Revision{
+		ID: storage.NewID(), CourseID: c.ID, Version: 1, At: time.Now(),
+	})
+	assert.Equal(t, storage.ErrDuplicate, err)
+}
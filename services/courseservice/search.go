@@ -0,0 +1,164 @@
+package courseservice
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const snippetWidth = 160
+
+//EnsureIndexes creates the indexes the course service relies on: unique names and slugs, the text index of Search,
+//the trash one of Purge, the lifecycle, catalog and price ones and the ones of the curriculum, course history and
+//revisions. Names and slugs are unique along with deletedAt, so the courses in the trash, which have one, leave theirs
+//to the live courses, which have none.
+func EnsureIndexes(ctx context.Context) error {
+	//the names and slugs were unique alone before, keeping the ones of the trash
+	for _, idx := range []storage.Index{{Keys: []string{"name"}, Unique: true}, {Keys: []string{"slug"}, Unique: true}} {
+		if err := storage.GetInstance().DropIndex(ctx, coll, idx); err != nil {
+			return err
+		}
+	}
+	for _, idx := range []storage.Index{
+		{Keys: []string{"name", "deletedAt"}, Unique: true},
+		{Keys: []string{"slug", "deletedAt"}, Unique: true},
+		{Keys: []string{"name", "description"}, Text: true},
+		{Keys: []string{"deletedAt"}},
+		{Keys: []string{"status", "author"}},
+		{Keys: []string{"instructors"}},
+		{Keys: []string{"scheduled.at"}},
+		{Keys: []string{"category"}},
+		{Keys: []string{"tags"}},
+		{Keys: []string{"price.currency", "price.amount"}},
+	} {
+		if err := storage.GetInstance().EnsureIndex(ctx, coll, idx); err != nil {
+			return err
+		}
+	}
+	if err := storage.GetInstance().EnsureIndex(ctx, moduleColl, storage.Index{Keys: []string{"courseId", "order"}}); err != nil {
+		return err
+	}
+	if err := storage.GetInstance().EnsureIndex(ctx, auditColl, storage.Index{Keys: []string{"courseId", "version"}}); err != nil {
+		return err
+	}
+	return storage.GetInstance().EnsureIndex(ctx, revisionColl, storage.Index{Keys: []string{"courseId", "version"}, Unique: true})
+}
+
+//Search ranks by relevance the courses which name or description match the text, the sort of the options is ignored
+func (s courseImpl) Search(text string, opts ListOptions) (hits []types.CourseHit, err error) {
+	opts.Sort, opts.Facets = "", false
+	if opts, _, err = withCategories(opts); err != nil {
+		return nil, err
+	}
+	v := opts.values()
+	v.Set("q", text)
+
+	err = cache.GetOrLoadTagged(coll+"search?"+v.Encode(), []string{coll}, &hits, time.Minute, func(v interface{}) error {
+		var cs []types.Course
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		findOpts := storage.FindOptions{Skip: opts.Offset, Limit: opts.Limit}
+		if err := storage.GetInstance().Search(ctx, coll, text, opts.query(), findOpts, &cs); err != nil {
+			return err
+		}
+		*v.(*[]types.CourseHit) = highlightAll(text, cs)
+		return nil
+	})
+	return hits, err
+}
+
+func highlightAll(text string, cs []types.Course) []types.CourseHit {
+	hits := make([]types.CourseHit, 0, len(cs))
+	re := termsRegexp(text)
+	for _, c := range cs {
+		hit := types.CourseHit{Course: c, Highlights: map[string]string{}}
+		if re != nil {
+			if h, ok := highlight(re, c.Name, 0); ok {
+				hit.Highlights["name"] = h
+			}
+			if h, ok := highlight(re, c.Description, snippetWidth); ok {
+				hit.Highlights["description"] = h
+			}
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+//termsRegexp matches, case insensitively, any of the words searched
+func termsRegexp(text string) *regexp.Regexp {
+	var terms []string
+	for _, t := range strings.Fields(text) {
+		t = strings.Trim(t, `"-`)
+		if t != "" {
+			terms = append(terms, regexp.QuoteMeta(t))
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(terms, "|"))
+}
+
+//highlight escapes the text and wraps the matched terms in <em> tags. When width is
+//greater than zero the text is cut, on word boundaries, to a snippet of at most about that many bytes
+//around the first match.
+func highlight(re *regexp.Regexp, text string, width int) (string, bool) {
+	first := re.FindStringIndex(text)
+	if first == nil {
+		return "", false
+	}
+
+	prefix, suffix := "", ""
+	if width > 0 && len(text) > width {
+		start := first[0] - width/4
+		if start < 0 {
+			start = 0
+		}
+		end := start + width
+		if end > len(text) {
+			end = len(text)
+		}
+		if end < first[1] {
+			end = first[1]
+		}
+		if i := strings.IndexByte(text[start:first[0]], ' '); start > 0 && i >= 0 {
+			start += i + 1
+		}
+		if i := strings.LastIndexByte(text[first[1]:end], ' '); end < len(text) && i >= 0 {
+			end = first[1] + i
+		}
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+		if start > 0 {
+			prefix = "…"
+		}
+		if end < len(text) {
+			suffix = "…"
+		}
+		text = text[start:end]
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	last := 0
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:m[0]]))
+		b.WriteString("<em>" + html.EscapeString(text[m[0]:m[1]]) + "</em>")
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	b.WriteString(suffix)
+	return b.String(), true
+}
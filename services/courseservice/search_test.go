@@ -0,0 +1,111 @@
+package courseservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	redis "github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/go-redis/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCourseSearch_FindsCached(t *testing.T) {
+	redisMock := &redis.Mock{}
+	redisHitsMock := []types.CourseHit{{Course: types.Course{Name: "Go basics"}}}
+	redisMock.Initialize(map[string]string{})
+
+	mockTagVersion(redisMock, 1)
+	redisMock.On("Get", coll+"search?limit=10&q=go#course=1", mock.Anything).Return(nil).
+		Run(func(args mock.Arguments) {
+			arg := args.Get(1).(*redis.Entry)
+			*arg, _ = redis.NewEntry(redisHitsMock, 0, time.Minute)
+		}).Once()
+
+	courseService := courseImpl{}
+
+	hits, err := courseService.Search("go", ListOptions{Limit: 10, Sort: "price"})
+	assert.Nil(t, err)
+	assert.Equal(t, redisHitsMock, hits)
+
+	redisMock.AssertExpectations(t)
+}
+
+func TestCourseSearch_ErrSearch(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	redisMock := &redis.Mock{}
+	errMock := errors.New("err search")
+	redisMock.Initialize(map[string]string{})
+	_ = mongoMock.Initialize(context.Background(), "", "")
+
+	mockTagVersion(redisMock, 1)
+	redisMock.On("Get", mock.Anything, mock.Anything).Return(cache.ErrCacheMiss).Once()
+	mongoMock.On("Search", mock.Anything, coll, "go", mock.Anything, mock.Anything, mock.AnythingOfType("*[]types.Course")).
+		Return(errMock).Once()
+
+	courseService := courseImpl{}
+
+	hits, err := courseService.Search("go", ListOptions{Limit: 10})
+	assert.Equal(t, errMock, err)
+	assert.Len(t, hits, 0)
+
+	redisMock.AssertExpectations(t)
+	mongoMock.AssertExpectations(t)
+}
+
+func TestCourseSearch_Success(t *testing.T) {
+	mongoMock := &storage.DataAccessLayerMock{}
+	redisMock := &redis.Mock{}
+	mongoCourseMock := []types.Course{
+		{Name: "Go basics", Description: "Learn <b>Go</b> from scratch"},
+		{Name: "Concurrency", Description: "Goroutines in go"},
+	}
+	published := map[string]interface{}{
+		"deletedAt": nil,
+		"status":    map[string]interface{}{"$in": []interface{}{types.StatusPublished, "", nil}},
+	}
+	redisMock.Initialize(map[string]string{})
+	_ = mongoMock.Initialize(context.Background(), "", "")
+
+	mockTagVersion(redisMock, 1)
+	redisMock.On("Get", mock.Anything, mock.Anything).Return(cache.ErrCacheMiss).Once()
+	mongoMock.On("Search", mock.Anything, coll, "go", published, storage.FindOptions{Skip: 10, Limit: 10}, mock.AnythingOfType("*[]types.Course")).
+		Run(func(args mock.Arguments) {
+			arg := args.Get(5).(*[]types.Course)
+			*arg = mongoCourseMock
+		}).Return(nil).Once()
+	redisMock.On("Set", coll+"search?limit=10&offset=10&q=go#course=1", mock.Anything, mock.Anything).Return(nil).Once()
+
+	courseService := courseImpl{}
+
+	hits, err := courseService.Search("go", ListOptions{Offset: 10, Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, []types.CourseHit{
+		{Course: mongoCourseMock[0], Highlights: map[string]string{
+			"name":        "<em>Go</em> basics",
+			"description": "Learn &lt;b&gt;<em>Go</em>&lt;/b&gt; from scratch",
+		}},
+		{Course: mongoCourseMock[1], Highlights: map[string]string{
+			"description": "<em>Go</em>routines in <em>go</em>",
+		}},
+	}, hits)
+
+	redisMock.AssertExpectations(t)
+	mongoMock.AssertExpectations(t)
+}
+
+func TestHighlight_Snippet(t *testing.T) {
+	re := termsRegexp(`"pointer" -slices`)
+	text := "Go has many features worth a look. Among them the pointer receivers are one of the most misunderstood."
+
+	h, ok := highlight(re, text, 40)
+	assert.True(t, ok)
+	assert.Equal(t, "…them the <em>pointer</em> receivers are one of…", h)
+
+	_, ok = highlight(re, "nothing to see here", 40)
+	assert.False(t, ok)
+}
@@ -0,0 +1,79 @@
+package courseservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+//Restore takes the course of the id or slug out of the trash as its next version
+func (s courseImpl) Restore(ctx context.Context, ref string) (types.Course, error) {
+	var old types.Course
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	query := byRef(ref)
+	query["deletedAt"] = map[string]interface{}{"$ne": nil}
+	if err := storage.GetInstance().FindOne(ctx, coll, query, &old); err != nil {
+		return old, err
+	}
+	if err := checkOwner(ctx, old); err != nil {
+		return old, err
+	}
+
+	course := old
+	course.DeletedAt = nil
+	course.Version = old.Version + 1
+	err := audited(ctx, ActionRestore, &old, &course, func(ctx context.Context) error {
+		return storage.GetInstance().Update(ctx, coll, versioned(old), map[string]interface{}{
+			"$set":   map[string]interface{}{"version": course.Version},
+			"$unset": map[string]interface{}{"deletedAt": ""},
+		})
+	})
+	if err == nil {
+		return course, invalidate(old)
+	}
+	if err == storage.ErrNotFound {
+		return old, ErrVersionMismatch
+	}
+	return old, err
+}
+
+//Purge removes for good, with their curriculum, the courses moved to the trash before the time and returns how many were removed
+func (s courseImpl) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var cs []types.Course
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	query := map[string]interface{}{"deletedAt": map[string]interface{}{"$lt": before}}
+	if err := storage.GetInstance().Find(ctx, coll, query, storage.FindOptions{}, &cs); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for i := range cs {
+		c := cs[i]
+		err := audited(ctx, ActionPurge, &c, nil, func(ctx context.Context) error {
+			if err := storage.GetInstance().Remove(ctx, coll, map[string]interface{}{"_id": c.ID, "deletedAt": query["deletedAt"]}); err != nil {
+				return err
+			}
+			ms, err := findModules(ctx, c.ID)
+			if err != nil {
+				return err
+			}
+			return writeModules(ctx, ms, nil)
+		})
+		if err == storage.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, cache.InvalidateTags(coll)
+}
@@ -0,0 +1,113 @@
+package courseservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ednesic/coursemanagement/services/instructorservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemoryCourses(t *testing.T) CourseService {
+	testutil.Memory(t, EnsureIndexes, instructorservice.EnsureIndexes)
+	return courseImpl{}
+}
+
+func TestCourseDelete_MovesToTrash(t *testing.T) {
+	s := newMemoryCourses(t)
+	c, err := s.Create(context.Background(), types.Course{Name: "Go basics"})
+	assert.NoError(t, err)
+	_, err = s.FindOne(c.Slug)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Delete(context.Background(), c.Slug, c.Version))
+	_, err = s.FindOne(c.Slug)
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, s.Delete(context.Background(), c.Slug, 0))
+
+	p, err := s.FindAll(ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), p.Total)
+
+	p, err = s.FindAll(ListOptions{Limit: 10, Deleted: true})
+	assert.NoError(t, err)
+	if assert.Len(t, p.Courses, 1) {
+		assert.Equal(t, c.ID, p.Courses[0].ID)
+		assert.NotNil(t, p.Courses[0].DeletedAt)
+		assert.Equal(t, int64(2), p.Courses[0].Version)
+	}
+}
+
+func TestCourseDelete_ReleasesName(t *testing.T) {
+	s := newMemoryCourses(t)
+	ctx := context.Background()
+	old, err := s.Create(ctx, types.Course{Name: "Go basics"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Delete(ctx, old.ID, 0))
+
+	c, err := s.Create(ctx, types.Course{Name: "Go basics"})
+	assert.NoError(t, err)
+	assert.Equal(t, old.Slug, c.Slug)
+	found, err := s.FindOne(c.Slug)
+	assert.NoError(t, err)
+	assert.Equal(t, c.ID, found.ID)
+	_, err = s.Create(ctx, types.Course{Name: "Go basics"})
+	assert.Equal(t, storage.ErrDuplicate, err)
+
+	h, err := s.History(c.Slug)
+	assert.NoError(t, err)
+	assert.Equal(t, c.ID, h[0].CourseID)
+	_, err = s.Restore(ctx, old.ID)
+	assert.Equal(t, storage.ErrDuplicate, err)
+	//the trash tells the courses of a name apart by the millisecond they were deleted at
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, s.Delete(ctx, c.ID, 0))
+	_, err = s.Restore(ctx, old.ID)
+	assert.NoError(t, err)
+}
+
+func TestCourseRestore(t *testing.T) {
+	s := newMemoryCourses(t)
+	c, err := s.Create(context.Background(), types.Course{Name: "Go basics"})
+	assert.NoError(t, err)
+
+	_, err = s.Restore(context.Background(), c.ID)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	assert.NoError(t, s.Delete(context.Background(), c.ID, 0))
+	restored, err := s.Restore(context.Background(), c.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	assert.Equal(t, int64(3), restored.Version)
+
+	found, err := s.FindOne(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, restored, found)
+}
+
+func TestCoursePurge(t *testing.T) {
+	s := newMemoryCourses(t)
+	kept, err := s.Create(context.Background(), types.Course{Name: "Kept"})
+	assert.NoError(t, err)
+	trashed, err := s.Create(context.Background(), types.Course{Name: "Trashed"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Delete(context.Background(), trashed.ID, 0))
+
+	n, err := s.Purge(context.Background(), time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	n, err = s.Purge(context.Background(), time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	p, err := s.FindAll(ListOptions{Limit: 10, Deleted: true})
+	assert.NoError(t, err)
+	assert.Empty(t, p.Courses)
+	_, err = s.FindOne(kept.ID)
+	assert.NoError(t, err)
+}
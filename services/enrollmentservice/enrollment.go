@@ -0,0 +1,314 @@
+package enrollmentservice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const (
+	coll      = "enrollment"
+	seatsColl = "course_seats"
+)
+
+var (
+	instance EnrollmentService
+	once     sync.Once
+
+	//ErrAlreadyEnrolled for students enrolling in a course they are enrolled in or waiting for
+	ErrAlreadyEnrolled = errors.New("student already enrolled")
+	//ErrNotEnrollable for enrollments in courses which are not published
+	ErrNotEnrollable = errors.New("course is not open for enrollment")
+	//ErrPaymentRequired for enrollments in priced courses without an order paying for them
+	ErrPaymentRequired = errors.New("course must be paid for with an order")
+)
+
+//EnrollmentService is an interface for enrollment service
+type EnrollmentService interface {
+	Enroll(context.Context, string, string) (types.Enrollment, error)
+	Admit(context.Context, string, string) (types.Enrollment, error)
+	Cancel(context.Context, string, string) error
+	FindByStudent(string) ([]types.Enrollment, error)
+	FindByCourse(string) ([]types.Enrollment, error)
+	Seats(string) (types.Seats, error)
+}
+
+type enrollmentImpl struct{}
+
+func init() {
+	courseservice.AddHook(promoteWaitlist)
+}
+
+//GetInstance to get service instance
+func GetInstance() EnrollmentService {
+	once.Do(func() {
+		if instance == nil {
+			instance = &enrollmentImpl{}
+		}
+	})
+	return instance
+}
+
+//EnsureIndexes creates the indexes the enrollment service relies on, the ones listing the enrollments of a student
+//and the waitlist of a course
+func EnsureIndexes(ctx context.Context) error {
+	for _, idx := range []storage.Index{
+		{Keys: []string{"student", "at"}},
+		{Keys: []string{"courseId", "status", "at"}},
+	} {
+		if err := storage.GetInstance().EnsureIndex(ctx, coll, idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Enroll takes a seat of the free published course of the id or slug for the student, or waitlists the student when
+//the course is at capacity. Priced courses are only enrolled in through Admit once paid.
+func (s enrollmentImpl) Enroll(ctx context.Context, ref, student string) (types.Enrollment, error) {
+	c, err := findCourse(ref)
+	if err != nil {
+		return types.Enrollment{}, err
+	}
+	if c.Price.Amount > 0 {
+		return types.Enrollment{}, ErrPaymentRequired
+	}
+	return admit(ctx, c, student)
+}
+
+//Admit enrolls the student like Enroll whatever the price of the course, for the orders paying for it and the free
+//bundles including it
+func (s enrollmentImpl) Admit(ctx context.Context, ref, student string) (types.Enrollment, error) {
+	c, err := findCourse(ref)
+	if err != nil {
+		return types.Enrollment{}, err
+	}
+	return admit(ctx, c, student)
+}
+
+//admit takes a seat of the published course for the student, or waitlists the student when the course is at
+//capacity. The seat counts and the enrollment are stored in one transaction.
+func admit(ctx context.Context, c types.Course, student string) (types.Enrollment, error) {
+	if c.Status != types.StatusPublished && c.Status != "" {
+		return types.Enrollment{}, ErrNotEnrollable
+	}
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if err := ensureSeats(ctx, c.ID); err != nil {
+		return types.Enrollment{}, err
+	}
+
+	e := types.Enrollment{ID: enrollmentID(c.ID, student), CourseID: c.ID, Student: student, At: time.Now().UTC()}
+	err := storage.GetInstance().WithTransaction(ctx, func(ctx context.Context) error {
+		var old types.Enrollment
+		err := storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": e.ID}, &old)
+		if err == nil && old.Status != types.EnrollmentCancelled {
+			return ErrAlreadyEnrolled
+		}
+		if err != nil && err != storage.ErrNotFound {
+			return err
+		}
+
+		e.Status = types.EnrollmentActive
+		err = storage.GetInstance().Update(ctx, seatsColl, seatAvailable(c), map[string]interface{}{"$inc": map[string]interface{}{"enrolled": 1}})
+		if err == storage.ErrNotFound {
+			e.Status = types.EnrollmentWaitlisted
+			err = storage.GetInstance().Update(ctx, seatsColl, map[string]interface{}{"_id": c.ID}, map[string]interface{}{"$inc": map[string]interface{}{"waitlisted": 1}})
+		}
+		if err != nil {
+			return err
+		}
+		if old.ID == "" {
+			return storage.GetInstance().Insert(ctx, coll, e)
+		}
+		return storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": e.ID}, map[string]interface{}{"$set": &e})
+	})
+	if err == storage.ErrDuplicate {
+		return e, ErrAlreadyEnrolled
+	}
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return e, err
+}
+
+//Cancel gives up the seat, or the place in the waitlist, of the student in the course of the id or slug. The seat
+//goes to the first student of the waitlist.
+func (s enrollmentImpl) Cancel(ctx context.Context, ref, student string) error {
+	c, err := findCourse(ref)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	id := enrollmentID(c.ID, student)
+	err = storage.GetInstance().WithTransaction(ctx, func(ctx context.Context) error {
+		var e types.Enrollment
+		query := map[string]interface{}{"_id": id, "status": map[string]interface{}{"$ne": types.EnrollmentCancelled}}
+		if err := storage.GetInstance().FindOne(ctx, coll, query, &e); err != nil {
+			return err
+		}
+		if err := setStatus(ctx, e, types.EnrollmentCancelled); err != nil {
+			return err
+		}
+		counter := "waitlisted"
+		if e.Status == types.EnrollmentActive {
+			counter = "enrolled"
+		}
+		if err := incSeats(ctx, c.ID, counter, -1); err != nil {
+			return err
+		}
+		if e.Status != types.EnrollmentActive {
+			return nil
+		}
+		_, err := promote(ctx, c)
+		return err
+	})
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return err
+}
+
+//FindByStudent lists the enrollments of the student, cancelled ones included, oldest first
+func (s enrollmentImpl) FindByStudent(student string) (es []types.Enrollment, err error) {
+	err = cache.GetOrLoadTagged(coll+"student"+student, []string{coll}, &es, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		query := map[string]interface{}{"student": student}
+		return storage.GetInstance().Find(ctx, coll, query, storage.FindOptions{Sort: []string{"at"}}, v)
+	})
+	return es, err
+}
+
+//FindByCourse lists the students enrolled in and waiting for the course of the id or slug, oldest first
+func (s enrollmentImpl) FindByCourse(ref string) (es []types.Enrollment, err error) {
+	c, err := findCourse(ref)
+	if err != nil {
+		return nil, err
+	}
+	err = cache.GetOrLoadTagged(coll+"course"+c.ID, []string{coll}, &es, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		query := map[string]interface{}{"courseId": c.ID, "status": map[string]interface{}{"$ne": types.EnrollmentCancelled}}
+		return storage.GetInstance().Find(ctx, coll, query, storage.FindOptions{Sort: []string{"at"}}, v)
+	})
+	return es, err
+}
+
+//Seats counts the students enrolled in and waiting for the course of the id or slug
+func (s enrollmentImpl) Seats(ref string) (seats types.Seats, err error) {
+	c, err := findCourse(ref)
+	if err != nil {
+		return seats, err
+	}
+	err = cache.GetOrLoadTagged(coll+"seats"+c.ID, []string{coll}, &seats, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		err := storage.GetInstance().FindOne(ctx, seatsColl, map[string]interface{}{"_id": c.ID}, v)
+		if err == storage.ErrNotFound {
+			*v.(*types.Seats) = types.Seats{CourseID: c.ID}
+			return nil
+		}
+		return err
+	})
+	seats.Capacity = c.Capacity
+	return seats, err
+}
+
+//findCourse finds the course of the id or slug, errors of the cache are not a failure
+func findCourse(ref string) (types.Course, error) {
+	c, err := courseservice.GetInstance().FindOne(ref)
+	if _, ok := err.(*cache.RedisErr); ok {
+		err = nil
+	}
+	return c, err
+}
+
+//ensureSeats stores the seat counts of the course when it has none yet. It runs before the transactions updating
+//them, as an insert failing there would abort them.
+func ensureSeats(ctx context.Context, courseID string) error {
+	err := storage.GetInstance().Insert(ctx, seatsColl, types.Seats{CourseID: courseID})
+	if err == storage.ErrDuplicate {
+		return nil
+	}
+	return err
+}
+
+//seatAvailable selects the seat counts of the course while it has seats left
+func seatAvailable(c types.Course) map[string]interface{} {
+	query := map[string]interface{}{"_id": c.ID}
+	if c.Capacity > 0 {
+		query["enrolled"] = map[string]interface{}{"$lt": c.Capacity}
+	}
+	return query
+}
+
+//promote gives a seat left to the first student of the waitlist, reporting whether the course had both
+func promote(ctx context.Context, c types.Course) (bool, error) {
+	var waiting []types.Enrollment
+	query := map[string]interface{}{"courseId": c.ID, "status": types.EnrollmentWaitlisted}
+	if err := storage.GetInstance().Find(ctx, coll, query, storage.FindOptions{Sort: []string{"at"}, Limit: 1}, &waiting); err != nil {
+		return false, err
+	}
+	if len(waiting) == 0 {
+		return false, nil
+	}
+	err := storage.GetInstance().Update(ctx, seatsColl, seatAvailable(c), map[string]interface{}{
+		"$inc": map[string]interface{}{"enrolled": 1, "waitlisted": -1},
+	})
+	if err == storage.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, setStatus(ctx, waiting[0], types.EnrollmentActive)
+}
+
+//promoteWaitlist is the courseservice.Hook giving the seats added to a course to the students of its waitlist,
+//oldest first
+func promoteWaitlist(ctx context.Context, action string, before, after *types.Course) ([]string, error) {
+	if before == nil || after == nil || before.Capacity == 0 || after.Capacity != 0 && after.Capacity <= before.Capacity {
+		return nil, nil
+	}
+	promoted := false
+	for {
+		ok, err := promote(ctx, *after)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		promoted = true
+	}
+	if !promoted {
+		return nil, nil
+	}
+	return []string{coll}, nil
+}
+
+func setStatus(ctx context.Context, e types.Enrollment, status string) error {
+	return storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": e.ID}, map[string]interface{}{
+		"$set": map[string]interface{}{"status": status},
+	})
+}
+
+func incSeats(ctx context.Context, courseID, counter string, n int64) error {
+	return storage.GetInstance().Update(ctx, seatsColl, map[string]interface{}{"_id": courseID}, map[string]interface{}{
+		"$inc": map[string]interface{}{counter: n},
+	})
+}
+
+//enrollmentID is the id of the enrollment of the student in the course, a student enrolls in a course once
+func enrollmentID(courseID, student string) string {
+	return courseID + ":" + student
+}
@@ -0,0 +1,54 @@
+package enrollmentservice
+
+import (
+	"context"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/mock"
+)
+
+//Mock is a mocked structure for enrollment service
+type Mock struct {
+	mock.Mock
+}
+
+//InitMock to initialize mock befores tests
+func (s *Mock) InitMock() {
+	instance = s
+}
+
+//Enroll is a mock for enrollment service enroll
+func (s *Mock) Enroll(ctx context.Context, ref, student string) (types.Enrollment, error) {
+	args := s.Called(ctx, ref, student)
+	return args.Get(0).(types.Enrollment), args.Error(1)
+}
+
+//Admit is a mock for enrollment service admit
+func (s *Mock) Admit(ctx context.Context, ref, student string) (types.Enrollment, error) {
+	args := s.Called(ctx, ref, student)
+	return args.Get(0).(types.Enrollment), args.Error(1)
+}
+
+//Cancel is a mock for enrollment service cancel
+func (s *Mock) Cancel(ctx context.Context, ref, student string) error {
+	args := s.Called(ctx, ref, student)
+	return args.Error(0)
+}
+
+//FindByStudent is a mock for enrollment service findByStudent
+func (s *Mock) FindByStudent(student string) ([]types.Enrollment, error) {
+	args := s.Called(student)
+	return args.Get(0).([]types.Enrollment), args.Error(1)
+}
+
+//FindByCourse is a mock for enrollment service findByCourse
+func (s *Mock) FindByCourse(ref string) ([]types.Enrollment, error) {
+	args := s.Called(ref)
+	return args.Get(0).([]types.Enrollment), args.Error(1)
+}
+
+//Seats is a mock for enrollment service seats
+func (s *Mock) Seats(ref string) (types.Seats, error) {
+	args := s.Called(ref)
+	return args.Get(0).(types.Seats), args.Error(1)
+}
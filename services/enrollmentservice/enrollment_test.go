@@ -0,0 +1,157 @@
+package enrollmentservice
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemoryEnrollments(t *testing.T) EnrollmentService {
+	testutil.Memory(t, courseservice.EnsureIndexes, EnsureIndexes)
+	return enrollmentImpl{}
+}
+
+func TestEnroll_CapacityAndWaitlist(t *testing.T) {
+	s := newMemoryEnrollments(t)
+	ctx := context.Background()
+	c := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Capacity: 2})
+
+	for _, student := range []string{"ana", "bob"} {
+		e, err := s.Enroll(ctx, c.Slug, student)
+		assert.NoError(t, err)
+		assert.Equal(t, types.EnrollmentActive, e.Status)
+	}
+	e, err := s.Enroll(ctx, c.ID, "eve")
+	assert.NoError(t, err)
+	assert.Equal(t, types.EnrollmentWaitlisted, e.Status)
+	_, err = s.Enroll(ctx, c.ID, "ana")
+	assert.Equal(t, ErrAlreadyEnrolled, err)
+
+	seats, err := s.Seats(c.Slug)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Seats{CourseID: c.ID, Capacity: 2, Enrolled: 2, Waitlisted: 1}, seats)
+
+	assert.NoError(t, s.Cancel(ctx, c.ID, "ana"))
+	es, err := s.FindByCourse(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob:active", "eve:active"}, statuses(es))
+	seats, err = s.Seats(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Seats{CourseID: c.ID, Capacity: 2, Enrolled: 2}, seats)
+
+	es, err = s.FindByStudent("ana")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ana:cancelled"}, statuses(es))
+	assert.Equal(t, storage.ErrNotFound, s.Cancel(ctx, c.ID, "ana"))
+
+	e, err = s.Enroll(ctx, c.ID, "ana")
+	assert.NoError(t, err)
+	assert.Equal(t, types.EnrollmentWaitlisted, e.Status)
+	assert.NoError(t, s.Cancel(ctx, c.ID, "ana"))
+	seats, err = s.Seats(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), seats.Waitlisted)
+}
+
+func TestEnroll_Errors(t *testing.T) {
+	s := newMemoryEnrollments(t)
+	ctx := context.Background()
+	draft, err := courseservice.GetInstance().Create(ctx, types.Course{Name: "Draft"})
+	assert.NoError(t, err)
+
+	_, err = s.Enroll(ctx, draft.ID, "ana")
+	assert.Equal(t, ErrNotEnrollable, err)
+	_, err = s.Enroll(ctx, "missing", "ana")
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.Seats("missing")
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, s.Cancel(ctx, draft.ID, "ana"))
+
+	seats, err := s.Seats(draft.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Seats{CourseID: draft.ID}, seats)
+}
+
+func TestEnroll_CapacityGrows(t *testing.T) {
+	s := newMemoryEnrollments(t)
+	ctx := context.Background()
+	c := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Capacity: 1})
+	for _, student := range []string{"ana", "bob", "eve", "joe"} {
+		_, err := s.Enroll(ctx, c.ID, student)
+		assert.NoError(t, err)
+	}
+	es, err := s.FindByCourse(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ana:active", "bob:waitlisted", "eve:waitlisted", "joe:waitlisted"}, statuses(es))
+
+	c.Capacity = 2
+	c, err = courseservice.GetInstance().Update(ctx, c)
+	assert.NoError(t, err)
+	es, err = s.FindByCourse(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ana:active", "bob:active", "eve:waitlisted", "joe:waitlisted"}, statuses(es))
+	seats, err := s.Seats(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Seats{CourseID: c.ID, Capacity: 2, Enrolled: 2, Waitlisted: 2}, seats)
+
+	c.Capacity = 0
+	_, err = courseservice.GetInstance().Update(ctx, c)
+	assert.NoError(t, err)
+	seats, err = s.Seats(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Seats{CourseID: c.ID, Enrolled: 4}, seats)
+}
+
+func TestEnroll_PaymentRequired(t *testing.T) {
+	s := newMemoryEnrollments(t)
+	ctx := context.Background()
+	c := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go"})
+	c.Price = types.Money{Amount: 1000, Currency: "USD"}
+	c, err := courseservice.GetInstance().Update(ctx, c)
+	assert.NoError(t, err)
+
+	_, err = s.Enroll(ctx, c.ID, "ana")
+	assert.Equal(t, ErrPaymentRequired, err)
+	es, err := s.FindByCourse(c.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, es)
+
+	e, err := s.Admit(ctx, c.ID, "ana")
+	assert.NoError(t, err)
+	assert.Equal(t, types.EnrollmentActive, e.Status)
+}
+
+func TestEnroll_Concurrent(t *testing.T) {
+	s := newMemoryEnrollments(t)
+	c := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Capacity: 5})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.Enroll(context.Background(), c.ID, fmt.Sprintf("student%d", i))
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	seats, err := s.Seats(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.Seats{CourseID: c.ID, Capacity: 5, Enrolled: 5, Waitlisted: 15}, seats)
+}
+
+func statuses(es []types.Enrollment) []string {
+	ss := []string{}
+	for _, e := range es {
+		ss = append(ss, e.Student+":"+e.Status)
+	}
+	return ss
+}
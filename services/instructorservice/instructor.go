@@ -0,0 +1,151 @@
+package instructorservice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const coll = "instructor"
+
+var (
+	instance InstructorService
+	once     sync.Once
+
+	//ErrNotUser for instructors created, changed or removed by someone who is neither their user nor an admin
+	ErrNotUser = errors.New("only the user of the instructor and the admins change it")
+)
+
+//InstructorService is an interface for instructor service
+type InstructorService interface {
+	Create(context.Context, types.Instructor) (types.Instructor, error)
+	Update(context.Context, types.Instructor) (types.Instructor, error)
+	FindAll() ([]types.Instructor, error)
+	FindOne(string) (types.Instructor, error)
+	FindByUser(string) (types.Instructor, error)
+	Delete(context.Context, string) error
+}
+
+type instructorImpl struct{}
+
+//GetInstance to get service instance
+func GetInstance() InstructorService {
+	once.Do(func() {
+		if instance == nil {
+			instance = &instructorImpl{}
+		}
+	})
+	return instance
+}
+
+//EnsureIndexes creates the indexes the instructor service relies on, one instructor per user
+func EnsureIndexes(ctx context.Context) error {
+	return storage.GetInstance().EnsureIndex(ctx, coll, storage.Index{Keys: []string{"user"}, Unique: true})
+}
+
+//FindOne finds the instructor by its id
+func (s instructorImpl) FindOne(id string) (i types.Instructor, err error) {
+	err = cache.GetOrLoad(coll+id, &i, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": id}, v)
+	})
+	return i, err
+}
+
+//FindByUser finds the instructor the user is
+func (s instructorImpl) FindByUser(user string) (i types.Instructor, err error) {
+	err = cache.GetOrLoadTagged(coll+"user"+user, []string{coll}, &i, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"user": user}, v)
+	})
+	return i, err
+}
+
+//FindAll lists the instructors by name
+func (s instructorImpl) FindAll() (is []types.Instructor, err error) {
+	err = cache.GetOrLoadTagged(coll+"all", []string{coll}, &is, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().Find(ctx, coll, map[string]interface{}{}, storage.FindOptions{Sort: []string{"name"}}, v)
+	})
+	return is, err
+}
+
+//Create generates the id of the instructor before storing it. Users are instructors once at most, and only become
+//one themselves unless an admin creates it.
+func (s instructorImpl) Create(ctx context.Context, i types.Instructor) (types.Instructor, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if err := checkUser(ctx, i.User); err != nil {
+		return i, err
+	}
+	i.ID = storage.NewID()
+	err := storage.GetInstance().Insert(ctx, coll, i)
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return i, err
+}
+
+//Update replaces the instructor with the same id, keeping its user. Only its user and the admins change it.
+func (s instructorImpl) Update(ctx context.Context, i types.Instructor) (types.Instructor, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	old, err := findOwn(ctx, i.ID)
+	if err != nil {
+		return i, err
+	}
+	i.User = old.User
+	err = storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": i.ID, "user": old.User}, map[string]interface{}{"$set": &i})
+	if err == nil {
+		err = invalidate(i.ID)
+	}
+	return i, err
+}
+
+//Delete removes the instructor of the id, the courses keep listing it. Only its user and the admins remove it.
+func (s instructorImpl) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if _, err := findOwn(ctx, id); err != nil {
+		return err
+	}
+	err := storage.GetInstance().Remove(ctx, coll, map[string]interface{}{"_id": id})
+	if err == nil {
+		err = invalidate(id)
+	}
+	return err
+}
+
+//findOwn finds in the storage the instructor of the id when the actor of the context may change it
+func findOwn(ctx context.Context, id string) (i types.Instructor, err error) {
+	if err = storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": id}, &i); err != nil {
+		return i, err
+	}
+	return i, checkUser(ctx, i.User)
+}
+
+//checkUser fails when the actor of the context is neither the user nor an admin
+func checkUser(ctx context.Context, user string) error {
+	if a := actor.FromContext(ctx); a != actor.Anonymous && a == user || actor.HasRole(ctx, actor.Admin) {
+		return nil
+	}
+	return ErrNotUser
+}
+
+//invalidate drops the instructor cached by id and every cached list and user lookup, which are tagged with coll
+func invalidate(id string) error {
+	err := cache.InvalidateTags(coll)
+	if cerr := cache.GetInstance().Delete(coll + id); err == nil && !cache.IsMiss(cerr) {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package instructorservice
+
+import (
+	"context"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/mock"
+)
+
+//Mock is a mocked structure for instructor service
+type Mock struct {
+	mock.Mock
+}
+
+//InitMock to initialize mock befores tests
+func (s *Mock) InitMock() {
+	instance = s
+}
+
+//Create is a mock for instructor service create
+func (s *Mock) Create(ctx context.Context, i types.Instructor) (types.Instructor, error) {
+	args := s.Called(ctx, i)
+	return args.Get(0).(types.Instructor), args.Error(1)
+}
+
+//Update is a mock for instructor service update
+func (s *Mock) Update(ctx context.Context, i types.Instructor) (types.Instructor, error) {
+	args := s.Called(ctx, i)
+	return args.Get(0).(types.Instructor), args.Error(1)
+}
+
+//FindAll is a mock for instructor service findAll
+func (s *Mock) FindAll() ([]types.Instructor, error) {
+	args := s.Called()
+	return args.Get(0).([]types.Instructor), args.Error(1)
+}
+
+//FindOne is a mock for instructor service findOne
+func (s *Mock) FindOne(id string) (types.Instructor, error) {
+	args := s.Called(id)
+	return args.Get(0).(types.Instructor), args.Error(1)
+}
+
+//FindByUser is a mock for instructor service findByUser
+func (s *Mock) FindByUser(user string) (types.Instructor, error) {
+	args := s.Called(user)
+	return args.Get(0).(types.Instructor), args.Error(1)
+}
+
+//Delete is a mock for instructor service delete
+func (s *Mock) Delete(ctx context.Context, id string) error {
+	args := s.Called(ctx, id)
+	return args.Error(0)
+}
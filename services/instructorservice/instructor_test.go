@@ -0,0 +1,101 @@
+package instructorservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemoryInstructors(t *testing.T) InstructorService {
+	testutil.Memory(t, EnsureIndexes)
+	return instructorImpl{}
+}
+
+func TestInstructor(t *testing.T) {
+	s := newMemoryInstructors(t)
+	ctx := actor.NewContext(context.Background(), "ana")
+	ana, err := s.Create(ctx, types.Instructor{User: "ana", Name: "Ana", Payout: &types.Payout{Method: types.PayoutBank, Account: "001"}})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ana.ID)
+	bob, err := s.Create(actor.NewContext(context.Background(), "bob"), types.Instructor{User: "bob", Name: "Bob"})
+	assert.NoError(t, err)
+
+	found, err := s.FindOne(ana.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ana, found)
+	found, err = s.FindByUser("bob")
+	assert.NoError(t, err)
+	assert.Equal(t, bob, found)
+
+	ana.Bio = "Teaches Go"
+	_, err = s.Update(ctx, ana)
+	assert.NoError(t, err)
+	found, err = s.FindOne(ana.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Teaches Go", found.Bio)
+
+	all, err := s.FindAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Instructor{ana, bob}, all)
+
+	assert.NoError(t, s.Delete(actor.NewContext(context.Background(), "bob"), bob.ID))
+	_, err = s.FindByUser("bob")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestInstructor_Errors(t *testing.T) {
+	s := newMemoryInstructors(t)
+	ctx := actor.NewContext(context.Background(), "ana")
+	_, err := s.Create(ctx, types.Instructor{User: "ana", Name: "Ana"})
+	assert.NoError(t, err)
+
+	_, err = s.Create(ctx, types.Instructor{User: "ana", Name: "Ana again"})
+	assert.Equal(t, storage.ErrDuplicate, err)
+	_, err = s.FindOne("missing")
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.Update(ctx, types.Instructor{ID: "missing", User: "ana", Name: "Ana"})
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, s.Delete(ctx, "missing"))
+}
+
+func TestInstructor_User(t *testing.T) {
+	s := newMemoryInstructors(t)
+	ana := actor.NewContext(context.Background(), "ana")
+	mallory := actor.NewContext(context.Background(), "mallory")
+	admin := actor.WithRoles(actor.NewContext(context.Background(), "root"), actor.Admin)
+
+	_, err := s.Create(mallory, types.Instructor{User: "ana", Name: "Ana"})
+	assert.Equal(t, ErrNotUser, err)
+	_, err = s.Create(context.Background(), types.Instructor{User: "ana", Name: "Ana"})
+	assert.Equal(t, ErrNotUser, err)
+	i, err := s.Create(ana, types.Instructor{User: "ana", Name: "Ana", Payout: &types.Payout{Method: types.PayoutBank, Account: "001"}})
+	assert.NoError(t, err)
+	_, err = s.Create(admin, types.Instructor{User: "bob", Name: "Bob"})
+	assert.NoError(t, err)
+
+	hijack := i
+	hijack.User = "mallory"
+	hijack.Payout = &types.Payout{Method: types.PayoutBank, Account: "666"}
+	_, err = s.Update(mallory, hijack)
+	assert.Equal(t, ErrNotUser, err)
+	_, err = s.Update(context.Background(), hijack)
+	assert.Equal(t, ErrNotUser, err)
+	assert.Equal(t, ErrNotUser, s.Delete(mallory, i.ID))
+
+	hijack.Payout = i.Payout
+	updated, err := s.Update(ana, hijack)
+	assert.NoError(t, err)
+	assert.Equal(t, "ana", updated.User)
+	found, err := s.FindByUser("ana")
+	assert.NoError(t, err)
+	assert.Equal(t, i, found)
+	_, err = s.FindByUser("mallory")
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	assert.NoError(t, s.Delete(admin, i.ID))
+}
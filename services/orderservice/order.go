@@ -0,0 +1,570 @@
+package orderservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/payment"
+	"github.com/ednesic/coursemanagement/services/bundleservice"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/services/promotionservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const (
+	coll       = "order"
+	refundColl = "refund"
+)
+
+var (
+	instance OrderService
+	once     sync.Once
+
+	//ErrUnknownCourse for carts of courses that do not exist
+	ErrUnknownCourse = errors.New("unknown course")
+	//ErrUnknownBundle for carts of bundles that do not exist
+	ErrUnknownBundle = errors.New("unknown bundle")
+	//ErrKeyReused for retries of an order with another cart
+	ErrKeyReused = errors.New("idempotency key used by another order")
+	//ErrCourseFull for orders of courses without a seat left
+	ErrCourseFull = errors.New("course is full")
+	//ErrNoSource for orders to pay without a payment source
+	ErrNoSource = errors.New("payment source is required")
+	//ErrNotRefundable for refunds of orders which are not paid
+	ErrNotRefundable = errors.New("order is not paid")
+	//ErrAlreadyRefunded for refunds of lines given back already
+	ErrAlreadyRefunded = errors.New("course already refunded")
+)
+
+//OrderService is an interface for order service
+type OrderService interface {
+	Create(context.Context, types.Cart, string) (types.Order, error)
+	FindOne(string) (types.Order, error)
+	FindByStudent(string) ([]types.Order, error)
+	Refund(context.Context, string, string) ([]types.Refund, error)
+	FindRefunds(string) ([]types.Refund, error)
+}
+
+type orderImpl struct{}
+
+//GetInstance to get service instance
+func GetInstance() OrderService {
+	once.Do(func() {
+		if instance == nil {
+			instance = &orderImpl{}
+		}
+	})
+	return instance
+}
+
+//EnsureIndexes creates the indexes the order service relies on, the one keeping a key to an order of each student
+//and the ones listing the orders of a student and the refunds of an order
+func EnsureIndexes(ctx context.Context) error {
+	for _, idx := range []storage.Index{
+		{Keys: []string{"student", "key"}, Unique: true},
+		{Keys: []string{"student", "createdAt"}},
+	} {
+		if err := storage.GetInstance().EnsureIndex(ctx, coll, idx); err != nil {
+			return err
+		}
+	}
+	return storage.GetInstance().EnsureIndex(ctx, refundColl, storage.Index{Keys: []string{"orderId", "at"}})
+}
+
+//FindOne finds the order by its id
+func (s orderImpl) FindOne(id string) (o types.Order, err error) {
+	err = cache.GetOrLoadTagged(coll+id, []string{coll}, &o, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": id}, v)
+	})
+	return o, err
+}
+
+//FindByStudent lists the orders of the student, the latest first
+func (s orderImpl) FindByStudent(student string) (orders []types.Order, err error) {
+	err = cache.GetOrLoadTagged(coll+"student"+student, []string{coll}, &orders, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		query := map[string]interface{}{"student": student}
+		return storage.GetInstance().Find(ctx, coll, query, storage.FindOptions{Sort: []string{"-createdAt"}}, v)
+	})
+	return orders, err
+}
+
+//FindRefunds lists the refunds of the order of the id by date
+func (s orderImpl) FindRefunds(id string) (rs []types.Refund, err error) {
+	err = cache.GetOrLoadTagged(coll+"refunds"+id, []string{coll}, &rs, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		query := map[string]interface{}{"orderId": id}
+		return storage.GetInstance().Find(ctx, refundColl, query, storage.FindOptions{Sort: []string{"at"}}, v)
+	})
+	return rs, err
+}
+
+//Create prices the cart and charges it, enrolling the student in its courses and the ones of its bundles once paid.
+//The lines which enrollment fails after the payment are refunded. Each key of a student is given to a single order:
+//retrying it resumes the payment of a pending order and answers the other orders as they are.
+func (s orderImpl) Create(ctx context.Context, cart types.Cart, key string) (types.Order, error) {
+	cs, err := courses(cart.Courses)
+	if err != nil {
+		return types.Order{}, err
+	}
+	bs, err := bundles(cart.Bundles)
+	if err != nil {
+		return types.Order{}, err
+	}
+	if key == "" {
+		key = storage.NewID()
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var o types.Order
+	err = storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"student": cart.Student, "key": key}, &o)
+	if err == storage.ErrNotFound {
+		o, err = open(ctx, cart, cs, bs, key)
+		if err == storage.ErrDuplicate {
+			return s.Create(ctx, cart, key)
+		}
+	} else if err == nil && !sameCart(o, cs, bs, cart) {
+		return o, ErrKeyReused
+	}
+	if err != nil {
+		return o, err
+	}
+	return pay(ctx, o, cart.Source)
+}
+
+//Refund gives back the payment of the line of the course or bundle of the ref, or of every line when it is empty, and
+//cancels the enrollments of the student in their courses
+func (s orderImpl) Refund(ctx context.Context, id, ref string) ([]types.Refund, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var o types.Order
+	if err := storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": id}, &o); err != nil {
+		return nil, err
+	}
+	if o.Status != types.OrderPaid {
+		return nil, ErrNotRefundable
+	}
+
+	rs, found := []types.Refund{}, false
+	for i, item := range o.Items {
+		if ref != "" && refOf(item) != ref {
+			continue
+		}
+		found = true
+		if item.Refunded {
+			if ref != "" {
+				return rs, ErrAlreadyRefunded
+			}
+			continue
+		}
+		r, err := refund(ctx, &o, i)
+		if err != nil {
+			return rs, err
+		}
+		rs = append(rs, r)
+		if err = withdraw(ctx, o.Student, coursesOf(item)); err != nil {
+			return rs, err
+		}
+	}
+	if !found {
+		return rs, storage.ErrNotFound
+	}
+	return rs, cache.InvalidateTags(coll)
+}
+
+//open prices the cart, redeeming its coupon, and stores it as a pending order
+func open(ctx context.Context, cart types.Cart, cs []types.Course, bs []types.Bundle, key string) (types.Order, error) {
+	o := types.Order{ID: storage.NewID(), Key: key, Student: cart.Student, Status: types.OrderPending, CreatedAt: time.Now().UTC()}
+	enrolled, err := enrollmentservice.GetInstance().FindByStudent(cart.Student)
+	if err != nil && !cached(err) {
+		return o, err
+	}
+	currency, taken := strings.ToUpper(cart.Currency), map[string]bool{}
+	for _, c := range cs {
+		if err := enrollable(c, enrolled); err != nil {
+			return o, err
+		}
+		q, err := promotionservice.GetInstance().Quote(c.ID, "", cart.Student, currency)
+		if err != nil && !cached(err) {
+			return o, err
+		}
+		currency = q.Price.Currency
+		taken[c.ID] = true
+		o.Items = append(o.Items, lineOf(q))
+	}
+	for _, b := range bs {
+		item, err := bundleLine(b, enrolled, taken, currency)
+		if err != nil {
+			return o, err
+		}
+		currency = item.Price.Currency
+		o.Items = append(o.Items, item)
+	}
+	for _, item := range o.Items {
+		if err := seated(coursesOf(item)); err != nil {
+			return o, err
+		}
+	}
+	if cart.Coupon != "" {
+		if err := redeem(ctx, &o, cart.Coupon, currency); err != nil {
+			return o, err
+		}
+	}
+
+	o.Total, o.Refunded = types.Money{Currency: currency}, types.Money{Currency: currency}
+	for _, item := range o.Items {
+		o.Total.Amount += item.Total.Amount
+	}
+	if o.Total.Amount > 0 && cart.Source == "" {
+		err = ErrNoSource
+	} else {
+		err = storage.GetInstance().Insert(ctx, coll, o)
+	}
+	if err != nil {
+		if rerr := release(ctx, o); rerr != nil {
+			return o, rerr
+		}
+	}
+	return o, err
+}
+
+//pay charges the pending order and enrolls its student in the courses of its lines once it is paid. Orders which are
+//not pending are answered as they are, failing again when declined.
+func pay(ctx context.Context, o types.Order, source string) (types.Order, error) {
+	switch o.Status {
+	case types.OrderPending:
+	case types.OrderDeclined:
+		return o, payment.ErrDeclined
+	default:
+		return o, nil
+	}
+
+	status := types.OrderPaid
+	if o.Total.Amount > 0 {
+		id, err := payment.GetInstance().Charge(ctx, o.ID, source, o.Total)
+		if err == payment.ErrDeclined {
+			status = types.OrderDeclined
+		} else if err != nil {
+			return o, err
+		}
+		o.Payment = id
+	}
+	err := storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": o.ID, "status": types.OrderPending},
+		map[string]interface{}{"$set": map[string]interface{}{"status": status, "payment": o.Payment}})
+	if err == storage.ErrNotFound {
+		if err = storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": o.ID}, &o); err != nil {
+			return o, err
+		}
+		return pay(ctx, o, source)
+	}
+	if err != nil {
+		return o, err
+	}
+	o.Status = status
+
+	if status == types.OrderDeclined {
+		if err = release(ctx, o); err == nil {
+			err = payment.ErrDeclined
+		}
+		if cerr := cache.InvalidateTags(coll); cerr != nil {
+			return o, cerr
+		}
+		return o, err
+	}
+	for i, item := range o.Items {
+		if err := admit(ctx, o.Student, coursesOf(item)); err == nil {
+			continue
+		}
+		if _, err = refund(ctx, &o, i); err != nil {
+			return o, err
+		}
+	}
+	return o, cache.InvalidateTags(coll)
+}
+
+//admit enrolls the student in the courses of the ids, skipping the ones the student is enrolled in already. Being
+//waitlisted fails with ErrCourseFull, the enrollments made are cancelled when one of them fails.
+func admit(ctx context.Context, student string, ids []string) error {
+	var admitted []string
+	for _, id := range ids {
+		e, err := enrollmentservice.GetInstance().Admit(ctx, id, student)
+		if err == enrollmentservice.ErrAlreadyEnrolled {
+			continue
+		}
+		if (err == nil || cached(err)) && e.Status == types.EnrollmentWaitlisted {
+			admitted, err = append(admitted, id), ErrCourseFull
+		}
+		if err != nil && !cached(err) {
+			if cerr := withdraw(ctx, student, admitted); cerr != nil {
+				return cerr
+			}
+			return err
+		}
+		admitted = append(admitted, id)
+	}
+	return nil
+}
+
+//withdraw cancels the enrollments of the student in the courses of the ids, the missing ones are not a failure
+func withdraw(ctx context.Context, student string, ids []string) error {
+	for _, id := range ids {
+		err := enrollmentservice.GetInstance().Cancel(ctx, id, student)
+		if err != nil && err != storage.ErrNotFound && !cached(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+//redeem discounts the first line of a course of the order the coupon of the code applies to
+func redeem(ctx context.Context, o *types.Order, code, currency string) error {
+	for i, item := range o.Items {
+		if item.CourseID == "" {
+			continue
+		}
+		q, err := promotionservice.GetInstance().Redeem(ctx, item.CourseID, code, o.Student, currency)
+		if err == promotionservice.ErrCouponNotApplicable {
+			continue
+		}
+		if err != nil && !cached(err) {
+			return err
+		}
+		o.Items[i] = lineOf(q)
+		return nil
+	}
+	return promotionservice.ErrCouponNotApplicable
+}
+
+//release gives back the use of the coupon of the order
+func release(ctx context.Context, o types.Order) error {
+	for _, item := range o.Items {
+		if item.Coupon == "" {
+			continue
+		}
+		err := promotionservice.GetInstance().Release(ctx, item.Coupon, o.Student, item.CourseID)
+		if err != nil && !cached(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+//refund gives back the payment of the line of the index and stores the refund along with the order. The refunds of
+//a line have the same id, so the gateway gives the payment back once.
+func refund(ctx context.Context, o *types.Order, i int) (types.Refund, error) {
+	item := o.Items[i]
+	r := types.Refund{ID: o.ID + ":" + refOf(item), OrderID: o.ID, CourseID: item.CourseID, BundleID: item.BundleID,
+		Amount: item.Total, At: time.Now().UTC()}
+	if item.Total.Amount > 0 {
+		var err error
+		if r.Payment, err = payment.GetInstance().Refund(ctx, r.ID, o.Payment, item.Total); err != nil {
+			return r, err
+		}
+	}
+
+	var refunded types.Order
+	err := storage.GetInstance().WithTransaction(ctx, func(ctx context.Context) error {
+		if err := storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": o.ID}, &refunded); err != nil {
+			return err
+		}
+		if refunded.Items[i].Refunded {
+			return ErrAlreadyRefunded
+		}
+		refunded.Items[i].Refunded = true
+		refunded.Refunded.Amount += item.Total.Amount
+		refunded.Status = types.OrderRefunded
+		for _, item := range refunded.Items {
+			if !item.Refunded {
+				refunded.Status = types.OrderPaid
+			}
+		}
+		if err := storage.GetInstance().Insert(ctx, refundColl, r); err != nil {
+			return err
+		}
+		return storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": o.ID}, map[string]interface{}{
+			"$set": map[string]interface{}{"items": refunded.Items, "refunded": refunded.Refunded, "status": refunded.Status},
+		})
+	})
+	if err == storage.ErrDuplicate {
+		return r, ErrAlreadyRefunded
+	}
+	if err == nil {
+		*o = refunded
+	}
+	return r, err
+}
+
+//courses finds the courses of the ids or slugs, leaving out the repeated ones
+func courses(refs []string) ([]types.Course, error) {
+	var cs []types.Course
+	for _, ref := range refs {
+		c, err := courseservice.GetInstance().FindOne(ref)
+		if err == storage.ErrNotFound {
+			return nil, ErrUnknownCourse
+		}
+		if err != nil && !cached(err) {
+			return nil, err
+		}
+		if !contains(cs, c.ID) {
+			cs = append(cs, c)
+		}
+	}
+	return cs, nil
+}
+
+//bundles finds the bundles of the ids, leaving out the repeated ones
+func bundles(ids []string) ([]types.Bundle, error) {
+	var bs []types.Bundle
+	for _, id := range ids {
+		b, err := bundleservice.GetInstance().FindOne(id)
+		if err == storage.ErrNotFound {
+			return nil, ErrUnknownBundle
+		}
+		if err != nil && !cached(err) {
+			return nil, err
+		}
+		if !containsBundle(bs, b.ID) {
+			bs = append(bs, b)
+		}
+	}
+	return bs, nil
+}
+
+//bundleLine prices the active bundle in the currency, or in its own when there is none, for the courses of the bundle
+//the student neither is enrolled in nor takes in another line. Bundles leaving no course to take fail with
+//enrollmentservice.ErrAlreadyEnrolled.
+func bundleLine(b types.Bundle, enrolled []types.Enrollment, taken map[string]bool, currency string) (types.LineItem, error) {
+	item := types.LineItem{BundleID: b.ID, Price: b.Price}
+	if !b.Active {
+		return item, bundleservice.ErrNotActive
+	}
+	if currency != "" {
+		var err error
+		if item.Price, err = money.Convert(b.Price, currency); err != nil {
+			return item, err
+		}
+	}
+	cs, err := courses(b.Courses)
+	if err != nil {
+		return item, err
+	}
+	for _, c := range cs {
+		err := enrollable(c, enrolled)
+		if err == enrollmentservice.ErrAlreadyEnrolled || taken[c.ID] {
+			continue
+		}
+		if err != nil {
+			return item, err
+		}
+		taken[c.ID] = true
+		item.Courses = append(item.Courses, c.ID)
+	}
+	if len(item.Courses) == 0 {
+		return item, enrollmentservice.ErrAlreadyEnrolled
+	}
+	item.Discount, item.Total = types.Money{Currency: item.Price.Currency}, item.Price
+	return item, nil
+}
+
+//enrollable fails for the courses not open for enrollment and the ones the student is enrolled in or waiting for
+func enrollable(c types.Course, enrolled []types.Enrollment) error {
+	if c.Status != types.StatusPublished && c.Status != "" {
+		return enrollmentservice.ErrNotEnrollable
+	}
+	for _, e := range enrolled {
+		if e.CourseID == c.ID && e.Status != types.EnrollmentCancelled {
+			return enrollmentservice.ErrAlreadyEnrolled
+		}
+	}
+	return nil
+}
+
+//seated fails with ErrCourseFull for the courses of the ids without a seat left
+func seated(ids []string) error {
+	for _, id := range ids {
+		seats, err := enrollmentservice.GetInstance().Seats(id)
+		if err != nil && !cached(err) {
+			return err
+		}
+		if seats.Capacity > 0 && seats.Enrolled >= seats.Capacity {
+			return ErrCourseFull
+		}
+	}
+	return nil
+}
+
+//sameCart reports whether the order is the one of the cart, so retrying its key is not a new order
+func sameCart(o types.Order, cs []types.Course, bs []types.Bundle, cart types.Cart) bool {
+	if len(o.Items) != len(cs)+len(bs) {
+		return false
+	}
+	coupon := ""
+	for i, item := range o.Items {
+		if i < len(cs) && item.CourseID != cs[i].ID || i >= len(cs) && item.BundleID != bs[i-len(cs)].ID {
+			return false
+		}
+		if item.Coupon != "" {
+			coupon = item.Coupon
+		}
+	}
+	if cart.Currency != "" && !strings.EqualFold(cart.Currency, o.Total.Currency) {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(cart.Coupon), coupon)
+}
+
+func lineOf(q types.Quote) types.LineItem {
+	return types.LineItem{CourseID: q.CourseID, Coupon: q.Coupon, Price: q.Price, Discount: q.Discount, Total: q.Total}
+}
+
+//refOf is the id of the course or bundle of the line
+func refOf(item types.LineItem) string {
+	if item.BundleID != "" {
+		return item.BundleID
+	}
+	return item.CourseID
+}
+
+//coursesOf lists the ids of the courses the line enrolls in
+func coursesOf(item types.LineItem) []string {
+	if item.BundleID != "" {
+		return item.Courses
+	}
+	return []string{item.CourseID}
+}
+
+//cached reports whether err is a failure of the cache, which the services carry on after
+func cached(err error) bool {
+	_, ok := err.(*cache.RedisErr)
+	return ok
+}
+
+func contains(cs []types.Course, id string) bool {
+	for _, c := range cs {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsBundle(bs []types.Bundle, id string) bool {
+	for _, b := range bs {
+		if b.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package orderservice
+
+import (
+	"context"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/mock"
+)
+
+//Mock is a mocked structure for order service
+type Mock struct {
+	mock.Mock
+}
+
+//InitMock to initialize mock befores tests
+func (s *Mock) InitMock() {
+	instance = s
+}
+
+//Create is a mock for order service create
+func (s *Mock) Create(ctx context.Context, cart types.Cart, key string) (types.Order, error) {
+	args := s.Called(ctx, cart, key)
+	return args.Get(0).(types.Order), args.Error(1)
+}
+
+//FindOne is a mock for order service findOne
+func (s *Mock) FindOne(id string) (types.Order, error) {
+	args := s.Called(id)
+	return args.Get(0).(types.Order), args.Error(1)
+}
+
+//FindByStudent is a mock for order service findByStudent
+func (s *Mock) FindByStudent(student string) ([]types.Order, error) {
+	args := s.Called(student)
+	return args.Get(0).([]types.Order), args.Error(1)
+}
+
+//Refund is a mock for order service refund
+func (s *Mock) Refund(ctx context.Context, id, courseID string) ([]types.Refund, error) {
+	args := s.Called(ctx, id, courseID)
+	return args.Get(0).([]types.Refund), args.Error(1)
+}
+
+//FindRefunds is a mock for order service findRefunds
+func (s *Mock) FindRefunds(id string) ([]types.Refund, error) {
+	args := s.Called(id)
+	return args.Get(0).([]types.Refund), args.Error(1)
+}
@@ -0,0 +1,308 @@
+package orderservice
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/payment"
+	"github.com/ednesic/coursemanagement/services/bundleservice"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/services/promotionservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemoryOrders(t *testing.T) OrderService {
+	testutil.Memory(t, courseservice.EnsureIndexes, enrollmentservice.EnsureIndexes, promotionservice.EnsureIndexes,
+		bundleservice.EnsureIndexes, EnsureIndexes)
+	assert.NoError(t, payment.NewFake().Initialize(map[string]string{}))
+	return orderImpl{}
+}
+
+//statuses of the enrollments of the student by course id
+func statuses(t *testing.T, student string) map[string]string {
+	es, err := enrollmentservice.GetInstance().FindByStudent(student)
+	assert.NoError(t, err)
+	m := map[string]string{}
+	for _, e := range es {
+		m[e.CourseID] = e.Status
+	}
+	return m
+}
+
+func couponUses(t *testing.T, code string) int64 {
+	cp, err := promotionservice.GetInstance().FindOne(code)
+	assert.NoError(t, err)
+	return cp.Uses
+}
+
+func TestCreate_Paid(t *testing.T) {
+	s := newMemoryOrders(t)
+	ctx := context.Background()
+	goCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Price: testutil.USD(1000)})
+	sqlCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "SQL", Price: testutil.USD(2000)})
+	_, err := promotionservice.GetInstance().Create(ctx, types.Coupon{Code: "SQL", Kind: types.CouponPercent, Percent: 50, Courses: []string{sqlCourse.ID}})
+	assert.NoError(t, err)
+
+	cart := types.Cart{Student: "ana", Courses: []string{"go", "sql", goCourse.ID}, Coupon: "sql", Source: "tok_visa"}
+	o, err := s.Create(ctx, cart, "")
+	assert.NoError(t, err)
+	assert.Equal(t, types.OrderPaid, o.Status)
+	assert.NotEmpty(t, o.Key)
+	assert.NotEmpty(t, o.Payment)
+	assert.Equal(t, []types.LineItem{
+		{CourseID: goCourse.ID, Price: testutil.USD(1000), Discount: testutil.USD(0), Total: testutil.USD(1000)},
+		{CourseID: sqlCourse.ID, Coupon: "SQL", Price: testutil.USD(2000), Discount: testutil.USD(1000), Total: testutil.USD(1000)},
+	}, o.Items)
+	assert.Equal(t, testutil.USD(2000), o.Total)
+	assert.Equal(t, map[string]string{goCourse.ID: types.EnrollmentActive, sqlCourse.ID: types.EnrollmentActive}, statuses(t, "ana"))
+	assert.Equal(t, int64(1), couponUses(t, "SQL"))
+
+	found, err := s.FindOne(o.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, o.Status, found.Status)
+	orders, err := s.FindByStudent("ana")
+	assert.NoError(t, err)
+	assert.Len(t, orders, 1)
+
+	_, err = s.Create(ctx, types.Cart{Student: "ana", Courses: []string{"go"}, Source: "tok_visa"}, "")
+	assert.Equal(t, enrollmentservice.ErrAlreadyEnrolled, err)
+}
+
+func TestCreate_Free(t *testing.T) {
+	s := newMemoryOrders(t)
+	c := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Price: testutil.USD(0)})
+
+	o, err := s.Create(context.Background(), types.Cart{Student: "ana", Courses: []string{c.ID}}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, types.OrderPaid, o.Status)
+	assert.Empty(t, o.Payment)
+	assert.Equal(t, types.EnrollmentActive, statuses(t, "ana")[c.ID])
+}
+
+func TestCreate_Bundle(t *testing.T) {
+	s := newMemoryOrders(t)
+	ctx := context.Background()
+	goCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Price: testutil.USD(1000)})
+	sqlCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "SQL", Price: testutil.USD(2000)})
+	rustCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Rust", Price: testutil.USD(0)})
+	b, err := bundleservice.GetInstance().Create(ctx, types.Bundle{Name: "Backend", Courses: []string{"go", "sql", "rust"}, Price: testutil.USD(2500), Active: true})
+	assert.NoError(t, err)
+	_, err = enrollmentservice.GetInstance().Enroll(ctx, rustCourse.ID, "ana")
+	assert.NoError(t, err)
+
+	o, err := s.Create(ctx, types.Cart{Student: "ana", Courses: []string{"go"}, Bundles: []string{b.ID, b.ID}, Source: "tok_visa"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, types.OrderPaid, o.Status)
+	assert.Equal(t, []types.LineItem{
+		{CourseID: goCourse.ID, Price: testutil.USD(1000), Discount: testutil.USD(0), Total: testutil.USD(1000)},
+		{BundleID: b.ID, Courses: []string{sqlCourse.ID}, Price: testutil.USD(2500), Discount: testutil.USD(0), Total: testutil.USD(2500)},
+	}, o.Items)
+	assert.Equal(t, testutil.USD(3500), o.Total)
+	assert.Equal(t, map[string]string{goCourse.ID: types.EnrollmentActive, sqlCourse.ID: types.EnrollmentActive,
+		rustCourse.ID: types.EnrollmentActive}, statuses(t, "ana"))
+
+	rs, err := s.Refund(ctx, o.ID, b.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.Refund{{ID: o.ID + ":" + b.ID, OrderID: o.ID, BundleID: b.ID, Amount: testutil.USD(2500),
+		Payment: rs[0].Payment, At: rs[0].At}}, rs)
+	assert.Equal(t, map[string]string{goCourse.ID: types.EnrollmentActive, sqlCourse.ID: types.EnrollmentCancelled,
+		rustCourse.ID: types.EnrollmentActive}, statuses(t, "ana"))
+	_, err = s.Refund(ctx, o.ID, b.ID)
+	assert.Equal(t, ErrAlreadyRefunded, err)
+}
+
+func TestCreate_Errors(t *testing.T) {
+	s := newMemoryOrders(t)
+	ctx := context.Background()
+	testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Price: testutil.USD(1000)})
+	_, err := courseservice.GetInstance().Create(ctx, types.Course{Name: "Draft", Price: testutil.USD(1000)})
+	assert.NoError(t, err)
+	_, err = promotionservice.GetInstance().Create(ctx, types.Coupon{Code: "OTHER", Kind: types.CouponPercent, Percent: 10, Courses: []string{"draft"}})
+	assert.NoError(t, err)
+	testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "SQL", Price: testutil.USD(2000)})
+	inactive, err := bundleservice.GetInstance().Create(ctx, types.Bundle{Name: "Inactive", Courses: []string{"go", "sql"}, Price: testutil.USD(2500)})
+	assert.NoError(t, err)
+	backend, err := bundleservice.GetInstance().Create(ctx, types.Bundle{Name: "Backend", Courses: []string{"go", "sql"}, Price: testutil.USD(2500), Active: true})
+	assert.NoError(t, err)
+	drafts, err := bundleservice.GetInstance().Create(ctx, types.Bundle{Name: "Drafts", Courses: []string{"go", "draft"}, Price: testutil.USD(1500), Active: true})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		cart types.Cart
+		err  error
+	}{
+		{"Unknown course", types.Cart{Courses: []string{"go", "missing"}, Source: "tok_visa"}, ErrUnknownCourse},
+		{"Not enrollable", types.Cart{Courses: []string{"go", "draft"}, Source: "tok_visa"}, enrollmentservice.ErrNotEnrollable},
+		{"Unknown bundle", types.Cart{Courses: []string{"go"}, Bundles: []string{"missing"}, Source: "tok_visa"}, ErrUnknownBundle},
+		{"Bundle not active", types.Cart{Bundles: []string{inactive.ID}, Source: "tok_visa"}, bundleservice.ErrNotActive},
+		{"Bundle not enrollable", types.Cart{Bundles: []string{drafts.ID}, Source: "tok_visa"}, enrollmentservice.ErrNotEnrollable},
+		{"Bundle already taken", types.Cart{Courses: []string{"go", "sql"}, Bundles: []string{backend.ID}, Source: "tok_visa"}, enrollmentservice.ErrAlreadyEnrolled},
+		{"No source", types.Cart{Courses: []string{"go"}}, ErrNoSource},
+		{"Unknown coupon", types.Cart{Courses: []string{"go"}, Coupon: "NOPE", Source: "tok_visa"}, promotionservice.ErrUnknownCoupon},
+		{"Coupon not applicable", types.Cart{Courses: []string{"go"}, Coupon: "OTHER", Source: "tok_visa"}, promotionservice.ErrCouponNotApplicable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cart.Student = "ana"
+			_, err := s.Create(ctx, tt.cart, "")
+			assert.Equal(t, tt.err, err)
+		})
+	}
+	orders, err := s.FindByStudent("ana")
+	assert.NoError(t, err)
+	assert.Empty(t, orders)
+}
+
+func TestCreate_Declined(t *testing.T) {
+	s := newMemoryOrders(t)
+	ctx := context.Background()
+	c := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Price: testutil.USD(1000)})
+	_, err := promotionservice.GetInstance().Create(ctx, types.Coupon{Code: "ONCE", Kind: types.CouponPercent, Percent: 10, MaxUses: 1})
+	assert.NoError(t, err)
+
+	cart := types.Cart{Student: "ana", Courses: []string{c.ID}, Coupon: "ONCE", Source: payment.DeclinedSource}
+	o, err := s.Create(ctx, cart, "key1")
+	assert.Equal(t, payment.ErrDeclined, err)
+	assert.Equal(t, types.OrderDeclined, o.Status)
+	assert.Empty(t, statuses(t, "ana"))
+	assert.Equal(t, int64(0), couponUses(t, "ONCE"))
+
+	cart.Source = "tok_visa"
+	again, err := s.Create(ctx, cart, "key1")
+	assert.Equal(t, payment.ErrDeclined, err)
+	assert.Equal(t, o.ID, again.ID)
+	o, err = s.Create(ctx, cart, "key2")
+	assert.NoError(t, err)
+	assert.Equal(t, types.OrderPaid, o.Status)
+	assert.Equal(t, int64(1), couponUses(t, "ONCE"))
+}
+
+func TestCreate_Retries(t *testing.T) {
+	s := newMemoryOrders(t)
+	ctx := context.Background()
+	goCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Price: testutil.USD(1000)})
+	sqlCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "SQL", Price: testutil.USD(2000)})
+	_, err := promotionservice.GetInstance().Create(ctx, types.Coupon{Code: "LAUNCH", Kind: types.CouponPercent, Percent: 10})
+	assert.NoError(t, err)
+	cart := types.Cart{Student: "ana", Courses: []string{goCourse.ID}, Coupon: "launch", Source: "tok_visa"}
+
+	var wg sync.WaitGroup
+	ids := make([]string, 10)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			o, err := s.Create(ctx, cart, "key1")
+			assert.NoError(t, err)
+			ids[i] = o.ID
+		}(i)
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		assert.Equal(t, ids[0], id)
+	}
+	n, err := storage.GetInstance().Count(ctx, coll, map[string]interface{}{"student": "ana"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, int64(1), couponUses(t, "LAUNCH"))
+
+	_, err = s.Create(ctx, types.Cart{Student: "ana", Courses: []string{sqlCourse.ID}, Source: "tok_visa"}, "key1")
+	assert.Equal(t, ErrKeyReused, err)
+	_, err = s.Create(ctx, types.Cart{Student: "ana", Courses: []string{goCourse.ID}, Source: "tok_visa"}, "key1")
+	assert.Equal(t, ErrKeyReused, err)
+	o, err := s.Create(ctx, types.Cart{Student: "bob", Courses: []string{sqlCourse.ID}, Source: "tok_visa"}, "key1")
+	assert.NoError(t, err)
+	assert.NotEqual(t, ids[0], o.ID)
+}
+
+func TestCreate_ResumesPending(t *testing.T) {
+	s := newMemoryOrders(t)
+	ctx := context.Background()
+	c := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Price: testutil.USD(1000)})
+	pending := types.Order{ID: "order1", Key: "key1", Student: "ana", Status: types.OrderPending, Total: testutil.USD(1000), Refunded: testutil.USD(0),
+		Items: []types.LineItem{{CourseID: c.ID, Price: testutil.USD(1000), Discount: testutil.USD(0), Total: testutil.USD(1000)}}}
+	assert.NoError(t, storage.GetInstance().Insert(ctx, coll, pending))
+
+	o, err := s.Create(ctx, types.Cart{Student: "ana", Courses: []string{"go"}, Source: "tok_visa"}, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "order1", o.ID)
+	assert.Equal(t, types.OrderPaid, o.Status)
+	assert.Equal(t, types.EnrollmentActive, statuses(t, "ana")[c.ID])
+}
+
+func TestCreate_Full(t *testing.T) {
+	s := newMemoryOrders(t)
+	ctx := context.Background()
+	c := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Price: testutil.USD(1000)})
+	c.Capacity = 1
+	_, err := courseservice.GetInstance().Update(ctx, c)
+	assert.NoError(t, err)
+	_, err = enrollmentservice.GetInstance().Admit(ctx, c.ID, "bob")
+	assert.NoError(t, err)
+
+	_, err = s.Create(ctx, types.Cart{Student: "ana", Courses: []string{"go"}, Source: "tok_visa"}, "")
+	assert.Equal(t, ErrCourseFull, err)
+	orders, err := s.FindByStudent("ana")
+	assert.NoError(t, err)
+	assert.Empty(t, orders)
+
+	pending := types.Order{ID: "order1", Key: "key1", Student: "ana", Status: types.OrderPending, Total: testutil.USD(1000), Refunded: testutil.USD(0),
+		Items: []types.LineItem{{CourseID: c.ID, Price: testutil.USD(1000), Discount: testutil.USD(0), Total: testutil.USD(1000)}}}
+	assert.NoError(t, storage.GetInstance().Insert(ctx, coll, pending))
+	o, err := s.Create(ctx, types.Cart{Student: "ana", Courses: []string{"go"}, Source: "tok_visa"}, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, types.OrderRefunded, o.Status)
+	assert.Equal(t, testutil.USD(1000), o.Refunded)
+	assert.Equal(t, types.EnrollmentCancelled, statuses(t, "ana")[c.ID])
+	seats, err := enrollmentservice.GetInstance().Seats(c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), seats.Waitlisted)
+}
+
+func TestRefund(t *testing.T) {
+	s := newMemoryOrders(t)
+	ctx := context.Background()
+	goCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go", Price: testutil.USD(1000)})
+	sqlCourse := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "SQL", Price: testutil.USD(2000)})
+	o, err := s.Create(ctx, types.Cart{Student: "ana", Courses: []string{goCourse.ID, sqlCourse.ID}, Source: "tok_visa"}, "")
+	assert.NoError(t, err)
+
+	rs, err := s.Refund(ctx, o.ID, sqlCourse.ID)
+	assert.NoError(t, err)
+	assert.Len(t, rs, 1)
+	assert.Equal(t, testutil.USD(2000), rs[0].Amount)
+	assert.NotEmpty(t, rs[0].Payment)
+	assert.Equal(t, map[string]string{goCourse.ID: types.EnrollmentActive, sqlCourse.ID: types.EnrollmentCancelled}, statuses(t, "ana"))
+	o, err = s.FindOne(o.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.OrderPaid, o.Status)
+	assert.Equal(t, testutil.USD(2000), o.Refunded)
+
+	_, err = s.Refund(ctx, o.ID, sqlCourse.ID)
+	assert.Equal(t, ErrAlreadyRefunded, err)
+	_, err = s.Refund(ctx, o.ID, "missing")
+	assert.Equal(t, storage.ErrNotFound, err)
+	rs, err = s.Refund(ctx, o.ID, "")
+	assert.NoError(t, err)
+	assert.Len(t, rs, 1)
+	assert.Equal(t, goCourse.ID, rs[0].CourseID)
+
+	o, err = s.FindOne(o.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, types.OrderRefunded, o.Status)
+	assert.Equal(t, testutil.USD(3000), o.Refunded)
+	rs, err = s.FindRefunds(o.ID)
+	assert.NoError(t, err)
+	assert.Len(t, rs, 2)
+	_, err = s.Refund(ctx, o.ID, "")
+	assert.Equal(t, ErrNotRefundable, err)
+	_, err = s.Refund(ctx, "missing", "")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
@@ -0,0 +1,259 @@
+package progressservice
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const (
+	coll            = "progress"
+	certificateColl = "certificate"
+
+	//completeWatched is the percent of the video of a lesson a student watches to complete it
+	completeWatched = 90
+)
+
+var (
+	instance ProgressService
+	once     sync.Once
+	secret   []byte
+
+	//ErrNotEnrolled for progress in a course the student does not have a seat in
+	ErrNotEnrolled = errors.New("student not enrolled in the course")
+)
+
+//ProgressService is an interface for progress service
+type ProgressService interface {
+	Record(context.Context, string, string, string, float64, bool) (types.Progress, error)
+	Progress(string, string) (types.Progress, error)
+	Certificate(string) (types.Certificate, error)
+	Verify(types.Certificate) bool
+}
+
+type progressImpl struct{}
+
+//GetInstance to get service instance
+func GetInstance() ProgressService {
+	once.Do(func() {
+		if instance == nil {
+			instance = &progressImpl{}
+		}
+	})
+	return instance
+}
+
+//SetSecret sets the key signing the certificates, certificates signed with another key do not verify
+func SetSecret(key []byte) {
+	secret = key
+}
+
+//EnsureIndexes creates the indexes the progress service relies on, one certificate per student and course
+func EnsureIndexes(ctx context.Context) error {
+	if err := storage.GetInstance().EnsureIndex(ctx, coll, storage.Index{Keys: []string{"student"}}); err != nil {
+		return err
+	}
+	return storage.GetInstance().EnsureIndex(ctx, certificateColl, storage.Index{Keys: []string{"courseId", "student"}, Unique: true})
+}
+
+//Record stores the progress of the student in the lesson of the course of the id or slug. The lesson is completed
+//when the student says so or watched most of its video. Completing the last required lesson issues the certificate.
+func (s progressImpl) Record(ctx context.Context, student, ref, lessonID string, watched float64, completed bool) (types.Progress, error) {
+	c, modules, err := enrolledCourse(student, ref)
+	if err != nil {
+		return types.Progress{}, err
+	}
+	if !hasLesson(modules, lessonID) {
+		return types.Progress{}, storage.ErrNotFound
+	}
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var p types.Progress
+	now := time.Now().UTC()
+	err = storage.GetInstance().WithTransaction(ctx, func(ctx context.Context) error {
+		if err := findProgress(ctx, c.ID, student, &p); err != nil {
+			return err
+		}
+		found := len(p.Lessons) > 0
+		lp, ok := p.Lessons[lessonID]
+		if !ok {
+			lp.StartedAt = now
+		}
+		if watched > lp.Watched {
+			lp.Watched = watched
+		}
+		if lp.CompletedAt == nil && (completed || lp.Watched >= completeWatched) {
+			lp.CompletedAt = &now
+		}
+		p.Lessons[lessonID] = lp
+
+		summarize(&p, modules)
+		if p.Required > 0 && p.Completed == p.Required && p.CertificateID == "" {
+			cert := issue(c, student, now)
+			if err := storage.GetInstance().Insert(ctx, certificateColl, cert); err != nil {
+				return err
+			}
+			p.CertificateID, p.CompletedAt = cert.ID, &now
+		}
+		if !found {
+			return storage.GetInstance().Insert(ctx, coll, p)
+		}
+		return storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": p.ID}, map[string]interface{}{"$set": &p})
+	})
+	if err == nil {
+		err = cache.InvalidateTags(coll + p.ID)
+	}
+	return p, err
+}
+
+//Progress finds the progress of the student in the course of the id or slug. Students enrolled in the course without
+//progress yet have an empty one, students who left it keep the progress they made.
+func (s progressImpl) Progress(student, ref string) (p types.Progress, err error) {
+	c, modules, err := enrolledCourse(student, ref)
+	if err != nil && err != ErrNotEnrolled {
+		return p, err
+	}
+	notEnrolled := err == ErrNotEnrolled
+	id := progressID(c.ID, student)
+	err = cache.GetOrLoadTagged(coll+id, []string{coll + id}, &p, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return findProgress(ctx, c.ID, student, v.(*types.Progress))
+	})
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return p, err
+	}
+	if notEnrolled && len(p.Lessons) == 0 {
+		return types.Progress{}, ErrNotEnrolled
+	}
+	if p.Lessons == nil {
+		p.Lessons = map[string]types.LessonProgress{}
+	}
+	summarize(&p, modules)
+	return p, err
+}
+
+//Certificate finds the certificate of the id
+func (s progressImpl) Certificate(id string) (cert types.Certificate, err error) {
+	err = cache.GetOrLoad(certificateColl+id, &cert, time.Hour, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().FindOne(ctx, certificateColl, map[string]interface{}{"_id": id}, v)
+	})
+	return cert, err
+}
+
+//Verify reports whether the certificate was issued by the service as it is
+func (s progressImpl) Verify(cert types.Certificate) bool {
+	got, err := hex.DecodeString(cert.Signature)
+	if err != nil {
+		return false
+	}
+	want, err := hex.DecodeString(sign(cert))
+	return err == nil && hmac.Equal(got, want)
+}
+
+//enrolledCourse finds the course of the id or slug and its curriculum, failing with ErrNotEnrolled, along with the
+//course, when the student does not have a seat in it
+func enrolledCourse(student, ref string) (types.Course, []types.Module, error) {
+	c, err := courseservice.GetInstance().FindOne(ref)
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return c, nil, err
+	}
+	modules, err := courseservice.GetInstance().Curriculum(c.ID)
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return c, nil, err
+	}
+	es, err := enrollmentservice.GetInstance().FindByStudent(student)
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return c, nil, err
+	}
+	for _, e := range es {
+		if e.CourseID == c.ID && e.Status == types.EnrollmentActive {
+			return c, modules, nil
+		}
+	}
+	return c, modules, ErrNotEnrolled
+}
+
+//findProgress finds the progress of the student in the course, p is an empty one when there is none yet
+func findProgress(ctx context.Context, courseID, student string, p *types.Progress) error {
+	err := storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": progressID(courseID, student)}, p)
+	if err == storage.ErrNotFound {
+		*p, err = types.Progress{ID: progressID(courseID, student), CourseID: courseID, Student: student}, nil
+	}
+	if p.Lessons == nil {
+		p.Lessons = map[string]types.LessonProgress{}
+	}
+	return err
+}
+
+//summarize counts the required lessons of the modules and the ones the student completed
+func summarize(p *types.Progress, modules []types.Module) {
+	p.Required, p.Completed, p.Percent = 0, 0, 0
+	for _, m := range modules {
+		for _, l := range m.Lessons {
+			if l.Optional {
+				continue
+			}
+			p.Required++
+			if lp, ok := p.Lessons[l.ID]; ok && lp.CompletedAt != nil {
+				p.Completed++
+			}
+		}
+	}
+	if p.Required > 0 {
+		p.Percent = float64(p.Completed) * 100 / float64(p.Required)
+	}
+}
+
+func hasLesson(modules []types.Module, id string) bool {
+	for _, m := range modules {
+		for _, l := range m.Lessons {
+			if l.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//issue signs a new certificate of the course for the student. Its time is kept to the second, the precision
+//surviving every store, as it is signed.
+func issue(c types.Course, student string, at time.Time) types.Certificate {
+	cert := types.Certificate{
+		ID:         storage.NewID(),
+		CourseID:   c.ID,
+		CourseName: c.Name,
+		Student:    student,
+		IssuedAt:   at.Truncate(time.Second),
+	}
+	cert.Signature = sign(cert)
+	return cert
+}
+
+//sign is the hex HMAC-SHA256 of the json of the certificate without its signature
+func sign(cert types.Certificate) string {
+	cert.Signature = ""
+	cert.IssuedAt = cert.IssuedAt.UTC()
+	payload, _ := json.Marshal(cert)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func progressID(courseID, student string) string {
+	return courseID + ":" + student
+}
@@ -0,0 +1,42 @@
+package progressservice
+
+import (
+	"context"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/mock"
+)
+
+//Mock is a mocked structure for progress service
+type Mock struct {
+	mock.Mock
+}
+
+//InitMock to initialize mock befores tests
+func (s *Mock) InitMock() {
+	instance = s
+}
+
+//Record is a mock for progress service record
+func (s *Mock) Record(ctx context.Context, student, ref, lessonID string, watched float64, completed bool) (types.Progress, error) {
+	args := s.Called(ctx, student, ref, lessonID, watched, completed)
+	return args.Get(0).(types.Progress), args.Error(1)
+}
+
+//Progress is a mock for progress service progress
+func (s *Mock) Progress(student, ref string) (types.Progress, error) {
+	args := s.Called(student, ref)
+	return args.Get(0).(types.Progress), args.Error(1)
+}
+
+//Certificate is a mock for progress service certificate
+func (s *Mock) Certificate(id string) (types.Certificate, error) {
+	args := s.Called(id)
+	return args.Get(0).(types.Certificate), args.Error(1)
+}
+
+//Verify is a mock for progress service verify
+func (s *Mock) Verify(cert types.Certificate) bool {
+	args := s.Called(cert)
+	return args.Bool(0)
+}
@@ -0,0 +1,130 @@
+package progressservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemoryProgress(t *testing.T) ProgressService {
+	testutil.Memory(t, courseservice.EnsureIndexes, enrollmentservice.EnsureIndexes, EnsureIndexes)
+	SetSecret([]byte("secret"))
+	return progressImpl{}
+}
+
+//courseWithLessons creates a published course with a module of a required and an optional lesson
+func courseWithLessons(t *testing.T) (types.Course, types.Lesson, types.Lesson) {
+	ctx := context.Background()
+	c, err := courseservice.GetInstance().Create(ctx, types.Course{Name: "Go"})
+	assert.NoError(t, err)
+	m, err := courseservice.GetInstance().AddModule(ctx, c.ID, types.Module{Title: "Basics"})
+	assert.NoError(t, err)
+	required, err := courseservice.GetInstance().AddLesson(ctx, c.ID, m.ID, types.Lesson{Title: "Types", ContentType: types.ContentVideo, Duration: 600})
+	assert.NoError(t, err)
+	optional, err := courseservice.GetInstance().AddLesson(ctx, c.ID, m.ID, types.Lesson{Title: "Extras", ContentType: types.ContentArticle, Optional: true})
+	assert.NoError(t, err)
+	_, err = courseservice.GetInstance().Transition(ctx, c.ID, types.StatusInReview, time.Time{}, 0)
+	assert.NoError(t, err)
+	c, err = courseservice.GetInstance().Transition(ctx, c.ID, types.StatusPublished, time.Time{}, 0)
+	assert.NoError(t, err)
+	return c, required, optional
+}
+
+func TestRecord_CompletesAndIssuesCertificate(t *testing.T) {
+	s := newMemoryProgress(t)
+	ctx := context.Background()
+	c, required, optional := courseWithLessons(t)
+	_, err := enrollmentservice.GetInstance().Enroll(ctx, c.ID, "ana")
+	assert.NoError(t, err)
+
+	p, err := s.Progress("ana", c.Slug)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, p.Required)
+	assert.Equal(t, 0, p.Completed)
+
+	p, err = s.Record(ctx, "ana", c.Slug, optional.ID, 0, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, p.Completed)
+	p, err = s.Record(ctx, "ana", c.ID, required.ID, 50, false)
+	assert.NoError(t, err)
+	assert.Nil(t, p.Lessons[required.ID].CompletedAt)
+	assert.Equal(t, float64(0), p.Percent)
+	p, err = s.Record(ctx, "ana", c.ID, required.ID, 30, false)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(50), p.Lessons[required.ID].Watched)
+	assert.Empty(t, p.CertificateID)
+
+	p, err = s.Record(ctx, "ana", c.ID, required.ID, 95, false)
+	assert.NoError(t, err)
+	assert.NotNil(t, p.Lessons[required.ID].CompletedAt)
+	assert.Equal(t, float64(100), p.Percent)
+	assert.NotNil(t, p.CompletedAt)
+	assert.NotEmpty(t, p.CertificateID)
+
+	got, err := s.Progress("ana", c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, p.CertificateID, got.CertificateID)
+	assert.Equal(t, 1, got.Completed)
+
+	cert, err := s.Certificate(p.CertificateID)
+	assert.NoError(t, err)
+	assert.Equal(t, "ana", cert.Student)
+	assert.Equal(t, "Go", cert.CourseName)
+	assert.True(t, s.Verify(cert))
+
+	p, err = s.Record(ctx, "ana", c.ID, required.ID, 100, true)
+	assert.NoError(t, err)
+	assert.Equal(t, cert.ID, p.CertificateID)
+}
+
+func TestRecord_Errors(t *testing.T) {
+	s := newMemoryProgress(t)
+	ctx := context.Background()
+	c, required, _ := courseWithLessons(t)
+
+	_, err := s.Record(ctx, "ana", c.ID, required.ID, 100, false)
+	assert.Equal(t, ErrNotEnrolled, err)
+	_, err = s.Progress("ana", c.ID)
+	assert.Equal(t, ErrNotEnrolled, err)
+
+	_, err = enrollmentservice.GetInstance().Enroll(ctx, c.ID, "ana")
+	assert.NoError(t, err)
+	_, err = s.Record(ctx, "ana", c.ID, "unknown", 100, false)
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.Record(ctx, "ana", "unknown", required.ID, 100, false)
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.Certificate("unknown")
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	_, err = s.Record(ctx, "ana", c.ID, required.ID, 10, false)
+	assert.NoError(t, err)
+	assert.NoError(t, enrollmentservice.GetInstance().Cancel(ctx, c.ID, "ana"))
+	p, err := s.Progress("ana", c.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(10), p.Lessons[required.ID].Watched)
+	_, err = s.Record(ctx, "ana", c.ID, required.ID, 20, false)
+	assert.Equal(t, ErrNotEnrolled, err)
+}
+
+func TestVerify(t *testing.T) {
+	s := newMemoryProgress(t)
+	cert := issue(types.Course{ID: "1", Name: "Go"}, "ana", time.Now())
+	assert.True(t, s.Verify(cert))
+
+	forged := cert
+	forged.Student = "eve"
+	assert.False(t, s.Verify(forged))
+	forged = cert
+	forged.Signature = "not hex"
+	assert.False(t, s.Verify(forged))
+
+	SetSecret([]byte("another"))
+	assert.False(t, s.Verify(cert))
+}
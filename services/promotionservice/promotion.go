@@ -0,0 +1,378 @@
+package promotionservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/services/categoryservice"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const (
+	coll           = "coupon"
+	redemptionColl = "redemption"
+)
+
+var (
+	instance PromotionService
+	once     sync.Once
+
+	//ErrInvalidCoupon for percent coupons without a percentage, fixed ones without an amount or validity windows
+	//ending before they start
+	ErrInvalidCoupon = errors.New("invalid coupon")
+	//ErrUnknownCoupon for quotes with a code that is not a coupon
+	ErrUnknownCoupon = errors.New("unknown coupon")
+	//ErrCouponNotApplicable for coupons out of their validity window or of the scope of the course
+	ErrCouponNotApplicable = errors.New("coupon does not apply")
+	//ErrCouponExhausted for coupons redeemed as many times as they may be, overall or by the student
+	ErrCouponExhausted = errors.New("coupon usage limit reached")
+)
+
+//PromotionService is an interface for promotion service
+type PromotionService interface {
+	Create(context.Context, types.Coupon) (types.Coupon, error)
+	Update(context.Context, types.Coupon) (types.Coupon, error)
+	Delete(context.Context, string) error
+	FindAll() ([]types.Coupon, error)
+	FindOne(string) (types.Coupon, error)
+	Quote(string, string, string, string) (types.Quote, error)
+	Redeem(context.Context, string, string, string, string) (types.Quote, error)
+	Release(context.Context, string, string, string) error
+}
+
+type promotionImpl struct{}
+
+//GetInstance to get service instance
+func GetInstance() PromotionService {
+	once.Do(func() {
+		if instance == nil {
+			instance = &promotionImpl{}
+		}
+	})
+	return instance
+}
+
+//EnsureIndexes creates the indexes the promotion service relies on, the one counting the redemptions of a student
+func EnsureIndexes(ctx context.Context) error {
+	return storage.GetInstance().EnsureIndex(ctx, redemptionColl, storage.Index{Keys: []string{"code", "student"}})
+}
+
+//FindOne finds the coupon by its code, whatever its case
+func (s promotionImpl) FindOne(code string) (cp types.Coupon, err error) {
+	code = normalizeCode(code)
+	err = cache.GetOrLoadTagged(coll+code, []string{coll}, &cp, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": code}, v)
+	})
+	return cp, err
+}
+
+//FindAll lists the coupons by code
+func (s promotionImpl) FindAll() (cps []types.Coupon, err error) {
+	err = cache.GetOrLoadTagged(coll+"all", []string{coll}, &cps, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		return storage.GetInstance().Find(ctx, coll, map[string]interface{}{}, storage.FindOptions{Sort: []string{"_id"}}, v)
+	})
+	return cps, err
+}
+
+//Create stores the coupon, its code upper case, as never redeemed
+func (s promotionImpl) Create(ctx context.Context, cp types.Coupon) (types.Coupon, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	cp.Code, cp.Uses = normalizeCode(cp.Code), 0
+	if err := normalize(&cp); err != nil {
+		return cp, err
+	}
+	err := storage.GetInstance().Insert(ctx, coll, cp)
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return cp, err
+}
+
+//Update replaces the terms of the coupon with the same code, how many times it was redeemed is kept
+func (s promotionImpl) Update(ctx context.Context, cp types.Coupon) (types.Coupon, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	cp.Code = normalizeCode(cp.Code)
+	if err := normalize(&cp); err != nil {
+		return cp, err
+	}
+	var old types.Coupon
+	err := storage.GetInstance().WithTransaction(ctx, func(ctx context.Context) error {
+		if err := storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": cp.Code}, &old); err != nil {
+			return err
+		}
+		return storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": cp.Code}, terms(cp))
+	})
+	if err != nil {
+		return cp, err
+	}
+	cp.Uses = old.Uses
+	return cp, cache.InvalidateTags(coll)
+}
+
+//Delete removes the coupon of the code, its redemptions are kept
+func (s promotionImpl) Delete(ctx context.Context, code string) error {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	err := storage.GetInstance().Remove(ctx, coll, map[string]interface{}{"_id": normalizeCode(code)})
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return err
+}
+
+//Quote prices the course of the id or slug in the currency, the one of its price when empty, with the discount of
+//the coupon of the code when there is one. The limits per student are only checked when the student is known.
+func (s promotionImpl) Quote(ref, code, student, currency string) (types.Quote, error) {
+	c, err := findCourse(ref)
+	if err != nil {
+		return types.Quote{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	q, _, err := quote(ctx, c, code, student, currency)
+	return q, err
+}
+
+//Redeem quotes the course for the student the way Quote does and counts the use of the coupon. The use is counted
+//only while the coupon is below its limits: the count of the coupon is raised in the same transaction that checks
+//the limits and records the redemption, so concurrent redemptions either see each other or conflict.
+func (s promotionImpl) Redeem(ctx context.Context, ref, code, student, currency string) (types.Quote, error) {
+	c, err := findCourse(ref)
+	if err != nil {
+		return types.Quote{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var q types.Quote
+	err = storage.GetInstance().WithTransaction(ctx, func(ctx context.Context) error {
+		var cp types.Coupon
+		var err error
+		if q, cp, err = quote(ctx, c, code, student, currency); err != nil || cp.Code == "" {
+			return err
+		}
+		selector := map[string]interface{}{"_id": cp.Code}
+		if cp.MaxUses > 0 {
+			selector["uses"] = map[string]interface{}{"$lt": cp.MaxUses}
+		}
+		err = storage.GetInstance().Update(ctx, coll, selector, map[string]interface{}{"$inc": map[string]interface{}{"uses": 1}})
+		if err == storage.ErrNotFound {
+			return ErrCouponExhausted
+		}
+		if err != nil {
+			return err
+		}
+		r := types.Redemption{ID: storage.NewID(), Code: cp.Code, Student: student, CourseID: c.ID, Discount: q.Discount, At: time.Now().UTC()}
+		return storage.GetInstance().Insert(ctx, redemptionColl, r)
+	})
+	if err == nil && q.Coupon != "" {
+		err = cache.InvalidateTags(coll)
+	}
+	return q, err
+}
+
+//Release gives back the use of the coupon of the code redeemed by the student for the course of the id, as when the
+//payment of the course is declined
+func (s promotionImpl) Release(ctx context.Context, code, student, courseID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	code = normalizeCode(code)
+	err := storage.GetInstance().WithTransaction(ctx, func(ctx context.Context) error {
+		var r types.Redemption
+		query := map[string]interface{}{"code": code, "student": student, "courseId": courseID}
+		if err := storage.GetInstance().FindOne(ctx, redemptionColl, query, &r); err != nil {
+			return err
+		}
+		if err := storage.GetInstance().Remove(ctx, redemptionColl, map[string]interface{}{"_id": r.ID}); err != nil {
+			return err
+		}
+		return storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": code}, map[string]interface{}{"$inc": map[string]interface{}{"uses": -1}})
+	})
+	if err == nil {
+		err = cache.InvalidateTags(coll)
+	}
+	return err
+}
+
+//quote prices the course with the coupon of the code, which is answered along with the quote
+func quote(ctx context.Context, c types.Course, code, student, currency string) (types.Quote, types.Coupon, error) {
+	price := c.Price
+	if currency != "" {
+		var err error
+		if price, err = courseservice.PriceIn(c, currency); err != nil {
+			return types.Quote{}, types.Coupon{}, err
+		}
+	}
+	q := types.Quote{CourseID: c.ID, Price: price, Discount: types.Money{Currency: price.Currency}, Total: price}
+	if code == "" {
+		return q, types.Coupon{}, nil
+	}
+
+	var cp types.Coupon
+	err := storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": normalizeCode(code)}, &cp)
+	if err == storage.ErrNotFound {
+		return q, cp, ErrUnknownCoupon
+	}
+	if err != nil {
+		return q, cp, err
+	}
+	if err = applies(cp, c, time.Now()); err != nil {
+		return q, cp, err
+	}
+	if err = available(ctx, cp, student); err != nil {
+		return q, cp, err
+	}
+	discount, err := discountOf(cp, price)
+	if err != nil {
+		return q, cp, err
+	}
+	q.Coupon, q.Discount = cp.Code, discount
+	q.Total.Amount -= discount.Amount
+	return q, cp, nil
+}
+
+//applies fails when the coupon is out of its validity window or the course out of its scope
+func applies(cp types.Coupon, c types.Course, now time.Time) error {
+	if cp.StartsAt != nil && now.Before(*cp.StartsAt) || cp.EndsAt != nil && !now.Before(*cp.EndsAt) {
+		return ErrCouponNotApplicable
+	}
+	if len(cp.Courses) == 0 && len(cp.Categories) == 0 {
+		return nil
+	}
+	for _, ref := range cp.Courses {
+		if ref == c.ID || ref == c.Slug {
+			return nil
+		}
+	}
+	if c.Category == "" || len(cp.Categories) == 0 {
+		return ErrCouponNotApplicable
+	}
+	cats, err := categoryservice.GetInstance().FindAll()
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return err
+	}
+	for _, cat := range cp.Categories {
+		for _, id := range categoryservice.Subtree(cats, cat) {
+			if id == c.Category {
+				return nil
+			}
+		}
+	}
+	return ErrCouponNotApplicable
+}
+
+//available fails when the coupon was redeemed as many times as it may be, overall or by the student
+func available(ctx context.Context, cp types.Coupon, student string) error {
+	if cp.MaxUses > 0 && cp.Uses >= cp.MaxUses {
+		return ErrCouponExhausted
+	}
+	if cp.MaxUsesPerStudent == 0 || student == "" {
+		return nil
+	}
+	n, err := storage.GetInstance().Count(ctx, redemptionColl, map[string]interface{}{"code": cp.Code, "student": student})
+	if err != nil {
+		return err
+	}
+	if n >= cp.MaxUsesPerStudent {
+		return ErrCouponExhausted
+	}
+	return nil
+}
+
+//discountOf is the discount of the coupon on the price, in its currency and never more than it. Percentages are
+//rounded half up to the minor units, fixed amounts in other currencies are converted at the current rate.
+func discountOf(cp types.Coupon, price types.Money) (types.Money, error) {
+	discount := types.Money{Currency: price.Currency}
+	switch cp.Kind {
+	case types.CouponPercent:
+		discount.Amount = (price.Amount*cp.Percent + 50) / 100
+	case types.CouponFixed:
+		amount, err := money.Convert(*cp.Amount, price.Currency)
+		if err != nil {
+			return discount, err
+		}
+		discount.Amount = amount.Amount
+	}
+	if discount.Amount > price.Amount {
+		discount.Amount = price.Amount
+	}
+	return discount, nil
+}
+
+//normalize checks the terms of the coupon, the currency of its amount is upper case and the default one when empty
+func normalize(cp *types.Coupon) error {
+	switch cp.Kind {
+	case types.CouponPercent:
+		if cp.Percent < 1 || cp.Percent > 100 || cp.Amount != nil {
+			return ErrInvalidCoupon
+		}
+	case types.CouponFixed:
+		if cp.Amount == nil || cp.Amount.Amount <= 0 || cp.Percent != 0 {
+			return ErrInvalidCoupon
+		}
+		amount := *cp.Amount
+		if amount.Currency = strings.ToUpper(amount.Currency); amount.Currency == "" {
+			amount.Currency = types.DefaultCurrency
+		}
+		if !money.IsCurrency(amount.Currency) {
+			return ErrInvalidCoupon
+		}
+		cp.Amount = &amount
+	default:
+		return ErrInvalidCoupon
+	}
+	if cp.StartsAt != nil && cp.EndsAt != nil && !cp.EndsAt.After(*cp.StartsAt) {
+		return ErrInvalidCoupon
+	}
+	return nil
+}
+
+//terms is the update setting every field of the coupon but its code and uses, the ones left empty are unset
+func terms(cp types.Coupon) map[string]interface{} {
+	set, unset := map[string]interface{}{"kind": cp.Kind}, map[string]interface{}{}
+	optional := func(field string, v interface{}, empty bool) {
+		if empty {
+			unset[field] = ""
+		} else {
+			set[field] = v
+		}
+	}
+	optional("percent", cp.Percent, cp.Percent == 0)
+	optional("amount", cp.Amount, cp.Amount == nil)
+	optional("startsAt", cp.StartsAt, cp.StartsAt == nil)
+	optional("endsAt", cp.EndsAt, cp.EndsAt == nil)
+	optional("maxUses", cp.MaxUses, cp.MaxUses == 0)
+	optional("maxUsesPerStudent", cp.MaxUsesPerStudent, cp.MaxUsesPerStudent == 0)
+	optional("courses", cp.Courses, len(cp.Courses) == 0)
+	optional("categories", cp.Categories, len(cp.Categories) == 0)
+	update := map[string]interface{}{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func findCourse(ref string) (types.Course, error) {
+	c, err := courseservice.GetInstance().FindOne(ref)
+	if _, ok := err.(*cache.RedisErr); ok {
+		err = nil
+	}
+	return c, err
+}
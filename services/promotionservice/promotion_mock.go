@@ -0,0 +1,66 @@
+package promotionservice
+
+import (
+	"context"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/mock"
+)
+
+//Mock is a mocked structure for promotion service
+type Mock struct {
+	mock.Mock
+}
+
+//InitMock to initialize mock befores tests
+func (s *Mock) InitMock() {
+	instance = s
+}
+
+//Create is a mock for promotion service create
+func (s *Mock) Create(ctx context.Context, cp types.Coupon) (types.Coupon, error) {
+	args := s.Called(ctx, cp)
+	return args.Get(0).(types.Coupon), args.Error(1)
+}
+
+//Update is a mock for promotion service update
+func (s *Mock) Update(ctx context.Context, cp types.Coupon) (types.Coupon, error) {
+	args := s.Called(ctx, cp)
+	return args.Get(0).(types.Coupon), args.Error(1)
+}
+
+//Delete is a mock for promotion service delete
+func (s *Mock) Delete(ctx context.Context, code string) error {
+	args := s.Called(ctx, code)
+	return args.Error(0)
+}
+
+//FindAll is a mock for promotion service findAll
+func (s *Mock) FindAll() ([]types.Coupon, error) {
+	args := s.Called()
+	return args.Get(0).([]types.Coupon), args.Error(1)
+}
+
+//FindOne is a mock for promotion service findOne
+func (s *Mock) FindOne(code string) (types.Coupon, error) {
+	args := s.Called(code)
+	return args.Get(0).(types.Coupon), args.Error(1)
+}
+
+//Quote is a mock for promotion service quote
+func (s *Mock) Quote(ref, code, student, currency string) (types.Quote, error) {
+	args := s.Called(ref, code, student, currency)
+	return args.Get(0).(types.Quote), args.Error(1)
+}
+
+//Redeem is a mock for promotion service redeem
+func (s *Mock) Redeem(ctx context.Context, ref, code, student, currency string) (types.Quote, error) {
+	args := s.Called(ctx, ref, code, student, currency)
+	return args.Get(0).(types.Quote), args.Error(1)
+}
+
+//Release is a mock for promotion service release
+func (s *Mock) Release(ctx context.Context, code, student, courseID string) error {
+	args := s.Called(ctx, code, student, courseID)
+	return args.Error(0)
+}
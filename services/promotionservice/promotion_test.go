@@ -0,0 +1,207 @@
+package promotionservice
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ednesic/coursemanagement/money"
+	"github.com/ednesic/coursemanagement/services/categoryservice"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemoryPromotions(t *testing.T) PromotionService {
+	testutil.Memory(t, courseservice.EnsureIndexes, categoryservice.EnsureIndexes, EnsureIndexes)
+	return promotionImpl{}
+}
+
+func eur(cents int64) types.Money {
+	return types.Money{Amount: cents, Currency: "EUR"}
+}
+
+func usdPtr(cents int64) *types.Money {
+	m := testutil.USD(cents)
+	return &m
+}
+
+func TestCoupon_CRUD(t *testing.T) {
+	s := newMemoryPromotions(t)
+	ctx := context.Background()
+
+	cp, err := s.Create(ctx, types.Coupon{Code: " launch ", Kind: types.CouponPercent, Percent: 20, MaxUses: 10, Uses: 7})
+	assert.NoError(t, err)
+	assert.Equal(t, types.Coupon{Code: "LAUNCH", Kind: types.CouponPercent, Percent: 20, MaxUses: 10}, cp)
+	_, err = s.Create(ctx, types.Coupon{Code: "Launch", Kind: types.CouponPercent, Percent: 10})
+	assert.Equal(t, storage.ErrDuplicate, err)
+
+	now := time.Now()
+	for _, invalid := range []types.Coupon{
+		{Code: "A", Kind: types.CouponPercent},
+		{Code: "B", Kind: types.CouponPercent, Percent: 101},
+		{Code: "C", Kind: types.CouponFixed},
+		{Code: "D", Kind: types.CouponFixed, Amount: &types.Money{Amount: 500, Currency: "XYZ"}},
+		{Code: "E", Kind: types.CouponFixed, Amount: &types.Money{Amount: 500}, Percent: 10},
+		{Code: "F", Kind: "free"},
+		{Code: "G", Kind: types.CouponPercent, Percent: 10, StartsAt: &now, EndsAt: &now},
+	} {
+		_, err = s.Create(ctx, invalid)
+		assert.Equal(t, ErrInvalidCoupon, err, invalid.Code)
+	}
+
+	cp, err = s.Update(ctx, types.Coupon{Code: "launch", Kind: types.CouponFixed, Amount: &types.Money{Amount: 500, Currency: "eur"}, Courses: []string{"go"}})
+	assert.NoError(t, err)
+	found, err := s.FindOne("LAUNCH")
+	assert.NoError(t, err)
+	assert.Equal(t, cp, found)
+	assert.Equal(t, types.Coupon{Code: "LAUNCH", Kind: types.CouponFixed, Amount: &types.Money{Amount: 500, Currency: "EUR"}, Courses: []string{"go"}}, found)
+	_, err = s.Update(ctx, types.Coupon{Code: "missing", Kind: types.CouponPercent, Percent: 10})
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	cps, err := s.FindAll()
+	assert.NoError(t, err)
+	assert.Len(t, cps, 1)
+	assert.NoError(t, s.Delete(ctx, "launch"))
+	_, err = s.FindOne("launch")
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, s.Delete(ctx, "launch"))
+}
+
+func TestQuote(t *testing.T) {
+	s := newMemoryPromotions(t)
+	ctx := context.Background()
+	rates := filepath.Join(t.TempDir(), "rates.json")
+	assert.NoError(t, ioutil.WriteFile(rates, []byte(`{"base": "USD", "rates": {"EUR": 0.5}}`), 0600))
+	assert.NoError(t, money.NewStatic().Initialize(map[string]string{"file": rates}))
+
+	dev, err := categoryservice.GetInstance().Create(ctx, types.Category{Name: "Development"})
+	assert.NoError(t, err)
+	web, err := categoryservice.GetInstance().Create(ctx, types.Category{Name: "Web", Parent: dev.ID})
+	assert.NoError(t, err)
+	goCourse, err := courseservice.GetInstance().Create(ctx, types.Course{Name: "Go", Price: testutil.USD(1990), Category: web.ID})
+	assert.NoError(t, err)
+	_, err = courseservice.GetInstance().Create(ctx, types.Course{Name: "Figma", Price: testutil.USD(1990)})
+	assert.NoError(t, err)
+
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	for _, cp := range []types.Coupon{
+		{Code: "PERCENT", Kind: types.CouponPercent, Percent: 15},
+		{Code: "FIXED", Kind: types.CouponFixed, Amount: &types.Money{Amount: 500, Currency: "EUR"}},
+		{Code: "HUGE", Kind: types.CouponFixed, Amount: usdPtr(5000)},
+		{Code: "GO", Kind: types.CouponPercent, Percent: 50, Courses: []string{"go"}},
+		{Code: "DEV", Kind: types.CouponPercent, Percent: 50, Categories: []string{dev.ID}},
+		{Code: "EXPIRED", Kind: types.CouponPercent, Percent: 50, EndsAt: &past},
+		{Code: "SOON", Kind: types.CouponPercent, Percent: 50, StartsAt: &future},
+	} {
+		_, err := s.Create(ctx, cp)
+		assert.NoError(t, err)
+	}
+
+	tests := []struct {
+		name     string
+		course   string
+		code     string
+		currency string
+		want     types.Quote
+		err      error
+	}{
+		{"No coupon", "go", "", "", types.Quote{CourseID: goCourse.ID, Price: testutil.USD(1990), Discount: testutil.USD(0), Total: testutil.USD(1990)}, nil},
+		{"Percent", "go", "percent", "", types.Quote{CourseID: goCourse.ID, Coupon: "PERCENT", Price: testutil.USD(1990), Discount: testutil.USD(299), Total: testutil.USD(1691)}, nil},
+		{"Percent in a currency", "go", "PERCENT", "eur", types.Quote{CourseID: goCourse.ID, Coupon: "PERCENT", Price: eur(995), Discount: eur(149), Total: eur(846)}, nil},
+		{"Fixed in another currency", "go", "FIXED", "", types.Quote{CourseID: goCourse.ID, Coupon: "FIXED", Price: testutil.USD(1990), Discount: testutil.USD(1000), Total: testutil.USD(990)}, nil},
+		{"Fixed above the price", "go", "HUGE", "", types.Quote{CourseID: goCourse.ID, Coupon: "HUGE", Price: testutil.USD(1990), Discount: testutil.USD(1990), Total: testutil.USD(0)}, nil},
+		{"Course scope", "go", "GO", "", types.Quote{CourseID: goCourse.ID, Coupon: "GO", Price: testutil.USD(1990), Discount: testutil.USD(995), Total: testutil.USD(995)}, nil},
+		{"Category scope", "go", "DEV", "", types.Quote{CourseID: goCourse.ID, Coupon: "DEV", Price: testutil.USD(1990), Discount: testutil.USD(995), Total: testutil.USD(995)}, nil},
+		{"Out of the course scope", "figma", "GO", "", types.Quote{}, ErrCouponNotApplicable},
+		{"Out of the category scope", "figma", "DEV", "", types.Quote{}, ErrCouponNotApplicable},
+		{"Expired", "go", "EXPIRED", "", types.Quote{}, ErrCouponNotApplicable},
+		{"Not started", "go", "SOON", "", types.Quote{}, ErrCouponNotApplicable},
+		{"Unknown coupon", "go", "NOPE", "", types.Quote{}, ErrUnknownCoupon},
+		{"Unknown course", "missing", "PERCENT", "", types.Quote{}, storage.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := s.Quote(tt.course, tt.code, "ana", tt.currency)
+			assert.Equal(t, tt.err, err)
+			if tt.err == nil {
+				assert.Equal(t, tt.want, q)
+			}
+		})
+	}
+}
+
+func TestRedeem_Limits(t *testing.T) {
+	s := newMemoryPromotions(t)
+	ctx := context.Background()
+	c, err := courseservice.GetInstance().Create(ctx, types.Course{Name: "Go", Price: testutil.USD(1000)})
+	assert.NoError(t, err)
+	_, err = s.Create(ctx, types.Coupon{Code: "ONCE", Kind: types.CouponPercent, Percent: 10, MaxUses: 2, MaxUsesPerStudent: 1})
+	assert.NoError(t, err)
+
+	q, err := s.Redeem(ctx, c.ID, "once", "ana", "")
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.USD(900), q.Total)
+	_, err = s.Redeem(ctx, c.ID, "ONCE", "ana", "")
+	assert.Equal(t, ErrCouponExhausted, err)
+	_, err = s.Quote(c.ID, "ONCE", "ana", "")
+	assert.Equal(t, ErrCouponExhausted, err)
+	_, err = s.Redeem(ctx, c.ID, "ONCE", "bob", "")
+	assert.NoError(t, err)
+	_, err = s.Redeem(ctx, c.ID, "ONCE", "eve", "")
+	assert.Equal(t, ErrCouponExhausted, err)
+
+	cp, err := s.FindOne("ONCE")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), cp.Uses)
+	q, err = s.Redeem(ctx, c.ID, "", "eve", "")
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.USD(1000), q.Total)
+
+	assert.NoError(t, s.Release(ctx, "once", "ana", c.ID))
+	assert.Equal(t, storage.ErrNotFound, s.Release(ctx, "ONCE", "ana", c.ID))
+	_, err = s.Redeem(ctx, c.ID, "ONCE", "eve", "")
+	assert.NoError(t, err)
+}
+
+func TestRedeem_Concurrent(t *testing.T) {
+	s := newMemoryPromotions(t)
+	ctx := context.Background()
+	c, err := courseservice.GetInstance().Create(ctx, types.Course{Name: "Go", Price: testutil.USD(1000)})
+	assert.NoError(t, err)
+	_, err = s.Create(ctx, types.Coupon{Code: "FIRST5", Kind: types.CouponPercent, Percent: 50, MaxUses: 5})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	redeemed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.Redeem(ctx, c.ID, "FIRST5", fmt.Sprintf("student%d", i), "")
+			if err == nil {
+				mu.Lock()
+				redeemed++
+				mu.Unlock()
+				return
+			}
+			assert.Equal(t, ErrCouponExhausted, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, redeemed)
+	cp, err := s.FindOne("FIRST5")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), cp.Uses)
+	n, err := storage.GetInstance().Count(ctx, redemptionColl, map[string]interface{}{"code": "FIRST5"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+}
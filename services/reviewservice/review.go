@@ -0,0 +1,223 @@
+package reviewservice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+)
+
+const (
+	coll = "review"
+
+	//flagLimit is how many users flag a review before it is hidden until the moderators look at it
+	flagLimit = 3
+)
+
+var (
+	instance ReviewService
+	once     sync.Once
+
+	//ErrNotEnrolled for reviews of a course by someone who is not one of its students
+	ErrNotEnrolled = errors.New("only the students of the course review it")
+	//ErrAlreadyReviewed for a second review of a course by the same student
+	ErrAlreadyReviewed = errors.New("student already reviewed the course")
+	//ErrInvalidStatus for moderations to a status reviews do not have
+	ErrInvalidStatus = errors.New("invalid review status")
+)
+
+//ReviewService is an interface for review service. The rating of the course follows its visible reviews.
+type ReviewService interface {
+	Create(context.Context, string, types.Review) (types.Review, error)
+	Update(context.Context, string, types.Review) (types.Review, error)
+	Delete(context.Context, string, string) error
+	FindByCourse(string, string) ([]types.Review, error)
+	Flag(context.Context, string, string) (types.Review, error)
+	Moderate(context.Context, string, string, string) (types.Review, error)
+}
+
+type reviewImpl struct{}
+
+//GetInstance to get service instance
+func GetInstance() ReviewService {
+	once.Do(func() {
+		if instance == nil {
+			instance = &reviewImpl{}
+		}
+	})
+	return instance
+}
+
+//EnsureIndexes creates the indexes the review service relies on, the one listing the reviews of a course
+func EnsureIndexes(ctx context.Context) error {
+	return storage.GetInstance().EnsureIndex(ctx, coll, storage.Index{Keys: []string{"courseId", "status", "-at"}})
+}
+
+//Create stores the review of its student of the course of the id or slug, students review a course they are
+//enrolled in once
+func (s reviewImpl) Create(ctx context.Context, ref string, review types.Review) (types.Review, error) {
+	_, err := courseservice.GetInstance().Rate(ctx, ref, func(ctx context.Context, c types.Course) (types.Rating, error) {
+		if err := checkEnrolled(c, review.Student); err != nil {
+			return types.Rating{}, err
+		}
+		review.ID, review.CourseID = reviewID(c.ID, review.Student), c.ID
+		review.Status, review.Flags = types.ReviewVisible, nil
+		review.At, review.UpdatedAt = time.Now().UTC(), nil
+		if err := storage.GetInstance().Insert(ctx, coll, review); err != nil {
+			return types.Rating{}, err
+		}
+		return rating(review, 1), nil
+	})
+	if err == storage.ErrDuplicate {
+		return review, ErrAlreadyReviewed
+	}
+	return review, invalidate(review, err)
+}
+
+//Update replaces the rating and the text of the review of its student of the course of the id or slug
+func (s reviewImpl) Update(ctx context.Context, ref string, review types.Review) (types.Review, error) {
+	var old types.Review
+	_, err := courseservice.GetInstance().Rate(ctx, ref, func(ctx context.Context, c types.Course) (types.Rating, error) {
+		if err := findReview(ctx, c.ID, review.Student, &old); err != nil {
+			return types.Rating{}, err
+		}
+		before, now := rating(old, -1), time.Now().UTC()
+		old.Rating, old.Text, old.UpdatedAt = review.Rating, review.Text, &now
+		err := storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": old.ID}, map[string]interface{}{
+			"$set": map[string]interface{}{"rating": old.Rating, "text": old.Text, "updatedAt": now},
+		})
+		return types.Rating{Stars: before.Stars + rating(old, 1).Stars}, err
+	})
+	return old, invalidate(old, err)
+}
+
+//Delete removes the review of the student of the course of the id or slug
+func (s reviewImpl) Delete(ctx context.Context, ref, student string) error {
+	var old types.Review
+	_, err := courseservice.GetInstance().Rate(ctx, ref, func(ctx context.Context, c types.Course) (types.Rating, error) {
+		if err := findReview(ctx, c.ID, student, &old); err != nil {
+			return types.Rating{}, err
+		}
+		return rating(old, -1), storage.GetInstance().Remove(ctx, coll, map[string]interface{}{"_id": old.ID})
+	})
+	return invalidate(old, err)
+}
+
+//FindByCourse lists the reviews of the status, visible when it is empty, of the course of the id or slug, newest first
+func (s reviewImpl) FindByCourse(ref, status string) (rs []types.Review, err error) {
+	if status == "" {
+		status = types.ReviewVisible
+	}
+	c, err := courseservice.GetInstance().FindOne(ref)
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return nil, err
+	}
+	err = cache.GetOrLoadTagged(coll+c.ID+status, []string{coll + c.ID}, &rs, time.Minute, func(v interface{}) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		query := map[string]interface{}{"courseId": c.ID, "status": status}
+		return storage.GetInstance().Find(ctx, coll, query, storage.FindOptions{Sort: []string{"-at"}}, v)
+	})
+	return rs, err
+}
+
+//Flag reports the review of the student of the course of the id or slug to the moderators on behalf of the actor
+//of the context, reviews flagged by enough users are hidden
+func (s reviewImpl) Flag(ctx context.Context, ref, student string) (types.Review, error) {
+	var r types.Review
+	by := actor.FromContext(ctx)
+	_, err := courseservice.GetInstance().Rate(ctx, ref, func(ctx context.Context, c types.Course) (types.Rating, error) {
+		if err := findReview(ctx, c.ID, student, &r); err != nil || contains(r.Flags, by) {
+			return types.Rating{}, err
+		}
+		r.Flags = append(r.Flags, by)
+		set := map[string]interface{}{"flags": r.Flags}
+		var change types.Rating
+		if len(r.Flags) >= flagLimit && r.Status == types.ReviewVisible {
+			change, r.Status = rating(r, -1), types.ReviewHidden
+			set["status"] = r.Status
+		}
+		return change, storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": r.ID}, map[string]interface{}{"$set": set})
+	})
+	return r, invalidate(r, err)
+}
+
+//Moderate moves the review of the student of the course of the id or slug to the status. Reviews made visible
+//again lose their flags.
+func (s reviewImpl) Moderate(ctx context.Context, ref, student, status string) (types.Review, error) {
+	if status != types.ReviewVisible && status != types.ReviewHidden {
+		return types.Review{}, ErrInvalidStatus
+	}
+	var r types.Review
+	_, err := courseservice.GetInstance().Rate(ctx, ref, func(ctx context.Context, c types.Course) (types.Rating, error) {
+		if err := findReview(ctx, c.ID, student, &r); err != nil || r.Status == status {
+			return types.Rating{}, err
+		}
+		change := rating(r, -1)
+		r.Status = status
+		update := map[string]interface{}{"$set": map[string]interface{}{"status": status}}
+		if status == types.ReviewVisible {
+			change, r.Flags = rating(r, 1), nil
+			update["$unset"] = map[string]interface{}{"flags": ""}
+		}
+		return change, storage.GetInstance().Update(ctx, coll, map[string]interface{}{"_id": r.ID}, update)
+	})
+	return r, invalidate(r, err)
+}
+
+//checkEnrolled fails when the student does not have a seat in the course
+func checkEnrolled(c types.Course, student string) error {
+	es, err := enrollmentservice.GetInstance().FindByStudent(student)
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return err
+	}
+	for _, e := range es {
+		if e.CourseID == c.ID && e.Status == types.EnrollmentActive {
+			return nil
+		}
+	}
+	return ErrNotEnrolled
+}
+
+func findReview(ctx context.Context, courseID, student string, r *types.Review) error {
+	return storage.GetInstance().FindOne(ctx, coll, map[string]interface{}{"_id": reviewID(courseID, student)}, r)
+}
+
+//rating is the change to the rating of the course of n reviews like r, none when r is hidden
+func rating(r types.Review, n int64) types.Rating {
+	if r.Status != types.ReviewVisible {
+		return types.Rating{}
+	}
+	return types.Rating{Stars: n * r.Rating, Count: n}
+}
+
+//invalidate drops the cached reviews of the course of r when err, the one of the change to r, is nil
+func invalidate(r types.Review, err error) error {
+	if _, ok := err.(*cache.RedisErr); !ok && err != nil {
+		return err
+	}
+	if ierr := cache.InvalidateTags(coll + r.CourseID); err == nil {
+		err = ierr
+	}
+	return err
+}
+
+func reviewID(courseID, student string) string {
+	return courseID + ":" + student
+}
+
+func contains(users []string, user string) bool {
+	for _, u := range users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
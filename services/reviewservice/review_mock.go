@@ -0,0 +1,54 @@
+package reviewservice
+
+import (
+	"context"
+
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/mock"
+)
+
+//Mock is a mocked structure for review service
+type Mock struct {
+	mock.Mock
+}
+
+//InitMock to initialize mock befores tests
+func (s *Mock) InitMock() {
+	instance = s
+}
+
+//Create is a mock for review service create
+func (s *Mock) Create(ctx context.Context, ref string, review types.Review) (types.Review, error) {
+	args := s.Called(ctx, ref, review)
+	return args.Get(0).(types.Review), args.Error(1)
+}
+
+//Update is a mock for review service update
+func (s *Mock) Update(ctx context.Context, ref string, review types.Review) (types.Review, error) {
+	args := s.Called(ctx, ref, review)
+	return args.Get(0).(types.Review), args.Error(1)
+}
+
+//Delete is a mock for review service delete
+func (s *Mock) Delete(ctx context.Context, ref, student string) error {
+	args := s.Called(ctx, ref, student)
+	return args.Error(0)
+}
+
+//FindByCourse is a mock for review service findByCourse
+func (s *Mock) FindByCourse(ref, status string) ([]types.Review, error) {
+	args := s.Called(ref, status)
+	return args.Get(0).([]types.Review), args.Error(1)
+}
+
+//Flag is a mock for review service flag
+func (s *Mock) Flag(ctx context.Context, ref, student string) (types.Review, error) {
+	args := s.Called(ctx, ref, student)
+	return args.Get(0).(types.Review), args.Error(1)
+}
+
+//Moderate is a mock for review service moderate
+func (s *Mock) Moderate(ctx context.Context, ref, student, status string) (types.Review, error) {
+	args := s.Called(ctx, ref, student, status)
+	return args.Get(0).(types.Review), args.Error(1)
+}
@@ -0,0 +1,146 @@
+package reviewservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ednesic/coursemanagement/actor"
+	"github.com/ednesic/coursemanagement/services/courseservice"
+	"github.com/ednesic/coursemanagement/services/enrollmentservice"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/testutil"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemoryReviews(t *testing.T) ReviewService {
+	testutil.Memory(t, courseservice.EnsureIndexes, enrollmentservice.EnsureIndexes, EnsureIndexes)
+	return reviewImpl{}
+}
+
+//enrolledCourse creates a published course the students are enrolled in
+func enrolledCourse(t *testing.T, students ...string) types.Course {
+	c := testutil.PublishedCourse(t, courseservice.GetInstance(), types.Course{Name: "Go"})
+	for _, s := range students {
+		_, err := enrollmentservice.GetInstance().Enroll(context.Background(), c.ID, s)
+		assert.NoError(t, err)
+	}
+	return c
+}
+
+func courseRating(t *testing.T, ref string) *types.Rating {
+	c, err := courseservice.GetInstance().FindOne(ref)
+	assert.NoError(t, err)
+	return c.Rating
+}
+
+func TestReview_Rating(t *testing.T) {
+	s := newMemoryReviews(t)
+	ctx := context.Background()
+	c := enrolledCourse(t, "ana", "bob", "eve")
+
+	_, err := s.Create(ctx, c.Slug, types.Review{Student: "ana", Rating: 5, Text: "great"})
+	assert.NoError(t, err)
+	assert.Equal(t, &types.Rating{Average: 5, Count: 1, Stars: 5}, courseRating(t, c.Slug))
+	_, err = s.Create(ctx, c.ID, types.Review{Student: "bob", Rating: 2})
+	assert.NoError(t, err)
+	_, err = s.Create(ctx, c.ID, types.Review{Student: "eve", Rating: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, &types.Rating{Average: 3.67, Count: 3, Stars: 11}, courseRating(t, c.ID))
+
+	r, err := s.Update(ctx, c.ID, types.Review{Student: "bob", Rating: 3, Text: "fine"})
+	assert.NoError(t, err)
+	assert.Equal(t, "fine", r.Text)
+	assert.NotNil(t, r.UpdatedAt)
+	assert.Equal(t, &types.Rating{Average: 4, Count: 3, Stars: 12}, courseRating(t, c.ID))
+
+	rs, err := s.FindByCourse(c.Slug, "")
+	assert.NoError(t, err)
+	assert.Len(t, rs, 3)
+
+	assert.NoError(t, s.Delete(ctx, c.ID, "eve"))
+	assert.Equal(t, &types.Rating{Average: 4, Count: 2, Stars: 8}, courseRating(t, c.ID))
+	assert.NoError(t, s.Delete(ctx, c.ID, "bob"))
+	assert.NoError(t, s.Delete(ctx, c.ID, "ana"))
+	assert.Nil(t, courseRating(t, c.ID))
+}
+
+func TestReview_RatingSurvivesCourseUpdates(t *testing.T) {
+	s := newMemoryReviews(t)
+	ctx := context.Background()
+	c := enrolledCourse(t, "ana")
+	_, err := s.Create(ctx, c.ID, types.Review{Student: "ana", Rating: 4})
+	assert.NoError(t, err)
+
+	c, err = courseservice.GetInstance().FindOne(c.ID)
+	assert.NoError(t, err)
+	c.Name, c.Rating = "Go basics", &types.Rating{Average: 1, Count: 100}
+	updated, err := courseservice.GetInstance().Update(ctx, c)
+	assert.NoError(t, err)
+	assert.Equal(t, c.Version+1, updated.Version)
+	assert.Equal(t, &types.Rating{Average: 4, Count: 1, Stars: 4}, updated.Rating)
+	assert.Equal(t, &types.Rating{Average: 4, Count: 1, Stars: 4}, courseRating(t, c.ID))
+}
+
+func TestReview_Errors(t *testing.T) {
+	s := newMemoryReviews(t)
+	ctx := context.Background()
+	c := enrolledCourse(t, "ana")
+
+	_, err := s.Create(ctx, c.ID, types.Review{Student: "eve", Rating: 5})
+	assert.Equal(t, ErrNotEnrolled, err)
+	_, err = s.Create(ctx, "unknown", types.Review{Student: "ana", Rating: 5})
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.Create(ctx, c.ID, types.Review{Student: "ana", Rating: 5})
+	assert.NoError(t, err)
+	_, err = s.Create(ctx, c.ID, types.Review{Student: "ana", Rating: 1})
+	assert.Equal(t, ErrAlreadyReviewed, err)
+	assert.Equal(t, &types.Rating{Average: 5, Count: 1, Stars: 5}, courseRating(t, c.ID))
+
+	_, err = s.Update(ctx, c.ID, types.Review{Student: "eve", Rating: 5})
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, storage.ErrNotFound, s.Delete(ctx, c.ID, "eve"))
+	_, err = s.Moderate(ctx, c.ID, "ana", "deleted")
+	assert.Equal(t, ErrInvalidStatus, err)
+}
+
+func TestReview_Moderation(t *testing.T) {
+	s := newMemoryReviews(t)
+	ctx := context.Background()
+	c := enrolledCourse(t, "ana", "bob")
+	_, err := s.Create(ctx, c.ID, types.Review{Student: "ana", Rating: 1, Text: "spam"})
+	assert.NoError(t, err)
+	_, err = s.Create(ctx, c.ID, types.Review{Student: "bob", Rating: 5})
+	assert.NoError(t, err)
+
+	for _, by := range []string{"u1", "u2", "u2"} {
+		r, err := s.Flag(actor.NewContext(ctx, by), c.ID, "ana")
+		assert.NoError(t, err)
+		assert.Equal(t, types.ReviewVisible, r.Status)
+	}
+	r, err := s.Flag(actor.NewContext(ctx, "u3"), c.ID, "ana")
+	assert.NoError(t, err)
+	assert.Equal(t, types.ReviewHidden, r.Status)
+	assert.Equal(t, []string{"u1", "u2", "u3"}, r.Flags)
+	assert.Equal(t, &types.Rating{Average: 5, Count: 1, Stars: 5}, courseRating(t, c.ID))
+
+	rs, err := s.FindByCourse(c.ID, "")
+	assert.NoError(t, err)
+	assert.Len(t, rs, 1)
+	rs, err = s.FindByCourse(c.ID, types.ReviewHidden)
+	assert.NoError(t, err)
+	assert.Len(t, rs, 1)
+
+	_, err = s.Update(ctx, c.ID, types.Review{Student: "ana", Rating: 2, Text: "spam"})
+	assert.NoError(t, err)
+	assert.Equal(t, &types.Rating{Average: 5, Count: 1, Stars: 5}, courseRating(t, c.ID))
+
+	r, err = s.Moderate(ctx, c.ID, "ana", types.ReviewVisible)
+	assert.NoError(t, err)
+	assert.Empty(t, r.Flags)
+	assert.Equal(t, &types.Rating{Average: 3.5, Count: 2, Stars: 7}, courseRating(t, c.ID))
+
+	_, err = s.Moderate(ctx, c.ID, "bob", types.ReviewHidden)
+	assert.NoError(t, err)
+	assert.Equal(t, &types.Rating{Average: 2, Count: 1, Stars: 2}, courseRating(t, c.ID))
+}
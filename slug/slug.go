@@ -0,0 +1,38 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+)
+
+var folds = map[rune]string{
+	'á': "a", 'à': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a",
+	'é': "e", 'è': "e", 'ê': "e", 'ë': "e",
+	'í': "i", 'ì': "i", 'î': "i", 'ï': "i",
+	'ó': "o", 'ò': "o", 'ô': "o", 'õ': "o", 'ö': "o",
+	'ú': "u", 'ù': "u", 'û': "u", 'ü': "u",
+	'ç': "c", 'ñ': "n", 'ß': "ss",
+}
+
+//Make turns the text in an URL-safe slug: lower case ASCII letters and digits separated by dashes.
+//Accented latin letters are replaced by the plain ones and any other character is a separator.
+func Make(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		s, ok := folds[r]
+		if !ok && r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			s, ok = string(r), true
+		}
+		if !ok {
+			dash = b.Len() > 0
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
@@ -0,0 +1,25 @@
+package slug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Go Basics", "go-basics"},
+		{"  Programação em Go: do zero!  ", "programacao-em-go-do-zero"},
+		{"C++ & C#", "c-c"},
+		{"100% Pure", "100-pure"},
+		{"日本語", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, Make(tt.text))
+		})
+	}
+}
@@ -1,10 +1,37 @@
 package storage
 
 import (
+	"errors"
+
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+const (
+	duplicateKeyCode      = 11000
+	namespaceNotFoundCode = 26
+	indexNotFoundCode     = 27
+)
+
 var (
 	//ErrNotFound for database not found documents
 	ErrNotFound = mongo.ErrNoDocuments
+	//ErrDuplicate for documents breaking an unique index
+	ErrDuplicate = errors.New("duplicate key")
 )
+
+//mongoErr translates the mongo errors the services handle to the ones of this package
+func mongoErr(err error) error {
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == duplicateKeyCode {
+				return ErrDuplicate
+			}
+		}
+	case mongo.CommandError:
+		if e.Code == duplicateKeyCode {
+			return ErrDuplicate
+		}
+	}
+	return err
+}
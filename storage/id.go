@@ -0,0 +1,8 @@
+package storage
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+//NewID generates an unique identifier for a document
+func NewID() string {
+	return primitive.NewObjectID().Hex()
+}
@@ -0,0 +1,818 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type txKey struct{}
+
+//txLog undoes the writes of a transaction, the last one first
+type txLog struct {
+	undo []func()
+}
+
+//memoryImpl keeps the documents in process memory. It understands the same selectors and
+//update documents the services send to mongo, so it can stand in for it on local runs and tests.
+type memoryImpl struct {
+	mu      sync.RWMutex
+	txMu    sync.Mutex
+	colls   map[string][]map[string]interface{}
+	indexes map[string][]Index
+}
+
+//NewMemory returns an in-memory data access layer, SetInstance makes it the one returned by GetInstance
+func NewMemory() DataAccessLayer {
+	return &memoryImpl{}
+}
+
+func (m *memoryImpl) Initialize(ctx context.Context, dbURI, dbName string) error {
+	m.mu.Lock()
+	m.colls = map[string][]map[string]interface{}{}
+	m.indexes = map[string][]Index{}
+	m.mu.Unlock()
+	return nil
+}
+
+//WithTransaction runs one transaction at a time and undoes its writes when fn fails. The writes made meanwhile
+//out of the transaction are kept.
+func (m *memoryImpl) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	log := &txLog{}
+	if err := fn(context.WithValue(ctx, txKey{}, log)); err != nil {
+		m.mu.Lock()
+		for i := len(log.undo) - 1; i >= 0; i-- {
+			log.undo[i]()
+		}
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+//record keeps how to undo a write in the transaction of the context, if any. The caller must hold the lock.
+func record(ctx context.Context, undo func()) {
+	if log, ok := ctx.Value(txKey{}).(*txLog); ok {
+		log.undo = append(log.undo, undo)
+	}
+}
+
+//indexOf is the position in the collection of the document of the id, -1 when there is none. The caller must
+//hold the lock.
+func (m *memoryImpl) indexOf(collName string, id interface{}) int {
+	for i, d := range m.colls[collName] {
+		if equals(d["_id"], id) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Insert stores documents in the collection
+func (m *memoryImpl) Insert(ctx context.Context, collName string, doc interface{}) error {
+	d, err := toDoc(doc)
+	if err != nil {
+		return err
+	}
+	if _, ok := d["_id"]; !ok {
+		d["_id"] = primitive.NewObjectID()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.duplicate(collName, d, -1) {
+		return ErrDuplicate
+	}
+	m.colls[collName] = append(m.colls[collName], d)
+	record(ctx, func() {
+		if i := m.indexOf(collName, d["_id"]); i >= 0 {
+			docs := m.colls[collName]
+			m.colls[collName] = append(docs[:i:i], docs[i+1:]...)
+		}
+	})
+	return nil
+}
+
+// Find finds all documents in the collection
+func (m *memoryImpl) Find(ctx context.Context, collName string, query map[string]interface{}, opts FindOptions, doc interface{}) error {
+	filter, err := toDoc(query)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	docs := m.filter(collName, filter)
+	m.mu.RUnlock()
+
+	sortDocs(docs, opts.Sort)
+	return decodeDocs(page(docs, opts), doc)
+}
+
+// Search finds the documents matching the text of the collection text index, the most relevant first.
+// Relevance is the number of words of the indexed fields starting with one of the searched terms.
+func (m *memoryImpl) Search(ctx context.Context, collName, text string, query map[string]interface{}, opts FindOptions, doc interface{}) error {
+	filter, err := toDoc(query)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	var keys []string
+	for _, idx := range m.indexes[collName] {
+		if idx.Text {
+			keys = idx.Keys
+		}
+	}
+	docs := m.filter(collName, filter)
+	m.mu.RUnlock()
+	if keys == nil {
+		return fmt.Errorf("text index required for $text query on %s", collName)
+	}
+
+	terms := words(text)
+	type scored struct {
+		doc   map[string]interface{}
+		score int
+	}
+	var hits []scored
+	for _, d := range docs {
+		score := 0
+		for _, k := range keys {
+			s, _ := lookup(d, k).(string)
+			for _, w := range words(s) {
+				for _, t := range terms {
+					if strings.HasPrefix(w, t) {
+						score++
+					}
+				}
+			}
+		}
+		if score > 0 {
+			hits = append(hits, scored{d, score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+
+	docs = make([]map[string]interface{}, 0, len(hits))
+	for _, h := range hits {
+		docs = append(docs, h.doc)
+	}
+	return decodeDocs(page(docs, opts), doc)
+}
+
+// FindOne finds one document in memory
+func (m *memoryImpl) FindOne(ctx context.Context, collName string, query map[string]interface{}, doc interface{}) error {
+	filter, err := toDoc(query)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, d := range m.colls[collName] {
+		if matches(d, filter) {
+			return decodeDoc(d, doc)
+		}
+	}
+	return ErrNotFound
+}
+
+// Update updates the first document of the collection matching the selector, ErrNotFound when none does
+func (m *memoryImpl) Update(ctx context.Context, collName string, selector map[string]interface{}, update interface{}) error {
+	filter, err := toDoc(selector)
+	if err != nil {
+		return err
+	}
+	upd, err := toDoc(update)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, d := range m.colls[collName] {
+		if matches(d, filter) {
+			updated := copyDoc(d)
+			if err := applyUpdate(updated, upd); err != nil {
+				return err
+			}
+			if m.duplicate(collName, updated, i) {
+				return ErrDuplicate
+			}
+			m.colls[collName][i] = updated
+			record(ctx, func() {
+				if i := m.indexOf(collName, d["_id"]); i >= 0 {
+					m.colls[collName][i] = d
+				}
+			})
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// Remove removes the first document of the collection matching the selector, ErrNotFound when none does
+func (m *memoryImpl) Remove(ctx context.Context, collName string, selector map[string]interface{}) error {
+	filter, err := toDoc(selector)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	docs := m.colls[collName]
+	for i, d := range docs {
+		if matches(d, filter) {
+			m.colls[collName] = append(docs[:i:i], docs[i+1:]...)
+			record(ctx, func() {
+				docs := m.colls[collName]
+				if i > len(docs) {
+					i = len(docs)
+				}
+				m.colls[collName] = append(docs[:i:i], append([]map[string]interface{}{d}, docs[i:]...)...)
+			})
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// Count returns the number of documents of the query
+func (m *memoryImpl) Count(ctx context.Context, collName string, query map[string]interface{}) (int64, error) {
+	filter, err := toDoc(query)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return int64(len(m.filter(collName, filter))), nil
+}
+
+// Facet counts the documents of the query by the values of the field, the most frequent first. Each value of an
+// array field counts, documents without the field do not.
+func (m *memoryImpl) Facet(ctx context.Context, collName string, query map[string]interface{}, field string) ([]FacetCount, error) {
+	filter, err := toDoc(query)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	docs := m.filter(collName, filter)
+	m.mu.RUnlock()
+
+	counts := map[string]int64{}
+	for _, d := range docs {
+		values, ok := asArray(lookup(d, field))
+		if !ok {
+			values = []interface{}{lookup(d, field)}
+		}
+		for _, v := range values {
+			if v != nil {
+				counts[fmt.Sprint(v)]++
+			}
+		}
+	}
+	facets := make([]FacetCount, 0, len(counts))
+	for v, n := range counts {
+		facets = append(facets, FacetCount{Value: v, Count: n})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	return facets, nil
+}
+
+// EnsureIndex records the index of the collection, text indexes are the fields Search looks at
+func (m *memoryImpl) EnsureIndex(ctx context.Context, collName string, idx Index) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, i := range m.indexes[collName] {
+		if reflect.DeepEqual(i, idx) {
+			return nil
+		}
+	}
+	m.indexes[collName] = append(m.indexes[collName], idx)
+	return nil
+}
+
+// DropIndex removes the index of the collection, indexes which do not exist are not an error
+func (m *memoryImpl) DropIndex(ctx context.Context, collName string, idx Index) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, other := range m.indexes[collName] {
+		if reflect.DeepEqual(other, idx) {
+			m.indexes[collName] = append(m.indexes[collName][:i:i], m.indexes[collName][i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *memoryImpl) Disconnect() {}
+
+//filter returns the documents of the collection matching the filter, the caller must hold the lock
+func (m *memoryImpl) filter(collName string, filter map[string]interface{}) []map[string]interface{} {
+	var docs []map[string]interface{}
+	for _, d := range m.colls[collName] {
+		if matches(d, filter) {
+			docs = append(docs, d)
+		}
+	}
+	return docs
+}
+
+//duplicate reports whether the document breaks the _id or an unique index of the collection. The
+//document at position skip is the one being replaced. The caller must hold the lock.
+func (m *memoryImpl) duplicate(collName string, d map[string]interface{}, skip int) bool {
+	indexes := append([]Index{{Keys: []string{"_id"}, Unique: true}}, m.indexes[collName]...)
+	for i, other := range m.colls[collName] {
+		if i == skip {
+			continue
+		}
+		for _, idx := range indexes {
+			if !idx.Unique {
+				continue
+			}
+			same := true
+			for _, k := range idx.Keys {
+				k = strings.TrimPrefix(k, "-")
+				same = same && equals(lookup(other, k), lookup(d, k))
+			}
+			if same {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func page(docs []map[string]interface{}, opts FindOptions) []map[string]interface{} {
+	if opts.Skip >= int64(len(docs)) {
+		return nil
+	}
+	docs = docs[opts.Skip:]
+	if opts.Limit > 0 && opts.Limit < int64(len(docs)) {
+		docs = docs[:opts.Limit]
+	}
+	return docs
+}
+
+func sortDocs(docs []map[string]interface{}, fields []string) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, f := range fields {
+			order := 1
+			if strings.HasPrefix(f, "-") {
+				order, f = -1, strings.TrimPrefix(f, "-")
+			}
+			if c := compareValues(lookup(docs[i], f), lookup(docs[j], f)); c != 0 {
+				return c*order < 0
+			}
+		}
+		return false
+	})
+}
+
+//toDoc turns a struct, map or pointer to them in the generic document representation of bson
+func toDoc(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return map[string]interface{}{}, nil
+	}
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	d := map[string]interface{}{}
+	return d, bson.Unmarshal(b, &d)
+}
+
+func decodeDoc(d map[string]interface{}, doc interface{}) error {
+	b, err := bson.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(b, doc)
+}
+
+func decodeDocs(docs []map[string]interface{}, doc interface{}) error {
+	resultv := reflect.ValueOf(doc)
+	if resultv.Kind() != reflect.Ptr || resultv.Elem().Kind() != reflect.Slice {
+		return errors.New("failed to return array response")
+	}
+
+	slicev := reflect.MakeSlice(resultv.Elem().Type(), 0, len(docs))
+	elem := slicev.Type().Elem()
+	for _, d := range docs {
+		elemp := reflect.New(elem)
+		if err := decodeDoc(d, elemp.Interface()); err != nil {
+			return err
+		}
+		slicev = reflect.Append(slicev, elemp.Elem())
+	}
+	resultv.Elem().Set(slicev)
+	return nil
+}
+
+func copyDoc(d map[string]interface{}) map[string]interface{} {
+	return copyValue(d).(map[string]interface{})
+}
+
+func copyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			c[k] = copyValue(e)
+		}
+		return c
+	case primitive.A:
+		return primitive.A(copyValue([]interface{}(t)).([]interface{}))
+	case []interface{}:
+		c := make([]interface{}, len(t))
+		for i, e := range t {
+			c[i] = copyValue(e)
+		}
+		return c
+	}
+	return v
+}
+
+//lookup returns the value of a dotted path of the document, nil when it does not exist
+func lookup(d map[string]interface{}, path string) interface{} {
+	var v interface{} = d
+	for _, p := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if v, ok = m[p]; !ok {
+			return nil
+		}
+	}
+	return v
+}
+
+func exists(d map[string]interface{}, path string) bool {
+	parts := strings.Split(path, ".")
+	m := d
+	for i, p := range parts {
+		v, ok := m[p]
+		if !ok {
+			return false
+		}
+		if i == len(parts)-1 {
+			return true
+		}
+		if m, ok = v.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return false
+}
+
+//matches reports whether the document satisfies the filter
+func matches(d map[string]interface{}, filter map[string]interface{}) bool {
+	for k, cond := range filter {
+		switch k {
+		case "$and", "$or", "$nor":
+			subs, _ := asArray(cond)
+			matched := false
+			for _, s := range subs {
+				sub, _ := s.(map[string]interface{})
+				ok := matches(d, sub)
+				if k == "$and" && !ok {
+					return false
+				}
+				matched = matched || ok
+			}
+			if (k == "$or" && !matched) || (k == "$nor" && matched) {
+				return false
+			}
+		default:
+			if !matchesField(d, k, cond) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchesField(d map[string]interface{}, path string, cond interface{}) bool {
+	ops, ok := cond.(map[string]interface{})
+	if !ok || !isOperatorDoc(ops) {
+		return equals(lookup(d, path), cond)
+	}
+	v := lookup(d, path)
+	for op, arg := range ops {
+		var ok bool
+		switch op {
+		case "$eq":
+			ok = equals(v, arg)
+		case "$ne":
+			ok = !equals(v, arg)
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = compareOp(v, op, arg)
+		case "$in":
+			ok = in(v, arg)
+		case "$nin":
+			ok = !in(v, arg)
+		case "$all":
+			ok = all(v, arg)
+		case "$exists":
+			want, _ := arg.(bool)
+			ok = exists(d, path) == want
+		case "$not":
+			sub, _ := arg.(map[string]interface{})
+			ok = !matchesField(d, path, sub)
+		default:
+			return false
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func isOperatorDoc(m map[string]interface{}) bool {
+	for k := range m {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+	return len(m) > 0
+}
+
+//equals compares like mongo does: arrays match when one of their elements does and nil matches missing fields
+func equals(v, want interface{}) bool {
+	if arr, ok := asArray(v); ok {
+		if _, wantArr := asArray(want); !wantArr {
+			for _, e := range arr {
+				if equals(e, want) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	if isNumber(v) && isNumber(want) {
+		return toFloat(v) == toFloat(want)
+	}
+	return reflect.DeepEqual(normalize(v), normalize(want))
+}
+
+func in(v, arg interface{}) bool {
+	values, _ := asArray(arg)
+	for _, want := range values {
+		if equals(v, want) {
+			return true
+		}
+	}
+	return false
+}
+
+//all reports whether every value of arg is one of v, which is an array or a single value
+func all(v, arg interface{}) bool {
+	values, _ := asArray(arg)
+	for _, want := range values {
+		if !equals(v, want) {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+func compareOp(v interface{}, op string, arg interface{}) bool {
+	if arr, ok := asArray(v); ok {
+		for _, e := range arr {
+			if compareOp(e, op, arg) {
+				return true
+			}
+		}
+		return false
+	}
+	if v == nil || arg == nil || typeOrder(v) != typeOrder(arg) {
+		return false
+	}
+	c := compareValues(v, arg)
+	switch op {
+	case "$gt":
+		return c > 0
+	case "$gte":
+		return c >= 0
+	case "$lt":
+		return c < 0
+	}
+	return c <= 0
+}
+
+//compareValues orders values of different types the way mongo sorts them and values of the same type naturally
+func compareValues(a, b interface{}) int {
+	if ta, tb := typeOrder(a), typeOrder(b); ta != tb {
+		return ta - tb
+	}
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		} else if !x {
+			return -1
+		}
+		return 1
+	case primitive.DateTime:
+		return compareFloat(float64(x), float64(b.(primitive.DateTime)))
+	case primitive.ObjectID:
+		return strings.Compare(x.Hex(), b.(primitive.ObjectID).Hex())
+	}
+	if isNumber(a) {
+		return compareFloat(toFloat(a), toFloat(b))
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case int32, int64, float64, int:
+		return 1
+	case string:
+		return 2
+	case map[string]interface{}:
+		return 3
+	case primitive.A, []interface{}:
+		return 4
+	case primitive.ObjectID:
+		return 5
+	case bool:
+		return 6
+	case primitive.DateTime:
+		return 7
+	}
+	return 8
+}
+
+func isNumber(v interface{}) bool {
+	return typeOrder(v) == 1
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func toInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	}
+	return int64(toFloat(v))
+}
+
+func asArray(v interface{}) ([]interface{}, bool) {
+	switch a := v.(type) {
+	case primitive.A:
+		return a, true
+	case []interface{}:
+		return a, true
+	}
+	return nil, false
+}
+
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case primitive.A:
+		return normalize([]interface{}(t))
+	case []interface{}:
+		c := make([]interface{}, len(t))
+		for i, e := range t {
+			c[i] = normalize(e)
+		}
+		return c
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			c[k] = normalize(e)
+		}
+		return c
+	}
+	if isNumber(v) {
+		return toFloat(v)
+	}
+	return v
+}
+
+//applyUpdate applies the update operators to the document
+func applyUpdate(d map[string]interface{}, update map[string]interface{}) error {
+	for op, arg := range update {
+		fields, ok := arg.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("update operator %s must be a document", op)
+		}
+		for path, v := range fields {
+			switch op {
+			case "$set":
+				setPath(d, path, v)
+			case "$unset":
+				unsetPath(d, path)
+			case "$inc":
+				cur := lookup(d, path)
+				if cur != nil && !isNumber(cur) {
+					return fmt.Errorf("cannot $inc non numeric field %s", path)
+				}
+				setPath(d, path, add(cur, v))
+			default:
+				return fmt.Errorf("unknown update operator %s", op)
+			}
+		}
+	}
+	return nil
+}
+
+func add(a, b interface{}) interface{} {
+	if a == nil {
+		return b
+	}
+	_, aFloat := a.(float64)
+	_, bFloat := b.(float64)
+	if aFloat || bFloat {
+		return toFloat(a) + toFloat(b)
+	}
+	return toInt(a) + toInt(b)
+}
+
+func setPath(d map[string]interface{}, path string, v interface{}) {
+	parts := strings.Split(path, ".")
+	m := d
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[p] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = copyValue(v)
+}
+
+func unsetPath(d map[string]interface{}, path string) {
+	parts := strings.Split(path, ".")
+	m := d
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(map[string]interface{})
+		if !ok {
+			return
+		}
+		m = next
+	}
+	delete(m, parts[len(parts)-1])
+}
+
+//words splits the text in lower case words
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memDoc struct {
+	Name  string
+	Price float64
+	Tags  []string
+	Meta  struct{ Level int }
+}
+
+func newTestMemory(t *testing.T, docs ...memDoc) DataAccessLayer {
+	m := NewMemory()
+	assert.NoError(t, m.Initialize(context.Background(), "", ""))
+	for _, d := range docs {
+		assert.NoError(t, m.Insert(context.Background(), "docs", d))
+	}
+	return m
+}
+
+func TestMemoryFind(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t,
+		memDoc{Name: "a", Price: 30, Tags: []string{"go"}},
+		memDoc{Name: "b", Price: 10, Tags: []string{"js"}},
+		memDoc{Name: "c", Price: 20, Tags: []string{"go", "js"}},
+		memDoc{Name: "d", Price: 0},
+	)
+	tests := []struct {
+		name  string
+		query map[string]interface{}
+		opts  FindOptions
+		want  []string
+	}{
+		{"All", map[string]interface{}{}, FindOptions{}, []string{"a", "b", "c", "d"}},
+		{"Equality", map[string]interface{}{"name": "b"}, FindOptions{}, []string{"b"}},
+		{"Array contains", map[string]interface{}{"tags": "go"}, FindOptions{}, []string{"a", "c"}},
+		{"Range", map[string]interface{}{"price": map[string]interface{}{"$gte": 10, "$lt": 30}}, FindOptions{}, []string{"b", "c"}},
+		{"In", map[string]interface{}{"name": map[string]interface{}{"$in": []string{"a", "d"}}}, FindOptions{}, []string{"a", "d"}},
+		{"Or", map[string]interface{}{"$or": []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"price": 0}}}, FindOptions{}, []string{"a", "d"}},
+		{"Nil matches null", map[string]interface{}{"tags": nil}, FindOptions{}, []string{"d"}},
+		{"Sort desc", map[string]interface{}{}, FindOptions{Sort: []string{"-price"}}, []string{"a", "c", "b", "d"}},
+		{"Skip and limit", map[string]interface{}{}, FindOptions{Sort: []string{"price"}, Skip: 1, Limit: 2}, []string{"b", "c"}},
+		{"Skip past end", map[string]interface{}{}, FindOptions{Skip: 10}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs []memDoc
+			assert.NoError(t, m.Find(ctx, "docs", tt.query, tt.opts, &docs))
+			names := []string{}
+			for _, d := range docs {
+				names = append(names, d.Name)
+			}
+			assert.Equal(t, tt.want, names)
+
+			if tt.opts.Skip == 0 && tt.opts.Limit == 0 {
+				n, err := m.Count(ctx, "docs", tt.query)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(len(tt.want)), n)
+			}
+		})
+	}
+}
+
+func TestMemoryUpdateAndRemove(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t, memDoc{Name: "a", Price: 10})
+
+	sel := map[string]interface{}{"name": "a"}
+	assert.NoError(t, m.Update(ctx, "docs", sel, map[string]interface{}{"$set": &memDoc{Name: "a", Price: 15}}))
+	assert.NoError(t, m.Update(ctx, "docs", sel, map[string]interface{}{"$inc": map[string]interface{}{"price": 5, "meta.level": 1}}))
+
+	var d memDoc
+	assert.NoError(t, m.FindOne(ctx, "docs", sel, &d))
+	assert.Equal(t, 20.0, d.Price)
+	assert.Equal(t, 1, d.Meta.Level)
+
+	assert.NoError(t, m.Update(ctx, "docs", sel, map[string]interface{}{"$unset": map[string]interface{}{"price": ""}}))
+	assert.NoError(t, m.FindOne(ctx, "docs", map[string]interface{}{"price": map[string]interface{}{"$exists": false}}, &d))
+
+	assert.NoError(t, m.Remove(ctx, "docs", sel))
+	assert.Equal(t, ErrNotFound, m.FindOne(ctx, "docs", sel, &d))
+	assert.Equal(t, ErrNotFound, m.Update(ctx, "docs", sel, map[string]interface{}{"$set": &memDoc{Name: "a"}}))
+	assert.Equal(t, ErrNotFound, m.Remove(ctx, "docs", sel))
+}
+
+func TestMemoryInitialize_SetInstance(t *testing.T) {
+	m := newTestMemory(t)
+	assert.True(t, GetInstance() != m)
+	SetInstance(m)
+	assert.True(t, GetInstance() == m)
+}
+
+func TestMemoryWithTransaction(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t)
+	errTx := errors.New("rollback")
+
+	err := m.WithTransaction(ctx, func(ctx context.Context) error {
+		assert.NoError(t, m.Insert(ctx, "docs", memDoc{Name: "a"}))
+		return errTx
+	})
+	assert.Equal(t, errTx, err)
+	n, _ := m.Count(ctx, "docs", map[string]interface{}{})
+	assert.Equal(t, int64(0), n)
+
+	err = m.WithTransaction(ctx, func(ctx context.Context) error {
+		return m.Insert(ctx, "docs", memDoc{Name: "a"})
+	})
+	assert.NoError(t, err)
+	n, _ = m.Count(ctx, "docs", map[string]interface{}{})
+	assert.Equal(t, int64(1), n)
+}
+
+func TestMemoryWithTransaction_UndoesOwnWrites(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t, memDoc{Name: "a", Price: 1}, memDoc{Name: "b", Price: 2}, memDoc{Name: "c", Price: 3})
+	errTx := errors.New("rollback")
+
+	err := m.WithTransaction(ctx, func(txCtx context.Context) error {
+		assert.NoError(t, m.Insert(txCtx, "docs", memDoc{Name: "d"}))
+		assert.NoError(t, m.Update(txCtx, "docs", map[string]interface{}{"name": "a"}, map[string]interface{}{
+			"$set": map[string]interface{}{"price": 10},
+		}))
+		assert.NoError(t, m.Remove(txCtx, "docs", map[string]interface{}{"name": "b"}))
+		//writes out of the transaction, like the ones of other requests
+		assert.NoError(t, m.Insert(ctx, "docs", memDoc{Name: "e"}))
+		assert.NoError(t, m.Update(ctx, "docs", map[string]interface{}{"name": "c"}, map[string]interface{}{
+			"$set": map[string]interface{}{"price": 30},
+		}))
+		return errTx
+	})
+	assert.Equal(t, errTx, err)
+
+	var docs []memDoc
+	assert.NoError(t, m.Find(ctx, "docs", map[string]interface{}{}, FindOptions{}, &docs))
+	assert.Equal(t, []memDoc{{Name: "a", Price: 1}, {Name: "b", Price: 2}, {Name: "c", Price: 30}, {Name: "e"}}, docs)
+}
+
+func TestMemorySearch(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t, memDoc{Name: "Go basics"}, memDoc{Name: "Advanced go: going further"}, memDoc{Name: "Rust"})
+	var docs []memDoc
+
+	assert.Error(t, m.Search(ctx, "docs", "go", nil, FindOptions{}, &docs))
+
+	assert.NoError(t, m.EnsureIndex(ctx, "docs", Index{Keys: []string{"name"}, Text: true}))
+	assert.NoError(t, m.Search(ctx, "docs", "go", nil, FindOptions{}, &docs))
+	assert.Len(t, docs, 2)
+	assert.Equal(t, "Advanced go: going further", docs[0].Name)
+}
+
+func TestMemoryUniqueIndex(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t, memDoc{Name: "a"}, memDoc{Name: "b"})
+	assert.NoError(t, m.EnsureIndex(ctx, "docs", Index{Keys: []string{"name"}, Unique: true}))
+
+	assert.Equal(t, ErrDuplicate, m.Insert(ctx, "docs", memDoc{Name: "a"}))
+	assert.Equal(t, ErrDuplicate, m.Update(ctx, "docs", map[string]interface{}{"name": "b"},
+		map[string]interface{}{"$set": map[string]interface{}{"name": "a"}}))
+	assert.NoError(t, m.Update(ctx, "docs", map[string]interface{}{"name": "b"},
+		map[string]interface{}{"$set": map[string]interface{}{"name": "c"}}))
+
+	n, err := m.Count(ctx, "docs", map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestMemoryFacet(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t,
+		memDoc{Name: "a", Price: 10, Tags: []string{"go", "web"}},
+		memDoc{Name: "b", Price: 20, Tags: []string{"go"}},
+		memDoc{Name: "c", Price: 30, Tags: []string{"rust"}},
+		memDoc{Name: "d", Price: 40},
+	)
+
+	counts, err := m.Facet(ctx, "docs", nil, "tags")
+	assert.NoError(t, err)
+	assert.Equal(t, []FacetCount{{Value: "go", Count: 2}, {Value: "rust", Count: 1}, {Value: "web", Count: 1}}, counts)
+
+	counts, err = m.Facet(ctx, "docs", map[string]interface{}{"price": map[string]interface{}{"$lte": 20}}, "tags")
+	assert.NoError(t, err)
+	assert.Equal(t, []FacetCount{{Value: "go", Count: 2}, {Value: "web", Count: 1}}, counts)
+
+	var docs []memDoc
+	assert.NoError(t, m.Find(ctx, "docs", map[string]interface{}{"tags": map[string]interface{}{"$all": []string{"go", "web"}}}, FindOptions{}, &docs))
+	assert.Len(t, docs, 1)
+
+	counts, err = m.Facet(ctx, "docs", nil, "name")
+	assert.NoError(t, err)
+	assert.Len(t, counts, 4)
+}
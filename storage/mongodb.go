@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -20,12 +21,16 @@ var (
 //DataAccessLayer is an interface for db connection
 type DataAccessLayer interface {
 	Insert(context.Context, string, interface{}) error
-	Find(context.Context, string, map[string]interface{}, interface{}) error
+	Find(context.Context, string, map[string]interface{}, FindOptions, interface{}) error
 	FindOne(context.Context, string, map[string]interface{}, interface{}) error
+	Search(context.Context, string, string, map[string]interface{}, FindOptions, interface{}) error
 	Count(context.Context, string, map[string]interface{}) (int64, error)
+	Facet(context.Context, string, map[string]interface{}, string) ([]FacetCount, error)
 	Update(context.Context, string, map[string]interface{}, interface{}) error
 	Remove(context.Context, string, map[string]interface{}) error
 	WithTransaction(context.Context, func(context.Context) error) error
+	EnsureIndex(context.Context, string, Index) error
+	DropIndex(context.Context, string, Index) error
 	Initialize(context.Context, string, string) error
 	Disconnect()
 }
@@ -40,6 +45,11 @@ func GetInstance() DataAccessLayer {
 	return instance
 }
 
+//SetInstance makes the data access layer the one returned by GetInstance
+func SetInstance(d DataAccessLayer) {
+	instance = d
+}
+
 type mongodbImpl struct {
 	client *mongo.Client
 	dbName string
@@ -66,9 +76,9 @@ func (m *mongodbImpl) WithTransaction(ctx context.Context, fn func(context.Conte
 		if err != nil {
 			return err
 		}
-		err = fn(sessionContext)
-		if err != nil {
-			return sessionContext.AbortTransaction(sessionContext)
+		if err = fn(sessionContext); err != nil {
+			_ = sessionContext.AbortTransaction(sessionContext)
+			return err
 		}
 		return sessionContext.CommitTransaction(sessionContext)
 	})
@@ -77,16 +87,35 @@ func (m *mongodbImpl) WithTransaction(ctx context.Context, fn func(context.Conte
 // Insert stores documents in the collection
 func (m *mongodbImpl) Insert(ctx context.Context, collName string, doc interface{}) error {
 	_, err := m.client.Database(m.dbName).Collection(collName).InsertOne(ctx, doc)
-	return err
+	return mongoErr(err)
 }
 
 // Find finds all documents in the collection
-func (m *mongodbImpl) Find(ctx context.Context, collName string, query map[string]interface{}, doc interface{}) error {
-	cur, err := m.client.Database(m.dbName).Collection(collName).Find(ctx, query)
+func (m *mongodbImpl) Find(ctx context.Context, collName string, query map[string]interface{}, opts FindOptions, doc interface{}) error {
+	cur, err := m.client.Database(m.dbName).Collection(collName).Find(ctx, query, opts.mongo())
+	if err != nil {
+		return err
+	}
+	return decodeAll(ctx, cur, doc)
+}
+
+// Search finds the documents matching the text of the collection text index, the most relevant first
+func (m *mongodbImpl) Search(ctx context.Context, collName, text string, query map[string]interface{}, opts FindOptions, doc interface{}) error {
+	filter := map[string]interface{}{"$text": map[string]interface{}{"$search": text}}
+	for k, v := range query {
+		filter[k] = v
+	}
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+	findOpts := opts.mongo().SetProjection(score).SetSort(score)
+
+	cur, err := m.client.Database(m.dbName).Collection(collName).Find(ctx, filter, findOpts)
 	if err != nil {
 		return err
 	}
+	return decodeAll(ctx, cur, doc)
+}
 
+func decodeAll(ctx context.Context, cur *mongo.Cursor, doc interface{}) error {
 	resultv := reflect.ValueOf(doc)
 	if resultv.Kind() != reflect.Ptr || resultv.Elem().Kind() != reflect.Slice {
 		return errors.New("failed to return array response")
@@ -118,16 +147,28 @@ func (m *mongodbImpl) FindOne(ctx context.Context, collName string, query map[st
 	return m.client.Database(m.dbName).Collection(collName).FindOne(ctx, query).Decode(doc)
 }
 
-// Update updates one or more documents in the collection
+// Update updates the first document of the collection matching the selector, ErrNotFound when none does
 func (m *mongodbImpl) Update(ctx context.Context, collName string, selector map[string]interface{}, update interface{}) error {
-	_, err := m.client.Database(m.dbName).Collection(collName).UpdateOne(ctx, selector, update)
-	return err
+	res, err := m.client.Database(m.dbName).Collection(collName).UpdateOne(ctx, selector, update)
+	if err != nil {
+		return mongoErr(err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
-// Remove one or more documents in the collection
+// Remove removes the first document of the collection matching the selector, ErrNotFound when none does
 func (m *mongodbImpl) Remove(ctx context.Context, collName string, selector map[string]interface{}) error {
-	_, err := m.client.Database(m.dbName).Collection(collName).DeleteOne(ctx, selector)
-	return err
+	res, err := m.client.Database(m.dbName).Collection(collName).DeleteOne(ctx, selector)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
 
 // Count returns the number of documents of the query
@@ -135,6 +176,38 @@ func (m *mongodbImpl) Count(ctx context.Context, collName string, query map[stri
 	return m.client.Database(m.dbName).Collection(collName).CountDocuments(ctx, query)
 }
 
+// Facet counts the documents of the query by the values of the field, the most frequent first. Each value of an
+// array field counts, documents without the field do not.
+func (m *mongodbImpl) Facet(ctx context.Context, collName string, query map[string]interface{}, field string) ([]FacetCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: query}},
+		{{Key: "$unwind", Value: "$" + field}},
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$" + field}, {Key: "count", Value: bson.M{"$sum": 1}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	cur, err := m.client.Database(m.dbName).Collection(collName).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var counts []FacetCount
+	return counts, decodeAll(ctx, cur, &counts)
+}
+
+// EnsureIndex creates the index in the collection when it does not exist yet
+func (m *mongodbImpl) EnsureIndex(ctx context.Context, collName string, idx Index) error {
+	_, err := m.client.Database(m.dbName).Collection(collName).Indexes().CreateOne(ctx, idx.mongo())
+	return err
+}
+
+// DropIndex removes the index of the collection, indexes which do not exist are not an error
+func (m *mongodbImpl) DropIndex(ctx context.Context, collName string, idx Index) error {
+	_, err := m.client.Database(m.dbName).Collection(collName).Indexes().DropOne(ctx, idx.name())
+	if e, ok := err.(mongo.CommandError); ok && (e.Code == indexNotFoundCode || e.Code == namespaceNotFoundCode) {
+		return nil
+	}
+	return err
+}
+
 func (m *mongodbImpl) Disconnect() {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
@@ -11,10 +11,13 @@ type DataAccessLayerMock struct {
 	mock.Mock
 }
 
-//WithTransaction is a mock for db WithTransaction
+//WithTransaction is a mock for db WithTransaction, fn is run unless the mocked call fails
 func (m *DataAccessLayerMock) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
 	args := m.Called(ctx, fn)
-	return args.Error(0)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(ctx)
 }
 
 //Initialize is a mock for db Initialize
@@ -36,8 +39,14 @@ func (m *DataAccessLayerMock) FindOne(ctx context.Context, collName string, quer
 }
 
 //Find is a mock for db Find
-func (m *DataAccessLayerMock) Find(ctx context.Context, collName string, query map[string]interface{}, doc interface{}) error {
-	args := m.Called(ctx, collName, query, doc)
+func (m *DataAccessLayerMock) Find(ctx context.Context, collName string, query map[string]interface{}, opts FindOptions, doc interface{}) error {
+	args := m.Called(ctx, collName, query, opts, doc)
+	return args.Error(0)
+}
+
+//Search is a mock for db Search
+func (m *DataAccessLayerMock) Search(ctx context.Context, collName, text string, query map[string]interface{}, opts FindOptions, doc interface{}) error {
+	args := m.Called(ctx, collName, text, query, opts, doc)
 	return args.Error(0)
 }
 
@@ -59,5 +68,23 @@ func (m *DataAccessLayerMock) Remove(ctx context.Context, collName string, selec
 	return args.Error(0)
 }
 
+//Facet is a mock for db Facet
+func (m *DataAccessLayerMock) Facet(ctx context.Context, collName string, query map[string]interface{}, field string) ([]FacetCount, error) {
+	args := m.Called(ctx, collName, query, field)
+	return args.Get(0).([]FacetCount), args.Error(1)
+}
+
+//EnsureIndex is a mock for EnsureIndex
+func (m *DataAccessLayerMock) EnsureIndex(ctx context.Context, collName string, idx Index) error {
+	args := m.Called(ctx, collName, idx)
+	return args.Error(0)
+}
+
+//DropIndex is a mock for DropIndex
+func (m *DataAccessLayerMock) DropIndex(ctx context.Context, collName string, idx Index) error {
+	args := m.Called(ctx, collName, idx)
+	return args.Error(0)
+}
+
 //Disconnect is a mock for Disconnect
 func (m *DataAccessLayerMock) Disconnect() {}
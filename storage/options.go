@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//FindOptions limits, skips and sorts the documents returned by Find.
+//Sort holds field names, a leading "-" sorts that field in descending order.
+type FindOptions struct {
+	Skip  int64
+	Limit int64
+	Sort  []string
+}
+
+func (o FindOptions) mongo() *options.FindOptions {
+	opts := options.Find()
+	if o.Skip > 0 {
+		opts.SetSkip(o.Skip)
+	}
+	if o.Limit > 0 {
+		opts.SetLimit(o.Limit)
+	}
+	if len(o.Sort) > 0 {
+		sort := bson.D{}
+		for _, field := range o.Sort {
+			order := 1
+			if strings.HasPrefix(field, "-") {
+				order = -1
+				field = strings.TrimPrefix(field, "-")
+			}
+			sort = append(sort, bson.E{Key: field, Value: order})
+		}
+		opts.SetSort(sort)
+	}
+	return opts
+}
+
+//FacetCount is how many documents have a value in the field counted by Facet
+type FacetCount struct {
+	Value string `json:"value" bson:"_id"`
+	Count int64  `json:"count"`
+}
+
+//Index describes an index of a collection. A Text index is the one matched by Search.
+//Keys holds field names like the Sort of FindOptions, a leading "-" indexes that field in descending order.
+type Index struct {
+	Keys   []string
+	Unique bool
+	Text   bool
+}
+
+func (i Index) mongo() mongo.IndexModel {
+	keys := bson.D{}
+	for _, k := range i.Keys {
+		var kind interface{} = 1
+		if strings.HasPrefix(k, "-") {
+			kind = -1
+			k = strings.TrimPrefix(k, "-")
+		}
+		if i.Text {
+			kind = "text"
+		}
+		keys = append(keys, bson.E{Key: k, Value: kind})
+	}
+	return mongo.IndexModel{Keys: keys, Options: options.Index().SetUnique(i.Unique)}
+}
+
+//name is the name mongo gives to the index, its keys and their kind joined by "_"
+func (i Index) name() string {
+	var parts []string
+	for _, k := range i.mongo().Keys.(bson.D) {
+		parts = append(parts, fmt.Sprintf("%s_%v", k.Key, k.Value))
+	}
+	return strings.Join(parts, "_")
+}
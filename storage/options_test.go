@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestIndexMongo(t *testing.T) {
+	tests := []struct {
+		name string
+		idx  Index
+		keys bson.D
+	}{
+		{"Ascending", Index{Keys: []string{"courseId", "status"}}, bson.D{{Key: "courseId", Value: 1}, {Key: "status", Value: 1}}},
+		{"Descending", Index{Keys: []string{"courseId", "-at"}}, bson.D{{Key: "courseId", Value: 1}, {Key: "at", Value: -1}}},
+		{"Text", Index{Keys: []string{"name"}, Text: true}, bson.D{{Key: "name", Value: "text"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.keys, tt.idx.mongo().Keys)
+		})
+	}
+}
+
+func TestMemoryUniqueIndex_Descending(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t, memDoc{Name: "a", Price: 1})
+	assert.NoError(t, m.EnsureIndex(ctx, "docs", Index{Keys: []string{"name", "-price"}, Unique: true}))
+
+	assert.Equal(t, ErrDuplicate, m.Insert(ctx, "docs", memDoc{Name: "a", Price: 1}))
+	assert.NoError(t, m.Insert(ctx, "docs", memDoc{Name: "a", Price: 2}))
+}
+
+func TestMemoryDropIndex(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMemory(t, memDoc{Name: "a"})
+	idx := Index{Keys: []string{"name"}, Unique: true}
+	assert.NoError(t, m.EnsureIndex(ctx, "docs", idx))
+	assert.Equal(t, ErrDuplicate, m.Insert(ctx, "docs", memDoc{Name: "a"}))
+
+	assert.NoError(t, m.DropIndex(ctx, "docs", idx))
+	assert.NoError(t, m.DropIndex(ctx, "docs", idx))
+	assert.NoError(t, m.Insert(ctx, "docs", memDoc{Name: "a"}))
+}
+
+func TestIndexName(t *testing.T) {
+	assert.Equal(t, "name_1", Index{Keys: []string{"name"}, Unique: true}.name())
+	assert.Equal(t, "courseId_1_at_-1", Index{Keys: []string{"courseId", "-at"}}.name())
+	assert.Equal(t, "name_text_description_text", Index{Keys: []string{"name", "description"}, Text: true}.name())
+}
@@ -0,0 +1,51 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ednesic/coursemanagement/cache"
+	"github.com/ednesic/coursemanagement/storage"
+	"github.com/ednesic/coursemanagement/types"
+	"github.com/stretchr/testify/assert"
+)
+
+//Courses creates courses and moves them through their lifecycle, as courseservice.CourseService does. The tests of
+//the course service pass it without importing it back.
+type Courses interface {
+	Create(context.Context, types.Course) (types.Course, error)
+	Transition(context.Context, string, string, time.Time, int64) (types.Course, error)
+}
+
+//Memory makes a memory storage and an LRU cache the instances the services use, then creates the indexes of the
+//services
+func Memory(t *testing.T, indexes ...func(context.Context) error) {
+	ctx := context.Background()
+	db := storage.NewMemory()
+	assert.NoError(t, db.Initialize(ctx, "", ""))
+	storage.SetInstance(db)
+	lc := cache.NewLRU()
+	lc.Initialize(map[string]string{})
+	cache.SetInstance(lc)
+	for _, ensure := range indexes {
+		assert.NoError(t, ensure(ctx))
+	}
+}
+
+//PublishedCourse creates the course and publishes it, so it is open for enrollment
+func PublishedCourse(t *testing.T, s Courses, c types.Course) types.Course {
+	ctx := context.Background()
+	c, err := s.Create(ctx, c)
+	assert.NoError(t, err)
+	_, err = s.Transition(ctx, c.ID, types.StatusInReview, time.Time{}, 0)
+	assert.NoError(t, err)
+	c, err = s.Transition(ctx, c.ID, types.StatusPublished, time.Time{}, 0)
+	assert.NoError(t, err)
+	return c
+}
+
+//USD is the amount of cents in US dollars
+func USD(cents int64) types.Money {
+	return types.Money{Amount: cents, Currency: "USD"}
+}
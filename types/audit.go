@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+//AuditEntry records a change to a course: who made it, when, and the course before and after it
+type AuditEntry struct {
+	ID       string    `json:"id" bson:"_id"`
+	CourseID string    `json:"courseId" bson:"courseId"`
+	Version  int64     `json:"version"`
+	Action   string    `json:"action"`
+	Actor    string    `json:"actor"`
+	At       time.Time `json:"at"`
+	Before   *Course   `json:"before,omitempty" bson:"before,omitempty"`
+	After    *Course   `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+//FieldChange is a field of a course with different values in two of its revisions
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
@@ -0,0 +1,11 @@
+package types
+
+//Bundle is a group of courses sold together for its price. Only active bundles are sold and shown on their courses.
+type Bundle struct {
+	ID          string   `json:"id" bson:"_id"`
+	Name        string   `json:"name" validate:"required,max=120"`
+	Description string   `json:"description" validate:"max=5000"`
+	Courses     []string `json:"courses" validate:"min=2,max=50,dive,required"`
+	Price       Money    `json:"price"`
+	Active      bool     `json:"active"`
+}
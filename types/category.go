@@ -0,0 +1,9 @@
+package types
+
+//Category groups courses in a tree. Its path is the ids of its ancestors, from the root down to its parent.
+type Category struct {
+	ID     string   `json:"id" bson:"_id"`
+	Name   string   `json:"name" validate:"required,max=60"`
+	Parent string   `json:"parent,omitempty" bson:"parent,omitempty"`
+	Path   []string `json:"path,omitempty" bson:"path,omitempty"`
+}
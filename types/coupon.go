@@ -0,0 +1,46 @@
+package types
+
+import "time"
+
+//Kinds of coupons, discounting a percentage of the price or a fixed amount of it
+const (
+	CouponPercent = "percent"
+	CouponFixed   = "fixed"
+)
+
+//Coupon is a code discounting the price of courses by a percentage or a fixed amount. It is valid from StartsAt and
+//until EndsAt when they are set, MaxUses times and MaxUsesPerStudent times per student, 0 is unlimited. It applies
+//to the courses of Courses and of the categories of Categories and their descendants, to every course when both
+//are empty. Uses is how many times it was redeemed, kept by the redemptions themselves.
+type Coupon struct {
+	Code              string     `json:"code" bson:"_id" validate:"required,max=40"`
+	Kind              string     `json:"kind" validate:"required,oneof=percent fixed"`
+	Percent           int64      `json:"percent,omitempty" bson:"percent,omitempty" validate:"gte=0,lte=100"`
+	Amount            *Money     `json:"amount,omitempty" bson:"amount,omitempty"`
+	StartsAt          *time.Time `json:"startsAt,omitempty" bson:"startsAt,omitempty"`
+	EndsAt            *time.Time `json:"endsAt,omitempty" bson:"endsAt,omitempty"`
+	MaxUses           int64      `json:"maxUses,omitempty" bson:"maxUses,omitempty" validate:"gte=0"`
+	MaxUsesPerStudent int64      `json:"maxUsesPerStudent,omitempty" bson:"maxUsesPerStudent,omitempty" validate:"gte=0"`
+	Courses           []string   `json:"courses,omitempty" bson:"courses,omitempty" validate:"dive,required"`
+	Categories        []string   `json:"categories,omitempty" bson:"categories,omitempty" validate:"dive,required"`
+	Uses              int64      `json:"uses"`
+}
+
+//Redemption is a use of a coupon by a student for a course and the discount it gave
+type Redemption struct {
+	ID       string    `json:"id" bson:"_id"`
+	Code     string    `json:"code"`
+	Student  string    `json:"student"`
+	CourseID string    `json:"courseId" bson:"courseId"`
+	Discount Money     `json:"discount"`
+	At       time.Time `json:"at"`
+}
+
+//Quote is the price of a course in a currency, the discount of a coupon and the total to pay
+type Quote struct {
+	CourseID string `json:"courseId"`
+	Coupon   string `json:"coupon,omitempty"`
+	Price    Money  `json:"price"`
+	Discount Money  `json:"discount"`
+	Total    Money  `json:"total"`
+}
@@ -1,9 +1,72 @@
 package types
 
+import "time"
+
+//Statuses of the lifecycle of a course. Courses stored before there was a lifecycle have no status and are published.
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in-review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
 //Course is a representation object of course
 type Course struct {
-	Name            string  `json:"name,omitempty"`
-	Price           float64 `json:"price,omitempty"`
-	Picture         string  `json:"picture,omitempty"`
-	PreviewURLVideo string  `json:"preview-url-video,omitempty"`
+	//ID is generated when the course is created
+	ID string `json:"id,omitempty" bson:"_id"`
+	//Slug is the name of the course in URLs, made from its name and unique among the live courses
+	Slug string `json:"slug,omitempty"`
+	//Version is raised by every change, changes sent with another version than 0 or the current one fail
+	Version int64 `json:"version,omitempty"`
+	//Status is the step of the lifecycle the course is in
+	Status string `json:"status,omitempty"`
+	//Author is the user who created the course
+	Author string `json:"author,omitempty"`
+	//Scheduled is the status the course moves to later, if any
+	Scheduled *ScheduledTransition `json:"scheduled,omitempty" bson:"scheduled,omitempty"`
+	//Instructors are the ids of the instructors teaching the course
+	Instructors []string `json:"instructors,omitempty" bson:"instructors,omitempty" validate:"dive,required"`
+	//Category is the id of the category of the course, which also belongs to the ancestors of the category
+	Category string `json:"category,omitempty" bson:"category,omitempty"`
+	//Tags are lowercase
+	Tags []string `json:"tags,omitempty" bson:"tags,omitempty" validate:"max=20,dive,required,max=30"`
+	//Name is the title of the course
+	Name string `json:"name" validate:"required,max=120"`
+	//Price is the base price of the course
+	Price Money `json:"price"`
+	//Prices are the regional price points, at most one per currency other than the one of Price
+	Prices []Money `json:"prices,omitempty" bson:"prices,omitempty" validate:"max=50,dive"`
+	//Capacity is how many students enroll before the next ones are waitlisted, 0 is unlimited
+	Capacity int64 `json:"capacity,omitempty" validate:"gte=0"`
+	//Picture is the URL of the cover of the course
+	Picture string `json:"picture" validate:"omitempty,url"`
+	//PreviewURLVideo is the URL of the video previewing the course
+	PreviewURLVideo string `json:"preview-url-video" validate:"omitempty,url"`
+	//Description is the text presenting the course
+	Description string `json:"description" validate:"max=5000"`
+	//Duration is the one of the curriculum, in seconds
+	Duration int64 `json:"duration"`
+	//Rating is the one of the visible reviews, kept by the reviews themselves
+	Rating *Rating `json:"rating,omitempty" bson:"rating,omitempty"`
+	//DeletedAt is when the course was moved to the trash, nil for live courses
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+}
+
+//ScheduledTransition is a status a course moves to at a later time
+type ScheduledTransition struct {
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+}
+
+//Rating is the average of the stars of the reviews of a course and how many there are
+type Rating struct {
+	Average float64 `json:"average"`
+	Count   int64   `json:"count"`
+	Stars   int64   `json:"-" bson:"stars"`
+}
+
+//CourseHit is a course found by a search and the highlighted snippets of its matching fields
+type CourseHit struct {
+	Course     Course            `json:"course"`
+	Highlights map[string]string `json:"highlights,omitempty"`
 }
@@ -0,0 +1,33 @@
+package types
+
+//Content types of the lessons
+const (
+	ContentVideo    = "video"
+	ContentArticle  = "article"
+	ContentQuiz     = "quiz"
+	ContentDownload = "download"
+)
+
+//Module is a section of the curriculum of a course. Its duration is the one of its lessons, in seconds.
+type Module struct {
+	ID       string   `json:"id,omitempty" bson:"_id"`
+	CourseID string   `json:"courseId,omitempty" bson:"courseId"`
+	Title    string   `json:"title" validate:"required,max=120"`
+	Order    int      `json:"order"`
+	Duration int64    `json:"duration"`
+	Lessons  []Lesson `json:"lessons" validate:"dive"`
+}
+
+//Lesson is a piece of content of a module, its duration is in seconds. The URL of free preview lessons is open to
+//everyone, the one of the others only to the owners and the students of the course. Students complete a course
+//without its optional lessons.
+type Lesson struct {
+	ID          string `json:"id,omitempty"`
+	Title       string `json:"title" validate:"required,max=120"`
+	Order       int    `json:"order"`
+	Duration    int64  `json:"duration" validate:"gte=0"`
+	ContentType string `json:"contentType" bson:"contentType" validate:"required,oneof=video article quiz download"`
+	URL         string `json:"url" validate:"omitempty,url"`
+	FreePreview bool   `json:"freePreview" bson:"freePreview"`
+	Optional    bool   `json:"optional,omitempty" bson:"optional,omitempty"`
+}
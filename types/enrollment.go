@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+//Statuses of the enrollments. Waitlisted students take the seats of the ones who cancel, first come first served.
+const (
+	EnrollmentActive     = "active"
+	EnrollmentWaitlisted = "waitlisted"
+	EnrollmentCancelled  = "cancelled"
+)
+
+//Enrollment is a student taking, or waiting for a seat in, a course
+type Enrollment struct {
+	ID       string    `json:"id" bson:"_id"`
+	CourseID string    `json:"courseId" bson:"courseId"`
+	Student  string    `json:"student"`
+	Status   string    `json:"status"`
+	At       time.Time `json:"at"`
+}
+
+//Seats counts the students enrolled in and waiting for a course. A capacity of 0 is unlimited.
+type Seats struct {
+	CourseID   string `json:"courseId" bson:"_id"`
+	Capacity   int64  `json:"capacity" bson:"-"`
+	Enrolled   int64  `json:"enrolled"`
+	Waitlisted int64  `json:"waitlisted"`
+}
@@ -0,0 +1,24 @@
+package types
+
+//Payout methods of the instructors
+const (
+	PayoutBank   = "bank"
+	PayoutPaypal = "paypal"
+)
+
+//Instructor is the profile of someone teaching courses. User is the actor the instructor makes requests as, it is
+//kept once the instructor is created.
+type Instructor struct {
+	ID     string  `json:"id,omitempty" bson:"_id"`
+	User   string  `json:"user" validate:"required,max=120"`
+	Name   string  `json:"name" validate:"required,max=120"`
+	Bio    string  `json:"bio" validate:"max=5000"`
+	Avatar string  `json:"avatar" validate:"omitempty,url"`
+	Payout *Payout `json:"payout,omitempty" bson:"payout,omitempty"`
+}
+
+//Payout is where the earnings of an instructor are paid, only the instructor sees it
+type Payout struct {
+	Method  string `json:"method" validate:"required,oneof=bank paypal"`
+	Account string `json:"account" validate:"required,max=120"`
+}
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+//DefaultCurrency is the currency of the prices given without one and of the prices stored before there were currencies
+const DefaultCurrency = "USD"
+
+//Money is an amount in the minor units of its ISO 4217 currency, cents for USD, so it is exact
+type Money struct {
+	Amount   int64  `json:"amount" validate:"gte=0"`
+	Currency string `json:"currency" validate:"omitempty,currency"`
+}
+
+//money is Money without its bson decoding, to decode it as a plain document
+type money Money
+
+//UnmarshalBSONValue decodes the money documents and the bare numbers prices were stored as before there were
+//currencies, which are major units of the default currency
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.EmbeddedDocument:
+		return bson.Unmarshal(data, (*money)(m))
+	case bsontype.Null, bsontype.Undefined:
+		*m = Money{}
+		return nil
+	}
+
+	raw := bson.RawValue{Type: t, Value: data}
+	var major *big.Rat
+	switch t {
+	case bsontype.Double:
+		major, _ = new(big.Rat).SetString(strconv.FormatFloat(raw.Double(), 'f', -1, 64))
+	case bsontype.Int32:
+		major = big.NewRat(int64(raw.Int32()), 1)
+	case bsontype.Int64:
+		major = big.NewRat(raw.Int64(), 1)
+	}
+	if major == nil {
+		return errors.New("money: cannot decode " + t.String())
+	}
+	//the legacy prices were in dollars, which have 2 decimal places
+	minor := major.Mul(major, big.NewRat(100, 1))
+	amount := new(big.Int).Quo(minor.Num(), minor.Denom())
+	if new(big.Int).Mul(new(big.Int).Rem(minor.Num(), minor.Denom()), big.NewInt(2)).CmpAbs(minor.Denom()) >= 0 {
+		amount.Add(amount, big.NewInt(int64(minor.Sign())))
+	}
+	*m = Money{Amount: amount.Int64(), Currency: DefaultCurrency}
+	return nil
+}
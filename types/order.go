@@ -0,0 +1,60 @@
+package types
+
+import "time"
+
+//Statuses of the orders. Pending orders are waiting for their payment, declined ones were never paid and refunded ones
+//gave back the payment of every line.
+const (
+	OrderPending  = "pending"
+	OrderPaid     = "paid"
+	OrderDeclined = "declined"
+	OrderRefunded = "refunded"
+)
+
+//Cart is the courses, by id or slug, and the bundles a student orders, paid from the payment source in the currency.
+//The coupon discounts the first course it applies to.
+type Cart struct {
+	Student  string   `json:"student"`
+	Courses  []string `json:"courses" validate:"max=50,dive,required"`
+	Bundles  []string `json:"bundles" validate:"max=50,dive,required"`
+	Coupon   string   `json:"coupon"`
+	Currency string   `json:"currency" validate:"omitempty,currency"`
+	Source   string   `json:"source"`
+}
+
+//LineItem is a course or a bundle of an order and what was paid for it. The line of a bundle enrolls in the courses of
+//the bundle the student had not taken when ordering it.
+type LineItem struct {
+	CourseID string   `json:"courseId,omitempty" bson:"courseId,omitempty"`
+	BundleID string   `json:"bundleId,omitempty" bson:"bundleId,omitempty"`
+	Courses  []string `json:"courses,omitempty" bson:"courses,omitempty"`
+	Coupon   string   `json:"coupon,omitempty" bson:"coupon,omitempty"`
+	Price    Money    `json:"price"`
+	Discount Money    `json:"discount"`
+	Total    Money    `json:"total"`
+	Refunded bool     `json:"refunded"`
+}
+
+//Order is a cart priced for a student and its payment. Retries of an order send its key again.
+type Order struct {
+	ID        string     `json:"id" bson:"_id"`
+	Key       string     `json:"key"`
+	Student   string     `json:"student"`
+	Items     []LineItem `json:"items"`
+	Total     Money      `json:"total"`
+	Refunded  Money      `json:"refunded"`
+	Status    string     `json:"status"`
+	Payment   string     `json:"payment,omitempty" bson:"payment,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+//Refund is the payment of a line of an order given back
+type Refund struct {
+	ID       string    `json:"id" bson:"_id"`
+	OrderID  string    `json:"orderId" bson:"orderId"`
+	CourseID string    `json:"courseId,omitempty" bson:"courseId,omitempty"`
+	BundleID string    `json:"bundleId,omitempty" bson:"bundleId,omitempty"`
+	Amount   Money     `json:"amount"`
+	Payment  string    `json:"payment,omitempty" bson:"payment,omitempty"`
+	At       time.Time `json:"at"`
+}
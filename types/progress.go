@@ -0,0 +1,34 @@
+package types
+
+import "time"
+
+//Progress is how far a student went through the lessons of a course. The counts and the percent are the ones of the
+//required lessons of the current curriculum.
+type Progress struct {
+	ID            string                    `json:"id" bson:"_id"`
+	CourseID      string                    `json:"courseId" bson:"courseId"`
+	Student       string                    `json:"student"`
+	Lessons       map[string]LessonProgress `json:"lessons"`
+	Required      int                       `json:"required" bson:"-"`
+	Completed     int                       `json:"completed" bson:"-"`
+	Percent       float64                   `json:"percent" bson:"-"`
+	CompletedAt   *time.Time                `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	CertificateID string                    `json:"certificateId,omitempty" bson:"certificateId,omitempty"`
+}
+
+//LessonProgress is when a student started and completed a lesson, and the percent of its video watched
+type LessonProgress struct {
+	StartedAt   time.Time  `json:"startedAt" bson:"startedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	Watched     float64    `json:"watched"`
+}
+
+//Certificate is issued to the students completing a course. Its signature proves it was issued by the service.
+type Certificate struct {
+	ID         string    `json:"id" bson:"_id"`
+	CourseID   string    `json:"courseId" bson:"courseId"`
+	CourseName string    `json:"courseName" bson:"courseName"`
+	Student    string    `json:"student"`
+	IssuedAt   time.Time `json:"issuedAt" bson:"issuedAt"`
+	Signature  string    `json:"signature,omitempty"`
+}
@@ -0,0 +1,23 @@
+package types
+
+import "time"
+
+//Statuses of the reviews. Hidden reviews are left out of the listings and of the rating of their course.
+const (
+	ReviewVisible = "visible"
+	ReviewHidden  = "hidden"
+)
+
+//Review is the rating, from 1 to 5 stars, and the opinion of a student of a course. Flags are the users who
+//reported it to the moderators.
+type Review struct {
+	ID        string     `json:"id" bson:"_id"`
+	CourseID  string     `json:"courseId" bson:"courseId"`
+	Student   string     `json:"student"`
+	Rating    int64      `json:"rating" validate:"required,min=1,max=5"`
+	Text      string     `json:"text" validate:"max=2000"`
+	Status    string     `json:"status"`
+	Flags     []string   `json:"flags,omitempty" bson:"flags,omitempty"`
+	At        time.Time  `json:"at"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+}
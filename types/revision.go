@@ -0,0 +1,13 @@
+package types
+
+import "time"
+
+//Revision is a course as it was saved with one of its versions. Revisions are never changed once stored.
+type Revision struct {
+	ID       string    `json:"id" bson:"_id"`
+	CourseID string    `json:"courseId" bson:"courseId"`
+	Version  int64     `json:"version"`
+	Actor    string    `json:"actor"`
+	At       time.Time `json:"at"`
+	Course   Course    `json:"course"`
+}